@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fp-apac-cognito-service/internal/handler"
+	"github.com/aws/aws-lambda-go/lambda"
+	"log"
+)
+
+// Serves every http endpoint from one Lambda, dispatching on method and path.
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	h, err := handler.New()
+	if err != nil {
+		log.Fatalf("Failed to start: %s", err.Error())
+	}
+	lambda.Start(h.Router().Handle)
+}
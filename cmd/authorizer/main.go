@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fp-apac-cognito-service/internal/authorizer"
+	"github.com/aws/aws-lambda-go/lambda"
+	"log"
+)
+
+// API Gateway custom authorizer accepting tokens from the user pool named by
+// COGNITO_USER_POOL_ID.
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	a, err := authorizer.FromEnv()
+	if err != nil {
+		log.Fatalf("Invalid authorizer configuration: %s", err.Error())
+	}
+	if a == nil {
+		log.Fatalf("COGNITO_USER_POOL_ID must be set")
+	}
+	lambda.Start(a.Authorize)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fp-apac-cognito-service/internal/handler"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Imports users from a CSV or NDJSON file the way POST /api/v1/users/import
+// does, without the request size and timeout limits of API Gateway. The
+// per-row report is printed as JSON, and the exit status is 1 when any row
+// failed.
+//
+//	go run cmd/importusers/main.go -user-pool-id <id> -file users.csv -dry-run
+func main() {
+	log.SetFlags(0)
+
+	region := flag.String("region", envOr("AWS_REGION", "ap-southeast-1"), "AWS region")
+	cognitoEndpoint := flag.String("cognito-endpoint", os.Getenv("COGNITO_ENDPOINT"), "override the Cognito user pool endpoint")
+	userPoolID := flag.String("user-pool-id", os.Getenv("COGNITO_USER_POOL_ID"), "user pool to create the users in")
+	file := flag.String("file", "", "CSV or NDJSON file of users, - for standard input")
+	format := flag.String("format", "", "csv or ndjson, default from the file extension")
+	mode := flag.String("mode", user.CreateModePassword, "password, or invite to have Cognito send temporary passwords")
+	dryRun := flag.Bool("dry-run", false, "only validate the rows")
+	concurrency := flag.Int("concurrency", 0, "users created at once, default 4")
+	flag.Parse()
+
+	if *userPoolID == "" || *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	var data []byte
+	var err error
+	if *file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*file)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read %s: %s", *file, err.Error())
+	}
+
+	sessions, err := handler.NewSession(&aws.Config{Region: region})
+	if err != nil {
+		log.Fatalf("Failed to connect to AWS: %s", err.Error())
+	}
+	cognito := &aws.Config{}
+	if *cognitoEndpoint != "" {
+		cognito.Endpoint = cognitoEndpoint
+	}
+	config := user.Config{
+		Information:   "Import Users CLI",
+		CognitoClient: cognitoidentityprovider.New(sessions, cognito),
+	}
+
+	response := config.ImportUsers(user.ImportRequest{
+		UserPoolID:  *userPoolID,
+		Format:      *format,
+		Data:        string(data),
+		Mode:        *mode,
+		DryRun:      *dryRun,
+		Concurrency: *concurrency,
+	})
+	report, _ := json.MarshalIndent(response, "", "  ")
+	os.Stdout.Write(append(report, '\n'))
+	if response.ResponseCode != 200 {
+		os.Exit(1)
+	}
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fp-apac-cognito-service/internal/authorizer"
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/handler"
+	"fp-apac-cognito-service/internal/router"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Runs the API Gateway handlers on net/http so the API can be curled locally,
+// against AWS, a Cognito-compatible endpoint, or the in-memory emulator.
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	addr := flag.String("addr", envOr("LOCAL_ADDR", ":8080"), "address to listen on")
+	region := flag.String("region", envOr("AWS_REGION", "ap-southeast-1"), "AWS region")
+	cognitoEndpoint := flag.String("cognito-endpoint", os.Getenv("COGNITO_ENDPOINT"), "override the Cognito user pool endpoint, e.g. a local emulator")
+	iamEndpoint := flag.String("iam-endpoint", os.Getenv("IAM_ENDPOINT"), "override the IAM endpoint")
+	s3Endpoint := flag.String("s3-endpoint", os.Getenv("S3_ENDPOINT"), "override the S3 endpoint, e.g. an S3-compatible store for user exports")
+	exportBucket := flag.String("export-bucket", envOr("EXPORT_BUCKET", "exports"), "the bucket user exports are stored in")
+	fake := flag.Bool("fake", os.Getenv("COGNITO_FAKE") == "true", "serve from the in-memory Cognito emulator instead of AWS")
+	userPoolID := flag.String("user-pool-id", os.Getenv("COGNITO_USER_POOL_ID"), "require bearer tokens from this user pool on administration routes, required without -fake")
+	adminEmail := flag.String("admin-email", envOr("LOCAL_ADMIN_EMAIL", "admin@example.com"), "with -fake, the admin user seeded into the local pool")
+	adminPassword := flag.String("admin-password", envOr("LOCAL_ADMIN_PASSWORD", "Passw0rd!"), "with -fake, the password of the seeded admin user")
+	clientIDs := flag.String("client-ids", os.Getenv("COGNITO_CLIENT_IDS"), "comma separated app clients whose tokens are accepted, default any")
+	jwksURL := flag.String("jwks-url", os.Getenv("COGNITO_JWKS_URL"), "override the JWKS URL of the user pool")
+	flag.Parse()
+
+	var h handler.Handler
+	var emulator *cognitofake.Cognito
+	mux := http.NewServeMux()
+	if *fake {
+		if *userPoolID != "" {
+			log.Fatalf("-user-pool-id cannot be used with -fake, which seeds its own pool")
+		}
+		emulator = cognitofake.New(*region)
+		h = handler.Handler{
+			CognitoClient: emulator,
+			IAMService:    cognitofake.NewIAM(),
+			ObjectStore:   cognitofake.NewS3(),
+		}
+		mux.HandleFunc("/local/confirmation_code", confirmationCode(emulator))
+		mux.HandleFunc("/local/jwks.json", jwks(emulator))
+		mux.Handle("/local/import/", emulator.ImportHandler())
+		emulator.ImportURL = localURL(*addr) + "/local/import"
+		poolID, clientID, err := seedPool(emulator, *adminEmail, *adminPassword)
+		if err != nil {
+			log.Fatalf("Failed to seed the local user pool: %s", err.Error())
+		}
+		*userPoolID = poolID
+		log.Printf("Using the in-memory Cognito emulator")
+		log.Printf("Seeded user pool %s with app client %s and admin %s", poolID, clientID, *adminEmail)
+	} else {
+		if *userPoolID == "" {
+			log.Fatalf("-user-pool-id is required without -fake, as administration routes cannot be served without verifying tokens")
+		}
+		sessions, err := handler.NewSession(&aws.Config{Region: region})
+		if err != nil {
+			log.Fatalf("Failed to connect to AWS: %s", err.Error())
+		}
+		h = handler.Handler{
+			CognitoClient: cognitoidentityprovider.New(sessions, endpointConfig(*cognitoEndpoint)),
+			IAMService:    iam.New(sessions, endpointConfig(*iamEndpoint)),
+			ObjectStore:   s3.New(sessions, handler.ObjectStoreConfig(*s3Endpoint)),
+		}
+	}
+
+	var clients []string
+	if *clientIDs != "" {
+		clients = strings.Split(*clientIDs, ",")
+	}
+	h.UserPoolID = *userPoolID
+	h.ExportBucket = *exportBucket
+	h.Authorizer = authorizer.New(*region, *userPoolID, clients...)
+	if *jwksURL != "" {
+		h.Authorizer.Keys = authorizer.NewKeySet(*jwksURL)
+	}
+	if emulator != nil {
+		h.Authorizer.Keys = authorizer.NewKeySetFunc(emulator.JWKS)
+	}
+	policy, err := handler.LoadPolicy()
+	if err != nil {
+		log.Fatalf("Invalid route policy: %s", err.Error())
+	}
+	h.Policy = policy
+	log.Printf("Requiring tokens issued by %s", h.Authorizer.Issuer)
+
+	api := h.Router()
+	for _, route := range api.Routes() {
+		log.Printf("%-7s %s (%s)", route.Method, route.Path, route.Name)
+	}
+	mux.Handle("/", api)
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, router.Logger(mux, log.Printf)))
+}
+
+// seedPool creates the pool the authorizer accepts tokens from in -fake
+// mode, as emulated pool IDs are random. It holds an app client allowing
+// password sign-in and a confirmed member of the admin group, so
+// administration routes can be called with the tokens authenticate_user
+// returns.
+func seedPool(emulator *cognitofake.Cognito, email string, password string) (string, string, error) {
+	pool, err := emulator.CreateUserPool(&cognitoidentityprovider.CreateUserPoolInput{
+		PoolName:               aws.String("local"),
+		UsernameAttributes:     aws.StringSlice([]string{cognitoidentityprovider.UsernameAttributeTypeEmail}),
+		AutoVerifiedAttributes: aws.StringSlice([]string{cognitoidentityprovider.VerifiedAttributeTypeEmail}),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	poolID := pool.UserPool.Id
+	client, err := emulator.CreateUserPoolClient(&cognitoidentityprovider.CreateUserPoolClientInput{
+		ClientName:        aws.String("local"),
+		ExplicitAuthFlows: aws.StringSlice([]string{"ALLOW_USER_PASSWORD_AUTH", "ALLOW_REFRESH_TOKEN_AUTH"}),
+		UserPoolId:        poolID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	_, err = emulator.CreateGroup(&cognitoidentityprovider.CreateGroupInput{
+		GroupName:  aws.String("admin"),
+		UserPoolId: poolID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	_, err = emulator.AdminCreateUser(&cognitoidentityprovider.AdminCreateUserInput{
+		MessageAction: aws.String(cognitoidentityprovider.MessageActionTypeSuppress),
+		UserAttributes: []*cognitoidentityprovider.AttributeType{
+			{Name: aws.String("email"), Value: aws.String(email)},
+			{Name: aws.String("email_verified"), Value: aws.String("true")},
+		},
+		UserPoolId: poolID,
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	_, err = emulator.AdminSetUserPassword(&cognitoidentityprovider.AdminSetUserPasswordInput{
+		Password:   aws.String(password),
+		Permanent:  aws.Bool(true),
+		UserPoolId: poolID,
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	_, err = emulator.AdminAddUserToGroup(&cognitoidentityprovider.AdminAddUserToGroupInput{
+		GroupName:  aws.String("admin"),
+		UserPoolId: poolID,
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return aws.StringValue(poolID), aws.StringValue(client.UserPoolClient.ClientId), nil
+}
+
+// confirmationCode stands in for the email or SMS carrying a confirmation
+// code, which the emulator never sends.
+func confirmationCode(emulator *cognitofake.Cognito) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		code, ok := emulator.ConfirmationCode(req.URL.Query().Get("pool_id"), req.URL.Query().Get("username"))
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write([]byte(code))
+	}
+}
+
+// jwks serves the emulator's signing keys, standing in for the JWKS a real
+// pool publishes.
+func jwks(emulator *cognitofake.Cognito) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, err := emulator.JWKS()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// localURL is the base URL of the server listening on ADDR.
+func localURL(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "http://localhost" + addr
+	}
+	return "http://" + addr
+}
+
+func endpointConfig(endpoint string) *aws.Config {
+	config := &aws.Config{}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
+	return config
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fp-apac-cognito-service/internal/handler"
+	"github.com/aws/aws-lambda-go/lambda"
+	"log"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	h, err := handler.New()
+	if err != nil {
+		log.Fatalf("Failed to start: %s", err.Error())
+	}
+	handle, err := h.Function("admin_set_mfa_preference")
+	if err != nil {
+		log.Fatal(err)
+	}
+	lambda.Start(handle)
+}
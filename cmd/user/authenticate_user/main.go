@@ -1,73 +1,20 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
-	"fp-apac-cognito-service/internal/user"
-	"github.com/aws/aws-lambda-go/events"
+	"fp-apac-cognito-service/internal/handler"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
-	"github.com/aws/aws-sdk-go/service/iam"
 	"log"
-	"os"
 )
 
-var cognitoClient *cognitoidentityprovider.CognitoIdentityProvider
-var iamSvc *iam.IAM
-
-func init() {
-	// Initialize a session that the SDK will use to load configuration,
-	// credentials, and region from the shared config file. (~/.aws/config).
-	region := os.Getenv("AWS_REGION")
-	if sessions, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Region: &region,
-		},
-		SharedConfigState: session.SharedConfigEnable,
-	}); err != nil {
-		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s ", err.Error()))
-	}else {
-		cognitoClient = cognitoidentityprovider.New(sessions)
-		iamSvc = iam.New(sessions)
-	}
-}
-
-func EventHandler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	modelConfig := user.Config{
-		Information:   "List User Handler",
-		CognitoClient: *cognitoClient,
-	}
-
-	item := user.LoginRequest{
-		Email:    "",
-		Password: "",
-		ClientID: "",
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	h, err := handler.New()
+	if err != nil {
+		log.Fatalf("Failed to start: %s", err.Error())
 	}
-
-	// Parse request body
-	err := json.Unmarshal([]byte(request.Body), &item)
+	handle, err := h.Function("authenticate_user")
 	if err != nil {
-		return events.APIGatewayProxyResponse{
-			StatusCode:        500,
-			Headers:           nil,
-			MultiValueHeaders: nil,
-			Body:              err.Error(),
-			IsBase64Encoded:   false,
-		}, nil
+		log.Fatal(err)
 	}
-	response, responseCode := modelConfig.AuthenticateUser(item)
-	return events.APIGatewayProxyResponse{
-		StatusCode:        responseCode,
-		Headers:           nil,
-		MultiValueHeaders: nil,
-		Body:              response,
-		IsBase64Encoded:   false,
-	}, nil
+	lambda.Start(handle)
 }
-
-func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	lambda.Start(EventHandler)
-}
\ No newline at end of file
@@ -1,18 +1,12 @@
 package main
 
 import (
-	"github.com/aws/aws-lambda-go/events"
+	"fp-apac-cognito-service/internal/handler"
 	"github.com/aws/aws-lambda-go/lambda"
 	"log"
 )
 
-func EventHandler(event events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
-	event.Response.AutoVerifyEmail = true
-	event.Response.AutoConfirmUser = true
-	return event, nil
-}
-
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	lambda.Start(EventHandler)
-}
\ No newline at end of file
+	lambda.Start(handler.AutoVerify)
+}
@@ -1 +1,20 @@
-package update_userpool_client
+package main
+
+import (
+	"fp-apac-cognito-service/internal/handler"
+	"github.com/aws/aws-lambda-go/lambda"
+	"log"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	h, err := handler.New()
+	if err != nil {
+		log.Fatalf("Failed to start: %s", err.Error())
+	}
+	handle, err := h.Function("update_userpool_client")
+	if err != nil {
+		log.Fatal(err)
+	}
+	lambda.Start(handle)
+}
@@ -0,0 +1,194 @@
+// Package apierror maps the errors of the AWS SDK, and the API's own
+// validation failures, onto HTTP statuses and the RFC 7807 problem document
+// every endpoint answers a failed request with.
+package apierror
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"log"
+	"strings"
+)
+
+// Codes for errors raised by the API itself rather than by AWS, whose
+// error codes are passed through.
+const (
+	CodeInvalidRequest = "InvalidRequest"
+	CodeMalformedRequest = "MalformedRequest"
+	CodeNotFound = "NotFound"
+	CodeMethodNotAllowed = "MethodNotAllowed"
+	CodeUnauthorized = "Unauthorized"
+	CodeForbidden = "Forbidden"
+	CodeBulkOperationFailed = "BulkOperationFailed"
+	CodeInternalError = "InternalError"
+)
+
+// statuses maps AWS error codes onto the status the API answers with. Codes
+// missing here take the status AWS answered with, or 500.
+var statuses = map[string]int{
+	cognitoidentityprovider.ErrCodeAliasExistsException:                      409,
+	cognitoidentityprovider.ErrCodeCodeDeliveryFailureException:              502,
+	cognitoidentityprovider.ErrCodeCodeMismatchException:                     400,
+	cognitoidentityprovider.ErrCodeConcurrentModificationException:           409,
+	cognitoidentityprovider.ErrCodeEnableSoftwareTokenMFAException:           400,
+	cognitoidentityprovider.ErrCodeExpiredCodeException:                      400,
+	cognitoidentityprovider.ErrCodeGroupExistsException:                      409,
+	cognitoidentityprovider.ErrCodeInternalErrorException:                    500,
+	cognitoidentityprovider.ErrCodeInvalidEmailRoleAccessPolicyException:     400,
+	cognitoidentityprovider.ErrCodeInvalidLambdaResponseException:            502,
+	cognitoidentityprovider.ErrCodeInvalidParameterException:                 400,
+	cognitoidentityprovider.ErrCodeInvalidPasswordException:                  400,
+	cognitoidentityprovider.ErrCodeInvalidSmsRoleAccessPolicyException:       400,
+	cognitoidentityprovider.ErrCodeInvalidSmsRoleTrustRelationshipException:  400,
+	cognitoidentityprovider.ErrCodeInvalidUserPoolConfigurationException:     400,
+	cognitoidentityprovider.ErrCodeLimitExceededException:                    429,
+	cognitoidentityprovider.ErrCodeMFAMethodNotFoundException:                400,
+	cognitoidentityprovider.ErrCodeNotAuthorizedException:                    401,
+	cognitoidentityprovider.ErrCodePasswordResetRequiredException:            403,
+	cognitoidentityprovider.ErrCodePreconditionNotMetException:               412,
+	cognitoidentityprovider.ErrCodeResourceNotFoundException:                 404,
+	cognitoidentityprovider.ErrCodeSoftwareTokenMFANotFoundException:         404,
+	cognitoidentityprovider.ErrCodeTooManyFailedAttemptsException:            429,
+	cognitoidentityprovider.ErrCodeTooManyRequestsException:                  429,
+	cognitoidentityprovider.ErrCodeUnexpectedLambdaException:                 502,
+	cognitoidentityprovider.ErrCodeUnsupportedOperationException:             400,
+	cognitoidentityprovider.ErrCodeUnsupportedTokenTypeException:             400,
+	cognitoidentityprovider.ErrCodeUnsupportedUserStateException:             409,
+	cognitoidentityprovider.ErrCodeUserImportInProgressException:             409,
+	cognitoidentityprovider.ErrCodeUserLambdaValidationException:             400,
+	cognitoidentityprovider.ErrCodeUserNotConfirmedException:                 403,
+	cognitoidentityprovider.ErrCodeUserNotFoundException:                     404,
+	cognitoidentityprovider.ErrCodeUsernameExistsException:                   409,
+	iam.ErrCodeEntityAlreadyExistsException:                                  409,
+	iam.ErrCodeLimitExceededException:                                        429,
+	iam.ErrCodeMalformedPolicyDocumentException:                              400,
+	iam.ErrCodeNoSuchEntityException:                                         404,
+	s3.ErrCodeNoSuchBucket:                                                   404,
+	s3.ErrCodeNoSuchKey:                                                      404,
+	s3.ErrCodeNoSuchUpload:                                                   404,
+	"AccessDenied":                                                           403,
+	"AccessDeniedException":                                                  403,
+	request.InvalidParameterErrCode:                                          400,
+	request.ParamRequiredErrCode:                                             400,
+	request.ParamMinValueErrCode:                                             400,
+	request.ParamMinLenErrCode:                                               400,
+	request.ParamMaxLenErrCode:                                               400,
+}
+
+// Error is the error in a response body. RequestID is that of the AWS
+// request that failed, for quoting to AWS support. Fields lists each invalid
+// field of a request that failed validation.
+type Error struct {
+	Status int `json:"-"`
+	Code string `json:"code"`
+	Message string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	Fields []FieldError `json:"errors,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Invalid is the 400 for a request that fails the API's own checks.
+func Invalid(message string) *Error {
+	return New(400, CodeInvalidRequest, message)
+}
+
+// InvalidFields is the 400 for a request with the invalid FIELDS, whose
+// messages it joins into its own.
+func InvalidFields(fields []FieldError) *Error {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	err := Invalid("Invalid request: " + strings.Join(messages, "; "))
+	err.Fields = fields
+	return err
+}
+
+// Malformed is the 400 for a request body that cannot be decoded.
+func Malformed(err error) *Error {
+	return New(400, CodeMalformedRequest, "The request body is not valid JSON: "+err.Error())
+}
+
+// FromError maps err onto an Error. Errors that are neither an Error nor
+// from AWS are internal errors: they are logged, and answered with a
+// generic message so nothing of the service's internals reaches the caller.
+func FromError(err error) *Error {
+	switch e := err.(type) {
+	case *Error:
+		return e
+	case awserr.Error:
+		mapped := New(Status(e), e.Code(), e.Message())
+		if params, ok := err.(request.ErrInvalidParams); ok {
+			mapped.Fields = paramFields(params)
+		}
+		if failure, ok := err.(awserr.RequestFailure); ok {
+			mapped.RequestID = failure.RequestID()
+		}
+		return mapped
+	}
+	log.Printf("Internal error: %s", err)
+	return New(500, CodeInternalError, "The request could not be completed because of an internal error")
+}
+
+// Status is the HTTP status for an AWS error.
+func Status(err awserr.Error) int {
+	if status, ok := statuses[err.Code()]; ok {
+		return status
+	}
+	if failure, ok := err.(awserr.RequestFailure); ok && failure.StatusCode() >= 400 {
+		return failure.StatusCode()
+	}
+	return 500
+}
+
+// paramFields lists the fields the SDK rejected before sending a request,
+// named as in the AWS API.
+func paramFields(params request.ErrInvalidParams) []FieldError {
+	var fields []FieldError
+	for _, err := range params.OrigErrs() {
+		if param, ok := err.(request.ErrInvalidParam); ok {
+			code, known := paramCodes[param.Code()]
+			if !known {
+				code = FieldInvalid
+			}
+			fields = append(fields, FieldError{Field: param.Field(), Code: code, Message: param.Message()})
+		}
+	}
+	return fields
+}
+
+// Result leads every response body. Error is only set when the request
+// failed, and ResponseCode and Message then repeat its status and message.
+type Result struct {
+	ResponseCode int `json:"response_code"`
+	Message string `json:"message"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// Outcome returns the result itself, so code handling any response can
+// reach the Result it embeds.
+func (r Result) Outcome() Result {
+	return r
+}
+
+// Fail records err as the outcome, mapped by FromError.
+func (r *Result) Fail(err error) {
+	r.Error = FromError(err)
+	r.ResponseCode = r.Error.Status
+	r.Message = r.Error.Message
+}
+
+// Invalid records a request that fails the API's own checks.
+func (r *Result) Invalid(message string) {
+	r.Fail(Invalid(message))
+}
@@ -0,0 +1,74 @@
+package apierror_test
+
+import (
+	"bytes"
+	"errors"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	invalid := request.ErrInvalidParams{Context: "AdminGetUserInput"}
+	invalid.Add(request.NewErrParamRequired("Username"))
+	invalid.Add(request.NewErrParamMinLen("UserPoolId", 1))
+
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		code      string
+		message   string
+		requestID string
+		fields    []string
+	}{
+		{"passes an Error through", apierror.Invalid("bad"), 400, apierror.CodeInvalidRequest, "bad", "", nil},
+		{"maps a Cognito code",
+			awserr.NewRequestFailure(awserr.New(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.", nil), 400, "req-1"),
+			404, cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.", "req-1", nil},
+		{"maps AccessDeniedException", awserr.New("AccessDeniedException", "not allowed", nil), 403, "AccessDeniedException", "not allowed", "", nil},
+		{"maps AccessDenied", awserr.New("AccessDenied", "Access Denied", nil), 403, "AccessDenied", "Access Denied", "", nil},
+		{"keeps the status AWS answered an unknown code with",
+			awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "try later", nil), 503, "req-2"),
+			503, "ServiceUnavailable", "try later", "req-2", nil},
+		{"answers 500 for an unknown code without a status", awserr.New("Mystery", "?", nil), 500, "Mystery", "?", "", nil},
+		{"lists the fields the SDK rejected", invalid, 400, request.InvalidParameterErrCode, invalid.Message(), "", []string{"AdminGetUserInput.Username:required", "AdminGetUserInput.UserPoolId:too_short"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := apierror.FromError(test.err)
+			if got.Status != test.status || got.Code != test.code || got.Message != test.message || got.RequestID != test.requestID {
+				t.Fatalf("FromError = %d %s %q %q, want %d %s %q %q", got.Status, got.Code, got.Message, got.RequestID, test.status, test.code, test.message, test.requestID)
+			}
+			var fields []string
+			for _, field := range got.Fields {
+				fields = append(fields, field.Field+":"+field.Code)
+			}
+			if strings.Join(fields, " ") != strings.Join(test.fields, " ") {
+				t.Errorf("fields = %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestFromErrorHidesInternalErrors(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	got := apierror.FromError(errors.New("dial tcp 10.0.0.7:443: connection refused"))
+	if got.Status != 500 || got.Code != apierror.CodeInternalError {
+		t.Fatalf("FromError = %d %s, want 500 %s", got.Status, got.Code, apierror.CodeInternalError)
+	}
+	if strings.Contains(got.Message, "10.0.0.7") {
+		t.Errorf("message %q exposes the internal error", got.Message)
+	}
+	if !strings.Contains(logged.String(), "10.0.0.7") {
+		t.Errorf("internal error was not logged: %q", logged.String())
+	}
+}
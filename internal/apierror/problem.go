@@ -0,0 +1,129 @@
+package apierror
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"strings"
+	"unicode"
+)
+
+// ProblemContentType is the media type of an RFC 7807 problem document.
+const ProblemContentType = "application/problem+json"
+
+// TypeBase prefixes the code of an error to form the type of its problem,
+// e.g. urn:fp-apac-cognito-service:problem:UserNotFoundException.
+const TypeBase = "urn:fp-apac-cognito-service:problem:"
+
+// Codes for FieldError, saying what is wrong with the field.
+const (
+	FieldRequired = "required"
+	FieldMalformed = "malformed"
+	FieldTooShort = "too_short"
+	FieldTooLong = "too_long"
+	FieldOutOfRange = "out_of_range"
+	FieldUnsupported = "unsupported"
+	FieldConflict = "conflict"
+	FieldPasswordPolicy = "password_policy"
+	FieldInvalid = "invalid"
+)
+
+// paramCodes maps the SDK's own parameter checks onto field codes.
+var paramCodes = map[string]string{
+	request.ParamRequiredErrCode: FieldRequired,
+	request.ParamMinLenErrCode:   FieldTooShort,
+	request.ParamMaxLenErrCode:   FieldTooLong,
+	request.ParamMinValueErrCode: FieldOutOfRange,
+}
+
+// FieldError is one invalid field of a request, named by its JSON path such
+// as user.email_address or invite.delivery_mediums[1].
+type FieldError struct {
+	Field string `json:"field"`
+	Code string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem is the RFC 7807 problem document every failed request is answered
+// with. Code, RequestID and Errors are extension members; Errors is empty
+// unless the request failed validation.
+type Problem struct {
+	Type string `json:"type"`
+	Title string `json:"title"`
+	Status int `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Errors []FieldError `json:"errors"`
+}
+
+// NewProblem describes err, mapped by FromError, as it occurred at
+// INSTANCE, the path requested.
+func NewProblem(err error, instance string) Problem {
+	mapped := FromError(err)
+	problem := Problem{
+		Type:      TypeBase + mapped.Code,
+		Title:     title(mapped.Code),
+		Status:    mapped.Status,
+		Detail:    mapped.Message,
+		Instance:  instance,
+		Code:      mapped.Code,
+		RequestID: mapped.RequestID,
+		Errors:    mapped.Fields,
+	}
+	if problem.Errors == nil {
+		problem.Errors = []FieldError{}
+	}
+	return problem
+}
+
+// ProblemBody is the problem document for err and its status. The members
+// of DETAILS, usually the response that failed, are kept as extension
+// members, apart from the response_code, message and error the problem
+// replaces.
+func ProblemBody(err error, instance string, details interface{}) (string, int) {
+	problem := NewProblem(err, instance)
+
+	members := map[string]json.RawMessage{}
+	if details != nil {
+		if raw, marshalErr := json.Marshal(details); marshalErr == nil {
+			json.Unmarshal(raw, &members)
+		}
+		delete(members, "response_code")
+		delete(members, "message")
+		delete(members, "error")
+	}
+	raw, marshalErr := json.Marshal(problem)
+	if marshalErr == nil {
+		marshalErr = json.Unmarshal(raw, &members)
+	}
+	if marshalErr == nil {
+		raw, marshalErr = json.Marshal(members)
+	}
+	if marshalErr != nil {
+		return "Unable to construct JSON", problem.Status
+	}
+	return string(raw), problem.Status
+}
+
+// title turns a code such as MFAMethodNotFoundException into the summary
+// "MFA method not found".
+func title(code string) string {
+	code = strings.TrimSuffix(code, "Exception")
+	runes := []rune(code)
+	var words []string
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !(unicode.IsUpper(runes[i]) &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			continue
+		}
+		word := string(runes[start:i])
+		if len(words) > 0 && word != strings.ToUpper(word) {
+			word = strings.ToLower(word)
+		}
+		words = append(words, word)
+		start = i
+	}
+	return strings.Join(words, " ")
+}
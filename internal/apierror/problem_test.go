@@ -0,0 +1,68 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"testing"
+)
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		title  string
+		fields int
+	}{
+		{"titles a Cognito code", awserr.New(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.", nil), "User not found", 0},
+		{"keeps acronyms", awserr.New(cognitoidentityprovider.ErrCodeMFAMethodNotFoundException, "no MFA", nil), "MFA method not found", 0},
+		{"lists invalid fields", apierror.InvalidFields([]apierror.FieldError{{Field: "email_address", Code: apierror.FieldRequired, Message: "is required"}}), "Invalid request", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problem := apierror.NewProblem(test.err, "/api/v1/user")
+			mapped := apierror.FromError(test.err)
+			if problem.Type != apierror.TypeBase+mapped.Code || problem.Title != test.title || problem.Status != mapped.Status || problem.Instance != "/api/v1/user" {
+				t.Errorf("problem = %+v, want type %s%s titled %q", problem, apierror.TypeBase, mapped.Code, test.title)
+			}
+			if problem.Errors == nil || len(problem.Errors) != test.fields {
+				t.Errorf("errors = %#v, want %d", problem.Errors, test.fields)
+			}
+		})
+	}
+}
+
+func TestProblemBody(t *testing.T) {
+	details := struct {
+		ResponseCode int    `json:"response_code"`
+		Message      string `json:"message"`
+		Succeeded    int    `json:"succeeded"`
+	}{400, "replaced", 3}
+	body, status := apierror.ProblemBody(awserr.New(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.", nil), "/api/v1/user/get", details)
+	if status != 404 {
+		t.Errorf("status = %d, want 404", status)
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &members); err != nil {
+		t.Fatalf("body %s: %s", body, err)
+	}
+	for _, dropped := range []string{"response_code", "message", "error"} {
+		if _, ok := members[dropped]; ok {
+			t.Errorf("body keeps %s: %s", dropped, body)
+		}
+	}
+	want := map[string]string{
+		"type":      `"` + apierror.TypeBase + cognitoidentityprovider.ErrCodeUserNotFoundException + `"`,
+		"status":    "404",
+		"detail":    `"User does not exist."`,
+		"instance":  `"/api/v1/user/get"`,
+		"errors":    "[]",
+		"succeeded": "3",
+	}
+	for member, value := range want {
+		if got := string(members[member]); got != value {
+			t.Errorf("%s = %s, want %s", member, got, value)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package authorizer
+
+import (
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+)
+
+// Claims are the verified claims of a token, as decoded from JSON.
+type Claims map[string]interface{}
+
+func (c Claims) String(name string) string {
+	switch v := c[name].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Subject is the user's sub.
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Username is the cognito:username of an ID token or username of an access
+// token.
+func (c Claims) Username() string {
+	if username := c.String("cognito:username"); username != "" {
+		return username
+	}
+	return c.String("username")
+}
+
+// Groups returns the cognito:groups the user belongs to.
+func (c Claims) Groups() []string {
+	return c.list("cognito:groups", ",")
+}
+
+// Scopes returns the OAuth scopes of an access token.
+func (c Claims) Scopes() []string {
+	return c.list("scope", " ")
+}
+
+// list reads a claim that is a JSON array in the token but arrives as a
+// delimited string from an API Gateway authorizer context.
+func (c Claims) list(name string, separator string) []string {
+	var values []string
+	switch v := c[name].(type) {
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	case []string:
+		values = append(values, v...)
+	case string:
+		for _, item := range strings.Split(strings.Trim(v, "[]"), separator) {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// context flattens the claims into the string values an API Gateway
+// authorizer context can carry.
+func (c Claims) context() map[string]interface{} {
+	context := map[string]interface{}{}
+	for name := range c {
+		switch v := c[name].(type) {
+		case string, float64, bool:
+			context[name] = v
+		case []interface{}:
+			separator := ","
+			if name == "scope" {
+				separator = " "
+			}
+			context[name] = strings.Join(c.list(name, separator), separator)
+		default:
+			context[name] = c.String(name)
+		}
+	}
+	return context
+}
+
+// ClaimsFromRequest returns the claims a Wrap-ped handler or an API Gateway
+// authorizer attached to the request.
+func ClaimsFromRequest(request events.APIGatewayProxyRequest) (Claims, bool) {
+	authorizer := request.RequestContext.Authorizer
+	if len(authorizer) == 0 {
+		return nil, false
+	}
+	switch claims := authorizer["claims"].(type) {
+	case Claims:
+		return claims, true
+	case map[string]interface{}:
+		return Claims(claims), true
+	}
+	return Claims(authorizer), true
+}
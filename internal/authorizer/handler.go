@@ -0,0 +1,88 @@
+package authorizer
+
+import (
+	"errors"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/router"
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+)
+
+// Wrap verifies the bearer token in the Authorization header before calling
+// NEXT, which finds the claims in RequestContext.Authorizer["claims"] the same
+// way it would behind an API Gateway Cognito authorizer. Requests without a
+// valid token get a 401.
+func (a *Authorizer) Wrap(next router.HandlerFunc) router.HandlerFunc {
+	return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		claims, err := a.Verify(header(request.Headers, "Authorization"))
+		if err != nil {
+			return Unauthorized(request, err.Error()), nil
+		}
+
+		authorizer := map[string]interface{}{}
+		for k, v := range request.RequestContext.Authorizer {
+			authorizer[k] = v
+		}
+		authorizer["claims"] = claims
+		authorizer["principalId"] = claims.Subject()
+		request.RequestContext.Authorizer = authorizer
+		return next(request)
+	}
+}
+
+// Authorize is the entrypoint of a TOKEN custom authorizer Lambda. Valid
+// tokens get a policy allowing every method of the stage, so API Gateway can
+// cache the result across routes, and their claims as the context.
+func (a *Authorizer) Authorize(event events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	claims, err := a.Verify(event.AuthorizationToken)
+	if err != nil {
+		// API Gateway answers 401 for exactly this error.
+		return events.APIGatewayCustomAuthorizerResponse{}, errors.New("Unauthorized")
+	}
+
+	resource := event.MethodArn
+	if parts := strings.SplitN(event.MethodArn, "/", 3); len(parts) == 3 {
+		resource = parts[0] + "/" + parts[1] + "/*"
+	}
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID: claims.Subject(),
+		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+			Version: "2012-10-17",
+			Statement: []events.IAMPolicyStatement{
+				{
+					Action:   []string{"execute-api:Invoke"},
+					Effect:   "Allow",
+					Resource: []string{resource},
+				},
+			},
+		},
+		Context: claims.context(),
+	}, nil
+}
+
+// Unauthorized is the 401 response to REQUEST for a missing or invalid
+// token.
+func Unauthorized(request events.APIGatewayProxyRequest, message string) events.APIGatewayProxyResponse {
+	body, status := apierror.ProblemBody(apierror.New(401, apierror.CodeUnauthorized, message), request.Path, nil)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers: map[string]string{
+			"Content-Type":     apierror.ProblemContentType,
+			"WWW-Authenticate": "Bearer",
+		},
+		Body:       body,
+	}
+}
+
+// header looks NAME up case-insensitively, as HTTP header names are.
+func header(headers map[string]string, name string) string {
+	if value, ok := headers[name]; ok {
+		return value
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
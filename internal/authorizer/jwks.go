@@ -0,0 +1,134 @@
+package authorizer
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned for a kid the key set does not hold, even after
+// fetching it again.
+var ErrUnknownKey = errors.New("token signed with an unknown key")
+
+// KeySet caches the RSA keys of a JWKS. Keys are fetched on first use and
+// again once MaxAge has passed, or when a token names a kid the cache does
+// not hold, so rotated keys are picked up. MinRefresh limits how often a
+// fetch is attempted, whether or not the last attempt succeeded, so an
+// unreachable JWKS is not fetched again for every token.
+type KeySet struct {
+	MaxAge time.Duration
+	MinRefresh time.Duration
+
+	fetch func() ([]byte, error)
+	now func() time.Time
+	mu sync.Mutex
+	keys map[string]*rsa.PublicKey
+	fetched time.Time
+	attempted time.Time
+	failed error
+}
+
+// NewKeySet returns a key set fetched over HTTP from URL, typically
+// <issuer>/.well-known/jwks.json.
+func NewKeySet(url string) *KeySet {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return NewKeySetFunc(func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	})
+}
+
+// NewKeySetFunc returns a key set loaded by FETCH, e.g. from a local emulator.
+func NewKeySetFunc(fetch func() ([]byte, error)) *KeySet {
+	return &KeySet{
+		MaxAge:     24 * time.Hour,
+		MinRefresh: time.Minute,
+		fetch:      fetch,
+		now:        time.Now,
+	}
+}
+
+// Key returns the public key for KID.
+func (k *KeySet) Key(kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	key, ok := k.keys[kid]
+	stale := now.Sub(k.fetched) >= k.MaxAge
+	if (!ok || stale) && now.Sub(k.attempted) >= k.MinRefresh {
+		k.failed = k.refresh(now)
+		key, ok = k.keys[kid]
+	}
+	if !ok && k.failed != nil {
+		return nil, k.failed
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// refresh fetches the keys, recording the attempt even when it fails.
+func (k *KeySet) refresh(now time.Time) error {
+	k.attempted = now
+	body, err := k.fetch()
+	if err != nil {
+		return fmt.Errorf("fetching JWKS: %s", err)
+	}
+	keys, err := parseJWKS(body)
+	if err != nil {
+		return err
+	}
+	k.keys = keys
+	k.fetched = now
+	return nil
+}
+
+func parseJWKS(body []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %s", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("parsing JWKS key %s: %s", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("parsing JWKS key %s: %s", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,165 @@
+// Package authorizer verifies Cognito user pool ID and access tokens against
+// the pool's JWKS. It can wrap API Gateway proxy handlers or run on its own as
+// an API Gateway custom authorizer Lambda.
+package authorizer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Authorizer accepts tokens issued by one user pool to one of ClientIDs. An
+// empty ClientIDs accepts any client of the pool; TokenUse, when set to "id"
+// or "access", accepts only that kind of token.
+type Authorizer struct {
+	Issuer string
+	ClientIDs []string
+	TokenUse string
+	Keys *KeySet
+	Now func() time.Time
+}
+
+var (
+	ErrMissingToken = errors.New("Missing bearer token")
+	ErrInvalidToken = errors.New("Invalid token")
+	ErrExpiredToken = errors.New("Token has expired")
+)
+
+// New returns an Authorizer for the user pool, fetching keys from the pool's
+// published JWKS.
+func New(region string, userPoolID string, clientIDs ...string) *Authorizer {
+	issuer := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID)
+	return &Authorizer{
+		Issuer:    issuer,
+		ClientIDs: clientIDs,
+		Keys:      NewKeySet(issuer + "/.well-known/jwks.json"),
+		Now:       time.Now,
+	}
+}
+
+// FromEnv builds an Authorizer from COGNITO_USER_POOL_ID, the comma separated
+// COGNITO_CLIENT_IDS, COGNITO_TOKEN_USE and AWS_REGION. It returns nil when
+// no user pool is configured.
+func FromEnv() (*Authorizer, error) {
+	userPoolID := os.Getenv("COGNITO_USER_POOL_ID")
+	if userPoolID == "" {
+		return nil, nil
+	}
+	region := os.Getenv("AWS_REGION")
+	if i := strings.Index(userPoolID, "_"); region == "" && i > 0 {
+		region = userPoolID[:i]
+	}
+
+	var clientIDs []string
+	for _, id := range strings.Split(os.Getenv("COGNITO_CLIENT_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			clientIDs = append(clientIDs, id)
+		}
+	}
+	a := New(region, userPoolID, clientIDs...)
+	a.TokenUse = os.Getenv("COGNITO_TOKEN_USE")
+	if a.TokenUse != "" && a.TokenUse != "id" && a.TokenUse != "access" {
+		return nil, fmt.Errorf("COGNITO_TOKEN_USE must be id or access, not %s", a.TokenUse)
+	}
+	return a, nil
+}
+
+// Verify checks the signature and claims of TOKEN, with or without a
+// "Bearer " prefix, and returns its claims.
+func (a *Authorizer) Verify(token string) (Claims, error) {
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return nil, ErrInvalidToken
+	}
+	key, err := a.Keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := Claims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (a *Authorizer) checkClaims(claims Claims) error {
+	if claims.String("iss") != a.Issuer {
+		return fmt.Errorf("Token was not issued by %s", a.Issuer)
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || a.now().Unix() >= int64(exp) {
+		return ErrExpiredToken
+	}
+
+	var clientID string
+	switch use := claims.String("token_use"); use {
+	case "id":
+		clientID = claims.String("aud")
+	case "access":
+		clientID = claims.String("client_id")
+	default:
+		return ErrInvalidToken
+	}
+	if a.TokenUse != "" && claims.String("token_use") != a.TokenUse {
+		return fmt.Errorf("Expected an %s token", a.TokenUse)
+	}
+	if len(a.ClientIDs) == 0 {
+		return nil
+	}
+	for _, id := range a.ClientIDs {
+		if id == clientID {
+			return nil
+		}
+	}
+	return fmt.Errorf("Token was not issued to an allowed client")
+}
+
+func (a *Authorizer) now() time.Time {
+	if a.Now == nil {
+		return time.Now()
+	}
+	return a.Now()
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
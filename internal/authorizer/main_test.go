@@ -0,0 +1,497 @@
+package authorizer_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fp-apac-cognito-service/internal/authorizer"
+	"github.com/aws/aws-lambda-go/events"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	issuer   = "https://cognito-idp.ap-southeast-1.amazonaws.com/ap-southeast-1_test"
+	clientID = "web"
+)
+
+var now = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+// signingKey is an RSA key published in a JWKS under its kid.
+type signingKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	return signingKey{kid: kid, key: key}
+}
+
+// jwks encodes KEYS the way a pool publishes them.
+func jwks(t *testing.T, keys ...signingKey) []byte {
+	var set []map[string]string
+	for _, k := range keys {
+		set = append(set, map[string]string{
+			"alg": "RS256",
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.PublicKey.E)).Bytes()),
+			"kid": k.kid,
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.key.PublicKey.N.Bytes()),
+			"use": "sig",
+		})
+	}
+	body, err := json.Marshal(map[string]interface{}{"keys": set})
+	if err != nil {
+		t.Fatalf("encoding JWKS: %s", err)
+	}
+	return body
+}
+
+// sign encodes HEADER and CLAIMS as a JWT signed with KEY.
+func sign(t *testing.T, key *rsa.PrivateKey, header map[string]string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("encoding token: %s", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("signing token: %s", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func accessClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"client_id":      clientID,
+		"cognito:groups": []string{"admin"},
+		"exp":            now.Add(time.Hour).Unix(),
+		"iss":            issuer,
+		"scope":          "aws.cognito.signin.user.admin",
+		"sub":            "2f1c7b3e-0000-4000-8000-000000000001",
+		"token_use":      "access",
+		"username":       "jo",
+	}
+}
+
+func idClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"aud":              clientID,
+		"cognito:username": "jo",
+		"exp":              now.Add(time.Hour).Unix(),
+		"iss":              issuer,
+		"sub":              "2f1c7b3e-0000-4000-8000-000000000001",
+		"token_use":        "id",
+	}
+}
+
+// with returns a copy of CLAIMS with NAME set to VALUE, or removed when
+// VALUE is nil.
+func with(claims map[string]interface{}, name string, value interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range claims {
+		out[k] = v
+	}
+	if value == nil {
+		delete(out, name)
+	} else {
+		out[name] = value
+	}
+	return out
+}
+
+func newAuthorizer(keys *authorizer.KeySet) *authorizer.Authorizer {
+	return &authorizer.Authorizer{
+		Issuer:    issuer,
+		ClientIDs: []string{clientID},
+		Keys:      keys,
+		Now:       func() time.Time { return now },
+	}
+}
+
+func TestVerify(t *testing.T) {
+	current := newSigningKey(t, "current")
+	other := newSigningKey(t, "other")
+	body := jwks(t, current)
+	a := newAuthorizer(authorizer.NewKeySetFunc(func() ([]byte, error) { return body, nil }))
+
+	rs256 := map[string]string{"alg": "RS256", "kid": current.kid}
+	valid := sign(t, current.key, rs256, accessClaims())
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"client_id":"web","exp":9999999999,"iss":"`+issuer+`","token_use":"access","cognito:groups":["admin"]}`)) + "." + parts[2]
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"current"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name     string
+		token    string
+		tokenUse string
+		want     error
+		wantErr  bool
+	}{
+		{"accepts access token", valid, "", nil, false},
+		{"accepts bearer prefix", "Bearer " + valid, "", nil, false},
+		{"accepts ID token", sign(t, current.key, rs256, idClaims()), "", nil, false},
+		{"rejects missing token", "", "", authorizer.ErrMissingToken, true},
+		{"rejects malformed token", "abc.def", "", authorizer.ErrInvalidToken, true},
+		{"rejects alg none", unsigned, "", authorizer.ErrInvalidToken, true},
+		{"rejects HS256", sign(t, current.key, map[string]string{"alg": "HS256", "kid": current.kid}, accessClaims()), "", authorizer.ErrInvalidToken, true},
+		{"rejects signature from another key", sign(t, other.key, rs256, accessClaims()), "", authorizer.ErrInvalidToken, true},
+		{"rejects tampered claims", tampered, "", authorizer.ErrInvalidToken, true},
+		{"rejects wrong issuer", sign(t, current.key, rs256, with(accessClaims(), "iss", "https://cognito-idp.ap-southeast-1.amazonaws.com/other")), "", nil, true},
+		{"rejects expired token", sign(t, current.key, rs256, with(accessClaims(), "exp", now.Add(-time.Second).Unix())), "", authorizer.ErrExpiredToken, true},
+		{"rejects token without expiry", sign(t, current.key, rs256, with(accessClaims(), "exp", nil)), "", authorizer.ErrExpiredToken, true},
+		{"rejects refresh token use", sign(t, current.key, rs256, with(accessClaims(), "token_use", "refresh")), "", authorizer.ErrInvalidToken, true},
+		{"rejects missing token use", sign(t, current.key, rs256, with(accessClaims(), "token_use", nil)), "", authorizer.ErrInvalidToken, true},
+		{"rejects ID token when access is required", sign(t, current.key, rs256, idClaims()), "access", nil, true},
+		{"rejects access token when ID is required", valid, "id", nil, true},
+		{"rejects client_id mismatch", sign(t, current.key, rs256, with(accessClaims(), "client_id", "mobile")), "", nil, true},
+		{"rejects aud mismatch", sign(t, current.key, rs256, with(idClaims(), "aud", "mobile")), "", nil, true},
+		{"rejects unknown kid", sign(t, other.key, map[string]string{"alg": "RS256", "kid": other.kid}, accessClaims()), "", authorizer.ErrUnknownKey, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a.TokenUse = test.tokenUse
+			claims, err := a.Verify(test.token)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("Verify: %s", err)
+				}
+				if claims.Username() != "jo" {
+					t.Fatalf("username = %q, want jo", claims.Username())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Verify succeeded, want an error")
+			}
+			if test.want != nil && err != test.want {
+				t.Fatalf("error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAnyClient(t *testing.T) {
+	key := newSigningKey(t, "current")
+	body := jwks(t, key)
+	a := newAuthorizer(authorizer.NewKeySetFunc(func() ([]byte, error) { return body, nil }))
+	a.ClientIDs = nil
+
+	token := sign(t, key.key, map[string]string{"alg": "RS256", "kid": key.kid}, with(accessClaims(), "client_id", "mobile"))
+	if _, err := a.Verify(token); err != nil {
+		t.Fatalf("Verify: %s", err)
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	old := newSigningKey(t, "old")
+	rotated := newSigningKey(t, "rotated")
+	rs256 := func(k signingKey) map[string]string { return map[string]string{"alg": "RS256", "kid": k.kid} }
+
+	t.Run("refetches for an unknown kid", func(t *testing.T) {
+		fetches := 0
+		published := jwks(t, old)
+		keys := authorizer.NewKeySetFunc(func() ([]byte, error) {
+			fetches++
+			return published, nil
+		})
+		keys.MinRefresh = 0
+		a := newAuthorizer(keys)
+
+		if _, err := a.Verify(sign(t, old.key, rs256(old), accessClaims())); err != nil {
+			t.Fatalf("verifying with the old key: %s", err)
+		}
+		published = jwks(t, old, rotated)
+		if _, err := a.Verify(sign(t, rotated.key, rs256(rotated), accessClaims())); err != nil {
+			t.Fatalf("verifying with the rotated key: %s", err)
+		}
+		if fetches != 2 {
+			t.Fatalf("fetches = %d, want 2", fetches)
+		}
+		if _, err := a.Verify(sign(t, old.key, rs256(old), accessClaims())); err != nil {
+			t.Fatalf("verifying with the old key again: %s", err)
+		}
+		if fetches != 2 {
+			t.Fatalf("fetches = %d after a cached kid, want 2", fetches)
+		}
+	})
+
+	t.Run("limits refetches for unknown kids", func(t *testing.T) {
+		fetches := 0
+		published := jwks(t, old)
+		keys := authorizer.NewKeySetFunc(func() ([]byte, error) {
+			fetches++
+			return published, nil
+		})
+		a := newAuthorizer(keys)
+
+		if _, err := a.Verify(sign(t, old.key, rs256(old), accessClaims())); err != nil {
+			t.Fatalf("verifying with the old key: %s", err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := a.Verify(sign(t, rotated.key, rs256(rotated), accessClaims())); err != authorizer.ErrUnknownKey {
+				t.Fatalf("error = %v, want %v", err, authorizer.ErrUnknownKey)
+			}
+		}
+		if fetches != 1 {
+			t.Fatalf("fetches = %d, want 1 within MinRefresh", fetches)
+		}
+	})
+
+	t.Run("fails when the JWKS cannot be fetched", func(t *testing.T) {
+		fetches := 0
+		keys := authorizer.NewKeySetFunc(func() ([]byte, error) {
+			fetches++
+			return nil, errors.New("connection refused")
+		})
+		a := newAuthorizer(keys)
+
+		for i := 0; i < 3; i++ {
+			_, err := a.Verify(sign(t, old.key, rs256(old), accessClaims()))
+			if err == nil || !strings.Contains(err.Error(), "connection refused") {
+				t.Fatalf("error = %v, want the fetch failure", err)
+			}
+		}
+		if fetches != 1 {
+			t.Fatalf("fetches = %d, want 1 within MinRefresh of the failure", fetches)
+		}
+	})
+
+	t.Run("fails on an unparsable JWKS", func(t *testing.T) {
+		keys := authorizer.NewKeySetFunc(func() ([]byte, error) {
+			return []byte("<html>"), nil
+		})
+		if _, err := keys.Key(old.kid); err == nil {
+			t.Fatalf("Key succeeded, want an error")
+		}
+	})
+
+	t.Run("keeps cached keys when a refresh fails", func(t *testing.T) {
+		fail := false
+		published := jwks(t, old)
+		keys := authorizer.NewKeySetFunc(func() ([]byte, error) {
+			if fail {
+				return nil, errors.New("connection refused")
+			}
+			return published, nil
+		})
+		keys.MaxAge = 0
+		a := newAuthorizer(keys)
+
+		if _, err := a.Verify(sign(t, old.key, rs256(old), accessClaims())); err != nil {
+			t.Fatalf("verifying with the old key: %s", err)
+		}
+		fail = true
+		if _, err := a.Verify(sign(t, old.key, rs256(old), accessClaims())); err != nil {
+			t.Fatalf("verifying from the cache while the JWKS is down: %s", err)
+		}
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	key := newSigningKey(t, "current")
+	body := jwks(t, key)
+	a := newAuthorizer(authorizer.NewKeySetFunc(func() ([]byte, error) { return body, nil }))
+	token := sign(t, key.key, map[string]string{"alg": "RS256", "kid": key.kid}, accessClaims())
+	arn := "arn:aws:execute-api:ap-southeast-1:123456789012:abcdef/dev/POST/api/v1/users"
+
+	response, err := a.Authorize(eventFor(token, arn))
+	if err != nil {
+		t.Fatalf("Authorize: %s", err)
+	}
+	if response.PrincipalID != "2f1c7b3e-0000-4000-8000-000000000001" {
+		t.Fatalf("principal = %q", response.PrincipalID)
+	}
+	statement := response.PolicyDocument.Statement[0]
+	if statement.Effect != "Allow" || statement.Resource[0] != "arn:aws:execute-api:ap-southeast-1:123456789012:abcdef/dev/*" {
+		t.Fatalf("statement = %+v", statement)
+	}
+	if response.Context["cognito:groups"] != "admin" {
+		t.Fatalf("context groups = %v", response.Context["cognito:groups"])
+	}
+
+	if _, err := a.Authorize(eventFor("Bearer nonsense", arn)); err == nil || err.Error() != "Unauthorized" {
+		t.Fatalf("error = %v, want Unauthorized", err)
+	}
+}
+
+func eventFor(token string, methodArn string) events.APIGatewayCustomAuthorizerRequest {
+	return events.APIGatewayCustomAuthorizerRequest{
+		Type:               "TOKEN",
+		AuthorizationToken: token,
+		MethodArn:          methodArn,
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := authorizer.Policy{
+		"list_user":            {Groups: []string{"admin", "support"}},
+		"list_groups":          {Scopes: []string{"groups/read"}},
+		"list_groups_for_user": {Any: true},
+		"delete_user":          {},
+	}
+	tests := []struct {
+		name   string
+		route  string
+		claims authorizer.Claims
+		want   bool
+	}{
+		{"allows a listed group", "list_user", authorizer.Claims{"cognito:groups": []interface{}{"support"}}, true},
+		{"allows a group from an authorizer context", "list_user", authorizer.Claims{"cognito:groups": "staff,admin"}, true},
+		{"denies another group", "list_user", authorizer.Claims{"cognito:groups": []interface{}{"staff"}}, false},
+		{"denies no group", "list_user", authorizer.Claims{}, false},
+		{"allows a listed scope", "list_groups", authorizer.Claims{"scope": "openid groups/read"}, true},
+		{"denies a missing scope", "list_groups", authorizer.Claims{"scope": "openid"}, false},
+		{"allows any caller under an any rule", "list_groups_for_user", authorizer.Claims{}, true},
+		{"denies everyone under an empty rule", "delete_user", authorizer.Claims{"cognito:groups": []interface{}{"admin"}}, false},
+		{"denies a route without a rule", "create_group", authorizer.Claims{"cognito:groups": []interface{}{"admin"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.Check(test.route, test.claims); got != test.want {
+				t.Fatalf("Check = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPolicyEnforce(t *testing.T) {
+	policy := authorizer.Policy{"list_user": {Groups: []string{"admin"}}}
+	next := func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+	withGroups := func(groups ...interface{}) events.APIGatewayProxyRequest {
+		request := events.APIGatewayProxyRequest{Path: "/api/v1/users"}
+		request.RequestContext.Authorizer = map[string]interface{}{"claims": authorizer.Claims{"cognito:groups": groups}}
+		return request
+	}
+
+	tests := []struct {
+		name    string
+		route   string
+		request events.APIGatewayProxyRequest
+		status  int
+	}{
+		{"serves a member", "list_user", withGroups("admin"), 200},
+		{"forbids a non-member", "list_user", withGroups("staff"), 403},
+		{"forbids a route without a rule", "delete_user", withGroups("admin"), 403},
+		{"requires claims", "list_user", events.APIGatewayProxyRequest{Path: "/api/v1/users"}, 401},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := policy.Enforce(test.route, next)(test.request)
+			if err != nil {
+				t.Fatalf("handler: %s", err)
+			}
+			if response.StatusCode != test.status {
+				t.Fatalf("status = %d (%s), want %d", response.StatusCode, response.Body, test.status)
+			}
+		})
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	support := authorizer.Rule{Groups: []string{"admin", "support"}}
+
+	t.Run("defaults without configuration", func(t *testing.T) {
+		t.Setenv("ROUTE_POLICY", "")
+		t.Setenv("ROUTE_POLICY_FILE", "")
+		policy, err := authorizer.PolicyFromEnv()
+		if err != nil {
+			t.Fatalf("PolicyFromEnv: %s", err)
+		}
+		if len(policy) != len(authorizer.DefaultPolicy()) {
+			t.Fatalf("policy has %d rules, want the %d default ones", len(policy), len(authorizer.DefaultPolicy()))
+		}
+	})
+
+	t.Run("overlays ROUTE_POLICY on the default", func(t *testing.T) {
+		t.Setenv("ROUTE_POLICY", `{"list_user": {"groups": ["admin", "support"]}}`)
+		t.Setenv("ROUTE_POLICY_FILE", "")
+		policy, err := authorizer.PolicyFromEnv()
+		if err != nil {
+			t.Fatalf("PolicyFromEnv: %s", err)
+		}
+		checkOverlay(t, policy, support)
+	})
+
+	t.Run("overlays ROUTE_POLICY_FILE on the default", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		if err := ioutil.WriteFile(path, []byte(`{"list_user": {"groups": ["admin", "support"]}}`), 0600); err != nil {
+			t.Fatalf("writing policy: %s", err)
+		}
+		t.Setenv("ROUTE_POLICY", "")
+		t.Setenv("ROUTE_POLICY_FILE", path)
+		policy, err := authorizer.PolicyFromEnv()
+		if err != nil {
+			t.Fatalf("PolicyFromEnv: %s", err)
+		}
+		checkOverlay(t, policy, support)
+	})
+
+	for _, empty := range []string{`{"delete_user": null}`, `{"delete_user": {}}`, `{"delete_user": {"groups": []}}`} {
+		t.Run("rejects "+empty, func(t *testing.T) {
+			t.Setenv("ROUTE_POLICY", empty)
+			t.Setenv("ROUTE_POLICY_FILE", "")
+			if _, err := authorizer.PolicyFromEnv(); err == nil {
+				t.Fatalf("PolicyFromEnv accepted an empty rule")
+			}
+		})
+	}
+
+	t.Run("opens a route with any", func(t *testing.T) {
+		t.Setenv("ROUTE_POLICY", `{"list_user": {"any": true}}`)
+		t.Setenv("ROUTE_POLICY_FILE", "")
+		policy, err := authorizer.PolicyFromEnv()
+		if err != nil {
+			t.Fatalf("PolicyFromEnv: %s", err)
+		}
+		if !policy.Check("list_user", authorizer.Claims{}) {
+			t.Fatalf("list_user refused a caller in no group")
+		}
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		t.Setenv("ROUTE_POLICY", `{"list_user": [`)
+		t.Setenv("ROUTE_POLICY_FILE", "")
+		if _, err := authorizer.PolicyFromEnv(); err == nil {
+			t.Fatalf("PolicyFromEnv succeeded, want an error")
+		}
+	})
+}
+
+// checkOverlay fails the test unless POLICY gives list_user the rule WANT
+// and keeps the default rules of every other route.
+func checkOverlay(t *testing.T, policy authorizer.Policy, want authorizer.Rule) {
+	t.Helper()
+	if got := policy["list_user"]; strings.Join(got.Groups, ",") != strings.Join(want.Groups, ",") {
+		t.Fatalf("list_user groups = %v, want %v", got.Groups, want.Groups)
+	}
+	for route, rule := range authorizer.DefaultPolicy() {
+		if route == "list_user" {
+			continue
+		}
+		if got, ok := policy[route]; !ok || strings.Join(got.Groups, ",") != strings.Join(rule.Groups, ",") {
+			t.Errorf("%s rule = %+v, want the default %+v", route, got, rule)
+		}
+	}
+	if policy.Check("delete_user", authorizer.Claims{"cognito:groups": []interface{}{"support"}}) {
+		t.Errorf("support may delete users")
+	}
+}
@@ -0,0 +1,194 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/router"
+	"github.com/aws/aws-lambda-go/events"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Rule restricts a route to callers in at least one of Groups, from the
+// cognito:groups claim, and holding at least one of Scopes. An empty list
+// is not checked. Any opens the route to every valid token; without it a
+// rule naming no groups or scopes is open to no one, so a null or empty rule
+// cannot open an admin route by mistake.
+type Rule struct {
+	Groups []string `json:"groups,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	Any bool `json:"any,omitempty"`
+}
+
+// Policy maps route names, as in handler.Routes, to the rule guarding them.
+// Routes without a rule are refused to everyone, so a new route stays closed
+// until it is given one.
+type Policy map[string]Rule
+
+// forbiddenDetails are the extension members of a 403 problem.
+type forbiddenDetails struct {
+	Route          string   `json:"route"`
+	RequiredGroups []string `json:"required_groups,omitempty"`
+	RequiredScopes []string `json:"required_scopes,omitempty"`
+}
+
+// DefaultPolicy keeps pool, user and group administration to the admin
+// group.
+func DefaultPolicy() Policy {
+	admin := Rule{Groups: []string{"admin"}}
+	return Policy{
+		"create_userpool":              admin,
+		"list_userpool":                admin,
+		"list_userpool_client":         admin,
+		"describe_userpool_client":     admin,
+		"create_userpool_client":       admin,
+		"update_userpool_client":       admin,
+		"set_userpool_mfa_config":      admin,
+		"create_user":                  admin,
+		"delete_user":                  admin,
+		"get_user":                     admin,
+		"list_user":                    admin,
+		"import_users":                 admin,
+		"export_users":                 admin,
+		"restore_users":                admin,
+		"bulk_delete_users":            admin,
+		"bulk_disable_users":           admin,
+		"admin_get_user":               admin,
+		"admin_update_user_attributes": admin,
+		"admin_delete_user_attributes": admin,
+		"disable_user":                 admin,
+		"enable_user":                  admin,
+		"admin_global_sign_out":        admin,
+		"lock_user":                    admin,
+		"reset_user_password":          admin,
+		"admin_set_mfa_preference":     admin,
+		"create_group":                 admin,
+		"update_group":                 admin,
+		"delete_group":                 admin,
+		"list_groups":                  admin,
+		"add_user_to_group":            admin,
+		"remove_user_from_group":       admin,
+		"list_users_in_group":          admin,
+		"list_groups_for_user":         admin,
+	}
+}
+
+// PolicyFromEnv reads rules as JSON from the file named by
+// ROUTE_POLICY_FILE or inline from ROUTE_POLICY, e.g.
+// {"list_user": {"groups": ["admin", "support"]}, "list_groups_for_user": {"any": true}},
+// and overlays them on DefaultPolicy, so naming one route leaves the others
+// guarded.
+func PolicyFromEnv() (Policy, error) {
+	var body []byte
+	if path := os.Getenv("ROUTE_POLICY_FILE"); path != "" {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading ROUTE_POLICY_FILE: %s", err)
+		}
+		body = file
+	} else if inline := os.Getenv("ROUTE_POLICY"); inline != "" {
+		body = []byte(inline)
+	}
+
+	policy := DefaultPolicy()
+	if body == nil {
+		return policy, nil
+	}
+	rules, err := ParsePolicy(body)
+	if err != nil {
+		return nil, err
+	}
+	for route, rule := range rules {
+		policy[route] = rule
+	}
+	return policy, nil
+}
+
+// ParsePolicy decodes a JSON route policy, refusing null and empty rules,
+// which would otherwise be read as opening the route to everyone.
+func ParsePolicy(body []byte) (Policy, error) {
+	policy := Policy{}
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return nil, fmt.Errorf("parsing route policy: %s", err)
+	}
+	for route, rule := range policy {
+		if rule.empty() {
+			return nil, fmt.Errorf(`route policy rule for %s names no groups or scopes; use {"any": true} to open it to every signed-in caller`, route)
+		}
+	}
+	return policy, nil
+}
+
+func (r Rule) empty() bool {
+	return !r.Any && len(r.Groups) == 0 && len(r.Scopes) == 0
+}
+
+// Check reports whether CLAIMS satisfy the rule for ROUTE. No claims
+// satisfy a route without a rule.
+func (p Policy) Check(route string, claims Claims) bool {
+	rule, ok := p[route]
+	if !ok || rule.empty() {
+		return false
+	}
+	return containsAny(claims.Groups(), rule.Groups) && containsAny(claims.Scopes(), rule.Scopes)
+}
+
+// Enforce answers 403 when the claims attached to the request, by Wrap or by
+// an API Gateway authorizer, do not satisfy the rule for ROUTE.
+func (p Policy) Enforce(route string, next router.HandlerFunc) router.HandlerFunc {
+	rule := p[route]
+	return func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		claims, ok := ClaimsFromRequest(request)
+		if !ok {
+			return Unauthorized(request, ErrMissingToken.Error()), nil
+		}
+		if !p.Check(route, claims) {
+			return Forbidden(request, route, rule), nil
+		}
+		return next(request)
+	}
+}
+
+// Forbidden is the 403 response to REQUEST for a caller the rule for ROUTE
+// turns away, or for any caller when ROUTE has no rule.
+func Forbidden(request events.APIGatewayProxyRequest, route string, rule Rule) events.APIGatewayProxyResponse {
+	var needs []string
+	if len(rule.Groups) > 0 {
+		needs = append(needs, "membership of one of the groups "+strings.Join(rule.Groups, ", "))
+	}
+	if len(rule.Scopes) > 0 {
+		needs = append(needs, "one of the scopes "+strings.Join(rule.Scopes, ", "))
+	}
+	details := forbiddenDetails{
+		Route:          route,
+		RequiredGroups: rule.Groups,
+		RequiredScopes: rule.Scopes,
+	}
+	message := fmt.Sprintf("%s has no route policy rule and is open to no one", route)
+	if len(needs) > 0 {
+		message = fmt.Sprintf("%s requires %s", route, strings.Join(needs, " and "))
+	}
+	err := apierror.New(403, apierror.CodeForbidden, message)
+	body, status := apierror.ProblemBody(err, request.Path, details)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": apierror.ProblemContentType},
+		Body:       body,
+	}
+}
+
+func containsAny(have []string, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+func (c *Cognito) ChangePassword(input *cognitoidentityprovider.ChangePasswordInput) (*cognitoidentityprovider.ChangePasswordOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(input.PreviousPassword) != u.password {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Incorrect username or password.")
+	}
+	if err := p.checkPassword(aws.StringValue(input.ProposedPassword)); err != nil {
+		return nil, err
+	}
+	u.password = aws.StringValue(input.ProposedPassword)
+	u.modified = c.Now()
+	return &cognitoidentityprovider.ChangePasswordOutput{}, nil
+}
+
+// UpdateUserAttributes marks a changed email or phone_number unverified and
+// sends a code to verify it, as a pool that auto-verifies them does.
+func (c *Cognito) UpdateUserAttributes(input *cognitoidentityprovider.UpdateUserAttributesInput) (*cognitoidentityprovider.UpdateUserAttributesOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkWritable(input.UserAttributes); err != nil {
+		return nil, err
+	}
+
+	output := &cognitoidentityprovider.UpdateUserAttributesOutput{}
+	for _, a := range input.UserAttributes {
+		name, value := aws.StringValue(a.Name), aws.StringValue(a.Value)
+		changed := u.attribute(name) != value
+		u.setAttribute(name, value)
+		if changed && (name == "email" || name == "phone_number") {
+			u.setAttribute(name+"_verified", "false")
+			output.CodeDeliveryDetailsList = append(output.CodeDeliveryDetailsList, c.sendAttributeCode(u, name))
+		}
+	}
+	u.modified = c.Now()
+	return output, nil
+}
+
+func (c *Cognito) GetUserAttributeVerificationCode(input *cognitoidentityprovider.GetUserAttributeVerificationCodeInput) (*cognitoidentityprovider.GetUserAttributeVerificationCodeOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.StringValue(input.AttributeName)
+	if (name != "email" && name != "phone_number") || u.attribute(name) == "" {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Cannot send a verification code for attribute "+name)
+	}
+	return &cognitoidentityprovider.GetUserAttributeVerificationCodeOutput{
+		CodeDeliveryDetails: c.sendAttributeCode(u, name),
+	}, nil
+}
+
+func (c *Cognito) VerifyUserAttribute(input *cognitoidentityprovider.VerifyUserAttributeInput) (*cognitoidentityprovider.VerifyUserAttributeOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.StringValue(input.AttributeName)
+	code, ok := u.attributeCodes[name]
+	if !ok || code != aws.StringValue(input.Code) {
+		return nil, newError(cognitoidentityprovider.ErrCodeCodeMismatchException,
+			"Invalid verification code provided, please try again.")
+	}
+	if c.Now().After(u.codeExpiry) {
+		return nil, newError(cognitoidentityprovider.ErrCodeExpiredCodeException,
+			"Invalid code provided, please request a code again.")
+	}
+	delete(u.attributeCodes, name)
+	u.setAttribute(name+"_verified", "true")
+	u.modified = c.Now()
+	return &cognitoidentityprovider.VerifyUserAttributeOutput{}, nil
+}
+
+func (c *Cognito) GlobalSignOut(input *cognitoidentityprovider.GlobalSignOutInput) (*cognitoidentityprovider.GlobalSignOutOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	c.signOut(p, u)
+	return &cognitoidentityprovider.GlobalSignOutOutput{}, nil
+}
+
+func (c *Cognito) DeleteUser(input *cognitoidentityprovider.DeleteUserInput) (*cognitoidentityprovider.DeleteUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	c.signOut(p, u)
+	delete(p.users, u.username)
+	for _, g := range p.groups {
+		delete(g.members, u.username)
+	}
+	return &cognitoidentityprovider.DeleteUserOutput{}, nil
+}
+
+// sendAttributeCode records a new code for verifying the email or
+// phone_number attribute NAME.
+func (c *Cognito) sendAttributeCode(u *user, name string) *cognitoidentityprovider.CodeDeliveryDetailsType {
+	if u.attributeCodes == nil {
+		u.attributeCodes = map[string]string{}
+	}
+	u.attributeCodes[name] = randomString(digits, 6)
+	u.codeExpiry = c.Now().Add(c.CodeTTL)
+
+	details := &cognitoidentityprovider.CodeDeliveryDetailsType{AttributeName: aws.String(name)}
+	if name == "phone_number" {
+		details.DeliveryMedium = aws.String(cognitoidentityprovider.DeliveryMediumTypeSms)
+		details.Destination = aws.String(maskPhone(u.attribute(name)))
+	} else {
+		details.DeliveryMedium = aws.String(cognitoidentityprovider.DeliveryMediumTypeEmail)
+		details.Destination = aws.String(maskEmail(u.attribute(name)))
+	}
+	return details
+}
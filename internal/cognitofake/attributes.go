@@ -0,0 +1,176 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+// standardAttributes are the attributes every pool has, in the order
+// DescribeUserPool lists them.
+var standardAttributes = []string{
+	"sub", "name", "given_name", "family_name", "middle_name", "nickname",
+	"preferred_username", "profile", "picture", "website", "email",
+	"email_verified", "gender", "birthdate", "zoneinfo", "locale",
+	"phone_number", "phone_number_verified", "address", "updated_at",
+}
+
+func (c *Cognito) AdminGetUser(input *cognitoidentityprovider.AdminGetUserInput) (*cognitoidentityprovider.AdminGetUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	userType := u.toUserType()
+	output := &cognitoidentityprovider.AdminGetUserOutput{
+		Enabled:              userType.Enabled,
+		UserAttributes:       userType.Attributes,
+		UserCreateDate:       userType.UserCreateDate,
+		UserLastModifiedDate: userType.UserLastModifiedDate,
+		UserStatus:           userType.UserStatus,
+		Username:             userType.Username,
+	}
+	if u.totpEnabled {
+		output.UserMFASettingList = []*string{aws.String(cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa)}
+		if u.totpPreferred {
+			output.PreferredMfaSetting = aws.String(cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa)
+		}
+	}
+	return output, nil
+}
+
+func (c *Cognito) AdminDeleteUserAttributes(input *cognitoidentityprovider.AdminDeleteUserAttributesInput) (*cognitoidentityprovider.AdminDeleteUserAttributesOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range input.UserAttributeNames {
+		attribute, err := p.schemaAttribute(aws.StringValue(name))
+		if err != nil {
+			return nil, err
+		}
+		if aws.BoolValue(attribute.Required) {
+			return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"Cannot delete required attribute "+aws.StringValue(name)+".")
+		}
+	}
+	for _, name := range input.UserAttributeNames {
+		u.deleteAttribute(aws.StringValue(name))
+	}
+	u.modified = c.Now()
+	return &cognitoidentityprovider.AdminDeleteUserAttributesOutput{}, nil
+}
+
+// poolSchema expands the schema given to CreateUserPool the way Cognito
+// does: every standard attribute is present, entries naming one override
+// it, and the rest become custom: attributes.
+func poolSchema(input []*cognitoidentityprovider.SchemaAttributeType) []*cognitoidentityprovider.SchemaAttributeType {
+	var schema []*cognitoidentityprovider.SchemaAttributeType
+	index := map[string]int{}
+	for _, name := range standardAttributes {
+		attribute := &cognitoidentityprovider.SchemaAttributeType{
+			AttributeDataType:      aws.String(cognitoidentityprovider.AttributeDataTypeString),
+			DeveloperOnlyAttribute: aws.Bool(false),
+			Mutable:                aws.Bool(name != "sub"),
+			Name:                   aws.String(name),
+			Required:               aws.Bool(name == "sub"),
+			StringAttributeConstraints: &cognitoidentityprovider.StringAttributeConstraintsType{
+				MaxLength: aws.String("2048"),
+				MinLength: aws.String("0"),
+			},
+		}
+		switch name {
+		case "email_verified", "phone_number_verified":
+			attribute.AttributeDataType = aws.String(cognitoidentityprovider.AttributeDataTypeBoolean)
+			attribute.StringAttributeConstraints = nil
+		case "updated_at":
+			attribute.AttributeDataType = aws.String(cognitoidentityprovider.AttributeDataTypeNumber)
+			attribute.StringAttributeConstraints = nil
+			attribute.NumberAttributeConstraints = &cognitoidentityprovider.NumberAttributeConstraintsType{
+				MinValue: aws.String("0"),
+			}
+		case "birthdate":
+			attribute.StringAttributeConstraints.MaxLength = aws.String("10")
+			attribute.StringAttributeConstraints.MinLength = aws.String("10")
+		}
+		index[name] = len(schema)
+		schema = append(schema, attribute)
+	}
+
+	for _, given := range input {
+		attribute := *given
+		name := aws.StringValue(attribute.Name)
+		if i, ok := index[name]; ok {
+			schema[i] = &attribute
+			continue
+		}
+		if aws.BoolValue(attribute.DeveloperOnlyAttribute) {
+			attribute.Name = aws.String("dev:custom:" + name)
+		} else {
+			attribute.Name = aws.String("custom:" + name)
+		}
+		schema = append(schema, &attribute)
+	}
+	return schema
+}
+
+// schemaAttribute looks NAME up in the pool schema. Developer-only attributes
+// can be addressed with or without their dev: prefix.
+func (p *pool) schemaAttribute(name string) (*cognitoidentityprovider.SchemaAttributeType, error) {
+	for _, attribute := range p.SchemaAttributes {
+		schemaName := aws.StringValue(attribute.Name)
+		if schemaName == name || schemaName == "dev:"+name {
+			return attribute, nil
+		}
+	}
+	return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+		"Attribute does not exist in the schema.")
+}
+
+// checkWritable rejects attributes missing from the schema and changes to
+// immutable ones.
+func (p *pool) checkWritable(attributes []*cognitoidentityprovider.AttributeType) error {
+	for _, a := range attributes {
+		name := aws.StringValue(a.Name)
+		if name == "sub" {
+			return newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"Cannot modify an already provided sub attribute")
+		}
+		attribute, err := p.schemaAttribute(name)
+		if err != nil {
+			return err
+		}
+		if !aws.BoolValue(attribute.Mutable) {
+			return newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"Cannot modify the non-mutable attribute "+name)
+		}
+	}
+	return nil
+}
+
+func (u *user) deleteAttribute(name string) {
+	for i, a := range u.attributes {
+		if aws.StringValue(a.Name) == name {
+			u.attributes = append(u.attributes[:i], u.attributes[i+1:]...)
+			return
+		}
+	}
+}
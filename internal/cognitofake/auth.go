@@ -0,0 +1,342 @@
+package cognitofake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+func (c *Cognito) InitiateAuth(input *cognitoidentityprovider.InitiateAuthInput) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch flow := aws.StringValue(input.AuthFlow); flow {
+	case cognitoidentityprovider.AuthFlowTypeUserPasswordAuth:
+		if !allowsFlow(client, flow) {
+			return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"USER_PASSWORD_AUTH flow not enabled for this client")
+		}
+		return c.passwordAuth(client, p, input.AuthParameters)
+	case cognitoidentityprovider.AuthFlowTypeRefreshTokenAuth, cognitoidentityprovider.AuthFlowTypeRefreshToken:
+		return c.refreshAuth(client, p, input.AuthParameters)
+	default:
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Unsupported auth flow "+flow)
+	}
+}
+
+func (c *Cognito) passwordAuth(client *cognitoidentityprovider.UserPoolClientType, p *pool, parameters map[string]*string) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+	username := parameters["USERNAME"]
+	if aws.StringValue(username) == "" {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Missing required parameter USERNAME")
+	}
+	if err := checkSecretHash(client, username, parameters["SECRET_HASH"]); err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(parameters["PASSWORD"]) != u.password {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Incorrect username or password.")
+	}
+	if !u.enabled {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "User is disabled.")
+	}
+	if u.status == cognitoidentityprovider.UserStatusTypeResetRequired {
+		return nil, newError(cognitoidentityprovider.ErrCodePasswordResetRequiredException, "Password reset required for the user")
+	}
+	if u.status == cognitoidentityprovider.UserStatusTypeUnconfirmed {
+		return nil, newError(cognitoidentityprovider.ErrCodeUserNotConfirmedException, "User is not confirmed.")
+	}
+
+	if u.status == cognitoidentityprovider.UserStatusTypeForceChangePassword {
+		return c.newChallenge(client, p, u, cognitoidentityprovider.ChallengeNameTypeNewPasswordRequired, map[string]*string{
+			"USER_ID_FOR_SRP":    aws.String(u.username),
+			"requiredAttributes": aws.String("[]"),
+			"userAttributes":     aws.String("{}"),
+		}), nil
+	}
+	return c.authenticated(client, p, u)
+}
+
+// authenticated finishes a sign-in once the password is settled: it asks for
+// a TOTP code, or for TOTP to be set up, when the pool requires it, and
+// otherwise issues tokens.
+func (c *Cognito) authenticated(client *cognitoidentityprovider.UserPoolClientType, p *pool, u *user) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+	if aws.StringValue(p.MfaConfiguration) != cognitoidentityprovider.UserPoolMfaTypeOff && p.softwareTokenMfa {
+		if u.totpEnabled {
+			return c.newChallenge(client, p, u, cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa, map[string]*string{
+				"USER_ID_FOR_SRP": aws.String(u.username),
+			}), nil
+		}
+		if aws.StringValue(p.MfaConfiguration) == cognitoidentityprovider.UserPoolMfaTypeOn {
+			return c.newChallenge(client, p, u, cognitoidentityprovider.ChallengeNameTypeMfaSetup, map[string]*string{
+				"MFAS_CAN_SETUP": aws.String(`["SOFTWARE_TOKEN_MFA"]`),
+			}), nil
+		}
+	}
+
+	result, err := c.issueTokens(client, p, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.InitiateAuthOutput{
+		AuthenticationResult: result,
+		ChallengeParameters:  map[string]*string{},
+	}, nil
+}
+
+// newChallenge records a session for the challenge so RespondToAuthChallenge
+// can pick up where the sign-in stopped.
+func (c *Cognito) newChallenge(client *cognitoidentityprovider.UserPoolClientType, p *pool, u *user, name string, parameters map[string]*string) *cognitoidentityprovider.InitiateAuthOutput {
+	token := randomString(alphanumeric, 64)
+	c.session[token] = &session{
+		poolID:    aws.StringValue(p.Id),
+		clientID:  aws.StringValue(client.ClientId),
+		username:  u.username,
+		challenge: name,
+		expiry:    c.Now().Add(c.CodeTTL),
+	}
+	return &cognitoidentityprovider.InitiateAuthOutput{
+		ChallengeName:       aws.String(name),
+		ChallengeParameters: parameters,
+		Session:             aws.String(token),
+	}
+}
+
+func (c *Cognito) refreshAuth(client *cognitoidentityprovider.UserPoolClientType, p *pool, parameters map[string]*string) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+	refresh, ok := c.refresh[aws.StringValue(parameters["REFRESH_TOKEN"])]
+	if !ok || refresh.clientID != aws.StringValue(client.ClientId) {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid Refresh Token")
+	}
+	if c.Now().After(refresh.expiry) {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Refresh Token has expired")
+	}
+	u, ok := p.users[refresh.username]
+	if !ok {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid Refresh Token")
+	}
+	if err := checkSecretHash(client, aws.String(u.username), parameters["SECRET_HASH"]); err != nil {
+		return nil, err
+	}
+	if !u.enabled {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "User is disabled.")
+	}
+
+	result, err := c.issueTokens(client, p, u, refresh)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.InitiateAuthOutput{
+		AuthenticationResult: result,
+		ChallengeParameters:  map[string]*string{},
+	}, nil
+}
+
+// RespondToAuthChallenge answers the NEW_PASSWORD_REQUIRED challenge issued to
+// users created with a temporary password and the SOFTWARE_TOKEN_MFA and
+// MFA_SETUP challenges issued by pools with TOTP MFA.
+func (c *Cognito) RespondToAuthChallenge(input *cognitoidentityprovider.RespondToAuthChallengeInput) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	challenge, ok := c.session[aws.StringValue(input.Session)]
+	if !ok || challenge.clientID != aws.StringValue(client.ClientId) || challenge.challenge != aws.StringValue(input.ChallengeName) {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid session for the user.")
+	}
+	if c.Now().After(challenge.expiry) {
+		delete(c.session, aws.StringValue(input.Session))
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid session for the user, session is expired.")
+	}
+
+	responses := input.ChallengeResponses
+	if aws.StringValue(responses["USERNAME"]) != challenge.username {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid session for the user.")
+	}
+	if err := checkSecretHash(client, responses["USERNAME"], responses["SECRET_HASH"]); err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(responses["USERNAME"])
+	if err != nil {
+		return nil, err
+	}
+
+	switch aws.StringValue(input.ChallengeName) {
+	case cognitoidentityprovider.ChallengeNameTypeNewPasswordRequired:
+		password := aws.StringValue(responses["NEW_PASSWORD"])
+		if password == "" {
+			return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"Missing required parameter NEW_PASSWORD")
+		}
+		if err := p.checkPassword(password); err != nil {
+			return nil, err
+		}
+		u.password = password
+		u.status = cognitoidentityprovider.UserStatusTypeConfirmed
+		u.modified = c.Now()
+
+		delete(c.session, aws.StringValue(input.Session))
+		next, err := c.authenticated(client, p, u)
+		if err != nil {
+			return nil, err
+		}
+		return &cognitoidentityprovider.RespondToAuthChallengeOutput{
+			AuthenticationResult: next.AuthenticationResult,
+			ChallengeName:        next.ChallengeName,
+			ChallengeParameters:  next.ChallengeParameters,
+			Session:              next.Session,
+		}, nil
+	case cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa:
+		if !checkTOTP(u.totpSecret, aws.StringValue(responses["SOFTWARE_TOKEN_MFA_CODE"]), c.Now()) {
+			return nil, newError(cognitoidentityprovider.ErrCodeCodeMismatchException, "Invalid code received for user")
+		}
+	case cognitoidentityprovider.ChallengeNameTypeMfaSetup:
+		if !u.totpEnabled {
+			return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"Software token MFA has not been set up, call VerifySoftwareToken first")
+		}
+	default:
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Unsupported challenge "+aws.StringValue(input.ChallengeName))
+	}
+
+	delete(c.session, aws.StringValue(input.Session))
+	result, err := c.issueTokens(client, p, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.RespondToAuthChallengeOutput{
+		AuthenticationResult: result,
+		ChallengeParameters:  map[string]*string{},
+	}, nil
+}
+
+// RevokeToken ends the refresh session of a refresh token, invalidating the
+// ID and access tokens issued from it. Unknown tokens are accepted, so
+// revoking twice succeeds.
+func (c *Cognito) RevokeToken(input *cognitoidentityprovider.RevokeTokenInput) (*cognitoidentityprovider.RevokeTokenOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, ok := c.clients[aws.StringValue(input.ClientId)]
+	if !ok {
+		return nil, newError(cognitoidentityprovider.ErrCodeResourceNotFoundException, "Client does not exist.")
+	}
+	if secret := aws.StringValue(client.ClientSecret); secret != "" && secret != aws.StringValue(input.ClientSecret) {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException,
+			"Unable to verify secret for client "+aws.StringValue(client.ClientId))
+	}
+	if !aws.BoolValue(client.EnableTokenRevocation) {
+		return nil, newError(cognitoidentityprovider.ErrCodeUnsupportedOperationException,
+			"Token revocation is not enabled for this client.")
+	}
+
+	token := aws.StringValue(input.Token)
+	refresh, ok := c.refresh[token]
+	if !ok {
+		return &cognitoidentityprovider.RevokeTokenOutput{}, nil
+	}
+	if refresh.clientID != aws.StringValue(client.ClientId) {
+		return nil, newError(cognitoidentityprovider.ErrCodeUnauthorizedException,
+			"Token was not issued to this client.")
+	}
+	delete(c.refresh, token)
+	return &cognitoidentityprovider.RevokeTokenOutput{}, nil
+}
+
+// issueTokens signs a fresh ID and access token for the user. Without a
+// REFRESH session it starts a new one and returns its opaque refresh token;
+// with one, the tokens share its origin_jti so revoking the refresh token or
+// signing the user out invalidates them too.
+func (c *Cognito) issueTokens(client *cognitoidentityprovider.UserPoolClientType, p *pool, u *user, refresh *session) (*cognitoidentityprovider.AuthenticationResultType, error) {
+	now := c.Now()
+	var refreshToken string
+	if refresh == nil {
+		refreshToken = randomString(alphanumeric, 128)
+		refresh = &session{
+			poolID:    aws.StringValue(p.Id),
+			clientID:  aws.StringValue(client.ClientId),
+			username:  u.username,
+			originJTI: newUUID(),
+			expiry:    now.AddDate(0, 0, int(aws.Int64Value(client.RefreshTokenValidity))),
+		}
+		c.refresh[refreshToken] = refresh
+	}
+
+	idToken, err := c.sign(c.idClaims(client, p, u, refresh.originJTI, now))
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := c.sign(c.accessClaims(client, p, u, refresh.originJTI, now))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &cognitoidentityprovider.AuthenticationResultType{
+		AccessToken: aws.String(accessToken),
+		ExpiresIn:   aws.Int64(int64(c.TokenTTL.Seconds())),
+		IdToken:     aws.String(idToken),
+		TokenType:   aws.String("Bearer"),
+	}
+	if refreshToken != "" {
+		result.RefreshToken = aws.String(refreshToken)
+	}
+	return result, nil
+}
+
+// signOut ends every refresh session of the user, which also invalidates the
+// ID and access tokens issued from them.
+func (c *Cognito) signOut(p *pool, u *user) {
+	for token, refresh := range c.refresh {
+		if refresh.poolID == aws.StringValue(p.Id) && refresh.username == u.username {
+			delete(c.refresh, token)
+		}
+	}
+}
+
+func allowsFlow(client *cognitoidentityprovider.UserPoolClientType, flow string) bool {
+	for _, allowed := range aws.StringValueSlice(client.ExplicitAuthFlows) {
+		if allowed == flow || allowed == "ALLOW_"+flow {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecretHash enforces SECRET_HASH on clients created with a secret.
+func checkSecretHash(client *cognitoidentityprovider.UserPoolClientType, username *string, secretHash *string) error {
+	secret := aws.StringValue(client.ClientSecret)
+	if secret == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(aws.StringValue(username) + aws.StringValue(client.ClientId)))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(aws.StringValue(secretHash))) {
+		return newError(cognitoidentityprovider.ErrCodeNotAuthorizedException,
+			"Unable to verify secret hash for client "+aws.StringValue(client.ClientId))
+	}
+	return nil
+}
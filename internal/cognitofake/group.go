@@ -0,0 +1,277 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"sort"
+)
+
+func (c *Cognito) CreateGroup(input *cognitoidentityprovider.CreateGroupInput) (*cognitoidentityprovider.CreateGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.StringValue(input.GroupName)
+	if _, exists := p.groups[name]; exists {
+		return nil, newError(cognitoidentityprovider.ErrCodeGroupExistsException,
+			"A group with the name "+name+" already exists.")
+	}
+
+	now := c.Now()
+	g := &group{
+		GroupType: &cognitoidentityprovider.GroupType{
+			CreationDate:     aws.Time(now),
+			Description:      input.Description,
+			GroupName:        aws.String(name),
+			LastModifiedDate: aws.Time(now),
+			Precedence:       input.Precedence,
+			RoleArn:          input.RoleArn,
+			UserPoolId:       p.Id,
+		},
+		members: map[string]bool{},
+	}
+	p.groups[name] = g
+	return &cognitoidentityprovider.CreateGroupOutput{Group: copyGroup(g)}, nil
+}
+
+func (c *Cognito) GetGroup(input *cognitoidentityprovider.GetGroupInput) (*cognitoidentityprovider.GetGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, err := c.findGroup(input.UserPoolId, input.GroupName)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.GetGroupOutput{Group: copyGroup(g)}, nil
+}
+
+// UpdateGroup replaces the description, precedence and role, clearing those
+// missing from the input just as Cognito does.
+func (c *Cognito) UpdateGroup(input *cognitoidentityprovider.UpdateGroupInput) (*cognitoidentityprovider.UpdateGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, err := c.findGroup(input.UserPoolId, input.GroupName)
+	if err != nil {
+		return nil, err
+	}
+	g.Description = input.Description
+	g.Precedence = input.Precedence
+	g.RoleArn = input.RoleArn
+	g.LastModifiedDate = aws.Time(c.Now())
+	return &cognitoidentityprovider.UpdateGroupOutput{Group: copyGroup(g)}, nil
+}
+
+func (c *Cognito) DeleteGroup(input *cognitoidentityprovider.DeleteGroupInput) (*cognitoidentityprovider.DeleteGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.findGroup(input.UserPoolId, input.GroupName); err != nil {
+		return nil, err
+	}
+	delete(c.pools[aws.StringValue(input.UserPoolId)].groups, aws.StringValue(input.GroupName))
+	return &cognitoidentityprovider.DeleteGroupOutput{}, nil
+}
+
+func (c *Cognito) ListGroups(input *cognitoidentityprovider.ListGroupsInput) (*cognitoidentityprovider.ListGroupsOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	limit, err := pageLimit(input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range p.groups {
+		names = append(names, name)
+	}
+	page, next := paginate(names, aws.StringValue(input.NextToken), limit)
+
+	output := &cognitoidentityprovider.ListGroupsOutput{Groups: []*cognitoidentityprovider.GroupType{}}
+	for _, name := range page {
+		output.Groups = append(output.Groups, copyGroup(p.groups[name]))
+	}
+	if next != "" {
+		output.NextToken = aws.String(next)
+	}
+	return output, nil
+}
+
+func (c *Cognito) AdminAddUserToGroup(input *cognitoidentityprovider.AdminAddUserToGroupInput) (*cognitoidentityprovider.AdminAddUserToGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, u, err := c.findMembership(input.UserPoolId, input.GroupName, input.Username)
+	if err != nil {
+		return nil, err
+	}
+	g.members[u.username] = true
+	return &cognitoidentityprovider.AdminAddUserToGroupOutput{}, nil
+}
+
+func (c *Cognito) AdminRemoveUserFromGroup(input *cognitoidentityprovider.AdminRemoveUserFromGroupInput) (*cognitoidentityprovider.AdminRemoveUserFromGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, u, err := c.findMembership(input.UserPoolId, input.GroupName, input.Username)
+	if err != nil {
+		return nil, err
+	}
+	delete(g.members, u.username)
+	return &cognitoidentityprovider.AdminRemoveUserFromGroupOutput{}, nil
+}
+
+func (c *Cognito) AdminListGroupsForUser(input *cognitoidentityprovider.AdminListGroupsForUserInput) (*cognitoidentityprovider.AdminListGroupsForUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	limit, err := pageLimit(input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	page, next := paginate(p.groupsOf(u), aws.StringValue(input.NextToken), limit)
+
+	output := &cognitoidentityprovider.AdminListGroupsForUserOutput{Groups: []*cognitoidentityprovider.GroupType{}}
+	for _, name := range page {
+		output.Groups = append(output.Groups, copyGroup(p.groups[name]))
+	}
+	if next != "" {
+		output.NextToken = aws.String(next)
+	}
+	return output, nil
+}
+
+func (c *Cognito) ListUsersInGroup(input *cognitoidentityprovider.ListUsersInGroupInput) (*cognitoidentityprovider.ListUsersInGroupOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	limit, err := pageLimit(input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, err := c.findGroup(input.UserPoolId, input.GroupName)
+	if err != nil {
+		return nil, err
+	}
+	var usernames []string
+	for username := range g.members {
+		usernames = append(usernames, username)
+	}
+	page, next := paginate(usernames, aws.StringValue(input.NextToken), limit)
+
+	p := c.pools[aws.StringValue(input.UserPoolId)]
+	output := &cognitoidentityprovider.ListUsersInGroupOutput{Users: []*cognitoidentityprovider.UserType{}}
+	for _, username := range page {
+		output.Users = append(output.Users, p.users[username].toUserType())
+	}
+	if next != "" {
+		output.NextToken = aws.String(next)
+	}
+	return output, nil
+}
+
+func (c *Cognito) findGroup(poolID *string, name *string) (*group, error) {
+	p, err := c.findPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+	g, ok := p.groups[aws.StringValue(name)]
+	if !ok {
+		return nil, newError(cognitoidentityprovider.ErrCodeResourceNotFoundException, "Group not found.")
+	}
+	return g, nil
+}
+
+func (c *Cognito) findMembership(poolID *string, name *string, username *string) (*group, *user, error) {
+	g, err := c.findGroup(poolID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := c.pools[aws.StringValue(poolID)].findUser(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, u, nil
+}
+
+// groupsOf returns the names of the user's groups, in the precedence order
+// Cognito uses for the cognito:groups claim: lowest precedence first, groups
+// without one last.
+func (p *pool) groupsOf(u *user) []string {
+	var names []string
+	for name, g := range p.groups {
+		if g.members[u.username] {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := p.groups[names[i]].Precedence, p.groups[names[j]].Precedence
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func copyGroup(g *group) *cognitoidentityprovider.GroupType {
+	copied := *g.GroupType
+	return &copied
+}
+
+// pageLimit applies Cognito's default and maximum page size of 60.
+func pageLimit(limit *int64) (int, error) {
+	if aws.Int64Value(limit) > 60 {
+		return 0, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"1 validation error detected: Value at 'limit' failed to satisfy constraint: Member must have value less than or equal to 60")
+	}
+	if aws.Int64Value(limit) == 0 {
+		return 60, nil
+	}
+	return int(aws.Int64Value(limit)), nil
+}
@@ -0,0 +1,55 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"sync"
+	"time"
+)
+
+// IAM emulates the role creation userpool.Config performs before creating a
+// pool with an SMS configuration. Other IAM operations fail with
+// ErrCodeNotImplemented.
+type IAM struct {
+	iamiface.IAMAPI
+
+	mu    sync.Mutex
+	roles map[string]*iam.Role
+}
+
+// NewIAM returns an IAM emulator with no roles.
+func NewIAM() *IAM {
+	return &IAM{IAMAPI: unimplementedIAM(), roles: map[string]*iam.Role{}}
+}
+
+func (i *IAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	name := aws.StringValue(input.RoleName)
+	if _, ok := i.roles[name]; ok {
+		return nil, awserr.NewRequestFailure(awserr.New(iam.ErrCodeEntityAlreadyExistsException,
+			"Role with name "+name+" already exists.", nil), 409, newUUID())
+	}
+
+	path := aws.StringValue(input.Path)
+	if path == "" {
+		path = "/"
+	}
+	role := &iam.Role{
+		Arn:                      aws.String("arn:aws:iam::000000000000:role" + path + name),
+		AssumeRolePolicyDocument: input.AssumeRolePolicyDocument,
+		CreateDate:               aws.Time(time.Now()),
+		Description:              input.Description,
+		Path:                     aws.String(path),
+		RoleId:                   aws.String("AROA" + randomString("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", 17)),
+		RoleName:                 aws.String(name),
+	}
+	i.roles[name] = role
+	return &iam.CreateRoleOutput{Role: role}, nil
+}
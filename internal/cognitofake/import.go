@@ -0,0 +1,198 @@
+package cognitofake
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+type importJob struct {
+	*cognitoidentityprovider.UserImportJobType
+	csv []byte
+}
+
+// GetCSVHeader lists the pool's attributes that an import CSV may carry, in
+// Cognito's order: the standard ones, then cognito:mfa_enabled and
+// cognito:username, then custom attributes.
+func (c *Cognito) GetCSVHeader(input *cognitoidentityprovider.GetCSVHeaderInput) (*cognitoidentityprovider.GetCSVHeaderOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	var header, custom []string
+	for _, attribute := range p.SchemaAttributes {
+		name := aws.StringValue(attribute.Name)
+		switch {
+		case name == "sub" || strings.HasPrefix(name, "dev:"):
+		case strings.HasPrefix(name, "custom:"):
+			custom = append(custom, name)
+		default:
+			header = append(header, name)
+		}
+	}
+	header = append(append(header, "cognito:mfa_enabled", "cognito:username"), custom...)
+	return &cognitoidentityprovider.GetCSVHeaderOutput{
+		CSVHeader:  aws.StringSlice(header),
+		UserPoolId: p.Id,
+	}, nil
+}
+
+func (c *Cognito) CreateUserImportJob(input *cognitoidentityprovider.CreateUserImportJobInput) (*cognitoidentityprovider.CreateUserImportJobOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	id := "import-" + randomString(alphanumeric, 10)
+	base := c.ImportURL
+	if base == "" {
+		base = "http://localhost:8080/local/import"
+	}
+	job := &importJob{UserImportJobType: &cognitoidentityprovider.UserImportJobType{
+		CloudWatchLogsRoleArn: input.CloudWatchLogsRoleArn,
+		CreationDate:          aws.Time(c.Now()),
+		FailedUsers:           aws.Int64(0),
+		ImportedUsers:         aws.Int64(0),
+		JobId:                 aws.String(id),
+		JobName:               input.JobName,
+		PreSignedUrl:          aws.String(strings.TrimSuffix(base, "/") + "/" + id),
+		SkippedUsers:          aws.Int64(0),
+		Status:                aws.String(cognitoidentityprovider.UserImportJobStatusTypeCreated),
+		UserPoolId:            p.Id,
+	}}
+	c.imports[id] = job
+	return &cognitoidentityprovider.CreateUserImportJobOutput{UserImportJob: copyImportJob(job)}, nil
+}
+
+// StartUserImportJob imports the uploaded CSV straight away. Users are
+// created in RESET_REQUIRED, as Cognito does, and rows naming an existing
+// user or lacking a username count as failed.
+func (c *Cognito) StartUserImportJob(input *cognitoidentityprovider.StartUserImportJobInput) (*cognitoidentityprovider.StartUserImportJobOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, job, err := c.findImportJob(input.UserPoolId, input.JobId)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(job.Status) != cognitoidentityprovider.UserImportJobStatusTypeCreated {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Import job "+aws.StringValue(job.JobId)+" has already been started.")
+	}
+	if job.csv == nil {
+		return nil, newError(cognitoidentityprovider.ErrCodePreconditionNotMetException,
+			"No CSV file has been uploaded for import job "+aws.StringValue(job.JobId)+".")
+	}
+
+	now := c.Now()
+	job.StartDate = aws.Time(now)
+	reader := csv.NewReader(bytes.NewReader(job.csv))
+	header, err := reader.Read()
+	if err != nil {
+		job.Status = aws.String(cognitoidentityprovider.UserImportJobStatusTypeFailed)
+		job.CompletionMessage = aws.String("The CSV file has no header.")
+		return &cognitoidentityprovider.StartUserImportJobOutput{UserImportJob: copyImportJob(job)}, nil
+	}
+	var imported, failed int64
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			failed++
+			continue
+		}
+		u := &user{
+			status:   cognitoidentityprovider.UserStatusTypeResetRequired,
+			enabled:  true,
+			created:  now,
+			modified: now,
+		}
+		u.setAttribute("sub", newUUID())
+		for i, value := range record {
+			switch {
+			case i >= len(header) || value == "" || header[i] == "cognito:mfa_enabled":
+			case header[i] == "cognito:username":
+				u.username = value
+			default:
+				u.setAttribute(header[i], value)
+			}
+		}
+		if _, exists := p.users[u.username]; exists || u.username == "" {
+			failed++
+			continue
+		}
+		p.users[u.username] = u
+		imported++
+	}
+
+	job.ImportedUsers = aws.Int64(imported)
+	job.FailedUsers = aws.Int64(failed)
+	job.CompletionDate = aws.Time(c.Now())
+	job.Status = aws.String(cognitoidentityprovider.UserImportJobStatusTypeSucceeded)
+	job.CompletionMessage = aws.String("Import Job Completed Successfully.")
+	return &cognitoidentityprovider.StartUserImportJobOutput{UserImportJob: copyImportJob(job)}, nil
+}
+
+// ImportHandler accepts the CSV uploads to the pre-signed URLs of import
+// jobs, standing in for S3.
+func (c *Cognito) ImportHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		id := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+		job, ok := c.imports[id]
+		if !ok || aws.StringValue(job.Status) != cognitoidentityprovider.UserImportJobStatusTypeCreated {
+			http.NotFound(w, req)
+			return
+		}
+		job.csv = body
+	})
+}
+
+func (c *Cognito) findImportJob(poolID *string, jobID *string) (*pool, *importJob, error) {
+	p, err := c.findPool(poolID)
+	if err != nil {
+		return nil, nil, err
+	}
+	job, ok := c.imports[aws.StringValue(jobID)]
+	if !ok || aws.StringValue(job.UserPoolId) != aws.StringValue(p.Id) {
+		return nil, nil, newError(cognitoidentityprovider.ErrCodeResourceNotFoundException, "Import job not found.")
+	}
+	return p, job, nil
+}
+
+func copyImportJob(job *importJob) *cognitoidentityprovider.UserImportJobType {
+	copied := *job.UserImportJobType
+	return &copied
+}
@@ -0,0 +1,354 @@
+// Package cognitofake is an in-memory emulator of the subset of the Cognito
+// user pool API used by this service. It satisfies
+// cognitoidentityprovideriface.CognitoIdentityProviderAPI so it can be
+// dropped into user.Config and userpool.Config for local development and
+// integration tests. Operations the service does not call fail with
+// ErrCodeNotImplemented.
+package cognitofake
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	lowerAlphanumeric = "abcdefghijklmnopqrstuvwxyz0123456789"
+	digits = "0123456789"
+	base32Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+)
+
+// Cognito holds every pool, client and user in memory. The zero value is not
+// usable; create one with New.
+type Cognito struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+
+	// Now returns the current time. Tests may replace it to expire codes
+	// and tokens.
+	Now func() time.Time
+	// CodeTTL is how long a confirmation code stays valid.
+	CodeTTL time.Duration
+	// TokenTTL is how long issued ID and access tokens stay valid.
+	TokenTTL time.Duration
+	// ImportURL is where ImportHandler is served; the pre-signed URLs of
+	// user import jobs point below it.
+	ImportURL string
+
+	region  string
+	key     *rsa.PrivateKey
+	keyID   string
+	mu      sync.Mutex
+	pools   map[string]*pool
+	clients map[string]*cognitoidentityprovider.UserPoolClientType
+	refresh map[string]*session
+	session map[string]*session
+	imports map[string]*importJob
+}
+
+type pool struct {
+	*cognitoidentityprovider.UserPoolType
+	users map[string]*user
+	groups map[string]*group
+	softwareTokenMfa bool
+}
+
+type group struct {
+	*cognitoidentityprovider.GroupType
+	members map[string]bool
+}
+
+type user struct {
+	username   string
+	password   string
+	status     string
+	enabled    bool
+	attributes []*cognitoidentityprovider.AttributeType
+	created    time.Time
+	modified   time.Time
+	code       string
+	codeExpiry time.Time
+	// attributeCodes holds the codes sent to verify a changed email or
+	// phone_number, by attribute name; they expire with codeExpiry.
+	attributeCodes map[string]string
+	// totpSecret is set by AssociateSoftwareToken and totpVerified once a
+	// code from it has been checked; totpEnabled is the MFA preference.
+	totpSecret    string
+	totpVerified  bool
+	totpEnabled   bool
+	totpPreferred bool
+}
+
+// session links an opaque refresh token or challenge session back to the
+// user and client it was issued for. originJTI ties a refresh token to the
+// ID and access tokens issued from it.
+type session struct {
+	poolID    string
+	clientID  string
+	username  string
+	challenge string
+	originJTI string
+	expiry    time.Time
+}
+
+// New returns an empty emulator issuing tokens for region, signed with a
+// freshly generated RSA key.
+func New(region string) *Cognito {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("cognitofake: generating signing key: %s", err))
+	}
+	return &Cognito{
+		CognitoIdentityProviderAPI: unimplementedCognito(),
+
+		Now:      time.Now,
+		CodeTTL:  time.Hour,
+		TokenTTL: time.Hour,
+		region:   region,
+		key:      key,
+		keyID:    randomString(lowerAlphanumeric, 16),
+		pools:    map[string]*pool{},
+		clients:  map[string]*cognitoidentityprovider.UserPoolClientType{},
+		refresh:  map[string]*session{},
+		session:  map[string]*session{},
+		imports:  map[string]*importJob{},
+	}
+}
+
+// ConfirmationCode returns the last code sent to USERNAME in POOL_ID, standing
+// in for the email or SMS a real pool would deliver.
+func (c *Cognito) ConfirmationCode(poolID string, username string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pools[poolID]
+	if !ok {
+		return "", false
+	}
+	u, ok := p.users[username]
+	if !ok || u.code == "" {
+		return "", false
+	}
+	return u.code, true
+}
+
+// AttributeVerificationCode returns the last code sent to verify the email or
+// phone_number attribute NAME of USERNAME in POOL_ID.
+func (c *Cognito) AttributeVerificationCode(poolID string, username string, name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pools[poolID]
+	if !ok {
+		return "", false
+	}
+	u, ok := p.users[username]
+	if !ok {
+		return "", false
+	}
+	code, ok := u.attributeCodes[name]
+	return code, ok
+}
+
+// TemporaryPassword returns the temporary password USERNAME in POOL_ID was
+// created or invited with, standing in for the invitation message.
+func (c *Cognito) TemporaryPassword(poolID string, username string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pools[poolID]
+	if !ok {
+		return "", false
+	}
+	u, ok := p.users[username]
+	if !ok || u.status != cognitoidentityprovider.UserStatusTypeForceChangePassword {
+		return "", false
+	}
+	return u.password, true
+}
+
+func (c *Cognito) findPool(poolID *string) (*pool, error) {
+	p, ok := c.pools[aws.StringValue(poolID)]
+	if !ok {
+		return nil, newError(cognitoidentityprovider.ErrCodeResourceNotFoundException,
+			fmt.Sprintf("User pool %s does not exist.", aws.StringValue(poolID)))
+	}
+	return p, nil
+}
+
+func (c *Cognito) findClient(clientID *string) (*cognitoidentityprovider.UserPoolClientType, *pool, error) {
+	client, ok := c.clients[aws.StringValue(clientID)]
+	if !ok {
+		return nil, nil, newError(cognitoidentityprovider.ErrCodeResourceNotFoundException,
+			fmt.Sprintf("User pool client %s does not exist.", aws.StringValue(clientID)))
+	}
+	p, err := c.findPool(client.UserPoolId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, p, nil
+}
+
+func (p *pool) findUser(username *string) (*user, error) {
+	u, ok := p.users[aws.StringValue(username)]
+	if !ok {
+		return nil, newError(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.")
+	}
+	return u, nil
+}
+
+func (u *user) attribute(name string) string {
+	for _, a := range u.attributes {
+		if aws.StringValue(a.Name) == name {
+			return aws.StringValue(a.Value)
+		}
+	}
+	return ""
+}
+
+func (u *user) setAttribute(name string, value string) {
+	for _, a := range u.attributes {
+		if aws.StringValue(a.Name) == name {
+			a.Value = aws.String(value)
+			return
+		}
+	}
+	u.attributes = append(u.attributes, &cognitoidentityprovider.AttributeType{
+		Name:  aws.String(name),
+		Value: aws.String(value),
+	})
+}
+
+func (u *user) toUserType() *cognitoidentityprovider.UserType {
+	attributes := make([]*cognitoidentityprovider.AttributeType, 0, len(u.attributes))
+	for _, a := range u.attributes {
+		attributes = append(attributes, &cognitoidentityprovider.AttributeType{
+			Name:  aws.String(aws.StringValue(a.Name)),
+			Value: aws.String(aws.StringValue(a.Value)),
+		})
+	}
+	return &cognitoidentityprovider.UserType{
+		Attributes:           attributes,
+		Enabled:              aws.Bool(u.enabled),
+		UserCreateDate:       aws.Time(u.created),
+		UserLastModifiedDate: aws.Time(u.modified),
+		UserStatus:           aws.String(u.status),
+		Username:             aws.String(u.username),
+	}
+}
+
+// checkUsername refuses a new username that is not an email address or
+// phone number in a pool that signs in with one, as Cognito does.
+func (p *pool) checkUsername(username string) error {
+	var email, phone bool
+	for _, attribute := range aws.StringValueSlice(p.UsernameAttributes) {
+		switch attribute {
+		case cognitoidentityprovider.UsernameAttributeTypeEmail:
+			email = true
+		case cognitoidentityprovider.UsernameAttributeTypePhoneNumber:
+			phone = true
+		}
+	}
+	switch {
+	case email && strings.Contains(username, "@"), phone && strings.HasPrefix(username, "+"), !email && !phone:
+		return nil
+	case email && phone:
+		return newError(cognitoidentityprovider.ErrCodeInvalidParameterException, "Username should be either an email or a phone number.")
+	case email:
+		return newError(cognitoidentityprovider.ErrCodeInvalidParameterException, "Username should be an email.")
+	}
+	return newError(cognitoidentityprovider.ErrCodeInvalidParameterException, "Username should be a phone number.")
+}
+
+// defaultPasswordPolicy is the policy of a pool created without one.
+func defaultPasswordPolicy() *cognitoidentityprovider.PasswordPolicyType {
+	return &cognitoidentityprovider.PasswordPolicyType{
+		MinimumLength:    aws.Int64(8),
+		RequireLowercase: aws.Bool(true),
+		RequireNumbers:   aws.Bool(true),
+		RequireSymbols:   aws.Bool(true),
+		RequireUppercase: aws.Bool(true),
+	}
+}
+
+// checkPassword applies the pool's password policy the way Cognito reports it.
+func (p *pool) checkPassword(password string) error {
+	policy := defaultPasswordPolicy()
+	if p.Policies != nil && p.Policies.PasswordPolicy != nil {
+		policy = p.Policies.PasswordPolicy
+	}
+
+	var reason string
+	switch {
+	case int64(len(password)) < aws.Int64Value(policy.MinimumLength):
+		reason = "Password not long enough"
+	case aws.BoolValue(policy.RequireLowercase) && !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz"):
+		reason = "Password must have lowercase characters"
+	case aws.BoolValue(policy.RequireUppercase) && !strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"):
+		reason = "Password must have uppercase characters"
+	case aws.BoolValue(policy.RequireNumbers) && !strings.ContainsAny(password, digits):
+		reason = "Password must have numeric characters"
+	case aws.BoolValue(policy.RequireSymbols) && !strings.ContainsAny(password, "^$*.[]{}()?\"!@#%&/\\,><':;|_~`=+-"):
+		reason = "Password must have symbol characters"
+	default:
+		return nil
+	}
+	return newError(cognitoidentityprovider.ErrCodeInvalidPasswordException,
+		"Password did not conform with policy: "+reason)
+}
+
+// newError builds the same awserr.RequestFailure shape the SDK returns for a
+// 400 response from Cognito, including a request ID.
+func newError(code string, message string) error {
+	return awserr.NewRequestFailure(awserr.New(code, message, nil), 400, newUUID())
+}
+
+func randomString(alphabet string, n int) string {
+	out := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range out {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(fmt.Sprintf("cognitofake: reading random bytes: %s", err))
+		}
+		out[i] = alphabet[idx.Int64()]
+	}
+	return string(out)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("cognitofake: reading random bytes: %s", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// paginate returns the page of sorted keys following token and the token for
+// the next page, if any. Tokens are the last key returned, so pages stay
+// stable while items are added or removed between calls.
+func paginate(keys []string, token string, limit int) ([]string, string) {
+	sort.Strings(keys)
+	start := 0
+	if token != "" {
+		start = sort.SearchStrings(keys, token)
+		if start < len(keys) && keys[start] == token {
+			start++
+		}
+	}
+	end := start + limit
+	if end >= len(keys) {
+		return keys[start:], ""
+	}
+	return keys[start:end], keys[end-1]
+}
@@ -0,0 +1,205 @@
+package cognitofake_test
+
+import (
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"testing"
+)
+
+// newPool creates a pool and a password sign-in client through userpool,
+// as the service would.
+func newPool(t *testing.T, fake *cognitofake.Cognito) (string, string) {
+	pools := userpool.Config{Information: "test", CognitoClient: fake, IAMService: cognitofake.NewIAM()}
+	created := pools.CreateUserPool(userpool.CreatePoolRequest{
+		EmailMessage:   "Your username is {username} and temporary password is {####}.",
+		EmailSubject:   "Welcome",
+		SMSMessage:     "Your username is {username} and temporary password is {####}.",
+		EmailVerifyMsg: "Your verification code is {####}.",
+		EmailVerifySub: "Verify your email",
+		SMSAuthMsg:     "Your authentication code is {####}.",
+		SMSVerifyMsg:   "Your verification code is {####}.",
+		PoolName:       "customers",
+	})
+	if created.ResponseCode != 200 {
+		t.Fatalf("creating pool: %s", created.Message)
+	}
+	poolID := created.Pools[0].PoolID
+
+	client := pools.CreateUserPoolClient(userpool.CreateUserPoolClientRequest{
+		ClientName:        "web",
+		ExplicitAuthFlows: []string{"ALLOW_USER_PASSWORD_AUTH", "ALLOW_REFRESH_TOKEN_AUTH"},
+		UserPoolId:        poolID,
+	})
+	if client.ResponseCode != 200 {
+		t.Fatalf("creating client: %s", client.Message)
+	}
+	return poolID, client.Client[0].ClientID
+}
+
+func TestUserPoolFlow(t *testing.T) {
+	fake := cognitofake.New("ap-southeast-1")
+	pools := userpool.Config{Information: "test", CognitoClient: fake, IAMService: cognitofake.NewIAM()}
+	poolID, clientID := newPool(t, fake)
+
+	steps := []struct {
+		name string
+		run  func() (int, string)
+	}{
+		{"lists pool", func() (int, string) {
+			response := pools.ListUserPool(10)
+			if len(response.Pools) != 1 || response.Pools[0].PoolID != poolID {
+				t.Errorf("pools = %+v", response.Pools)
+			}
+			return response.ResponseCode, response.Message
+		}},
+		{"lists client", func() (int, string) {
+			response := pools.ListUserPoolClients(&cognitoidentityprovider.ListUserPoolClientsInput{
+				MaxResults: aws.Int64(10),
+				UserPoolId: aws.String(poolID),
+			})
+			if len(response.Client) != 1 || response.Client[0].ClientID != clientID {
+				t.Errorf("clients = %+v", response.Client)
+			}
+			return response.ResponseCode, response.Message
+		}},
+		{"renames client", func() (int, string) {
+			response := pools.UpdateUserPoolClient(userpool.CreateUserPoolClientRequest{ClientName: "mobile", UserPoolId: poolID}, clientID)
+			return response.ResponseCode, response.Message
+		}},
+		{"describes renamed client", func() (int, string) {
+			response := pools.DescribeUserPoolClient(&cognitoidentityprovider.DescribeUserPoolClientInput{
+				ClientId:   aws.String(clientID),
+				UserPoolId: aws.String(poolID),
+			})
+			if len(response.Client) != 1 || response.Client[0].ClientName != "mobile" {
+				t.Errorf("clients = %+v", response.Client)
+			}
+			return response.ResponseCode, response.Message
+		}},
+		{"turns MFA optional", func() (int, string) {
+			response := pools.SetUserPoolMfaConfig(userpool.MfaConfigRequest{
+				UserPoolID:              poolID,
+				MfaConfiguration:        cognitoidentityprovider.UserPoolMfaTypeOptional,
+				SoftwareTokenMfaEnabled: true,
+			})
+			return response.ResponseCode, response.Message
+		}},
+	}
+	for _, step := range steps {
+		if status, message := step.run(); status != 200 {
+			t.Fatalf("%s: response code = %d (%s)", step.name, status, message)
+		}
+	}
+}
+
+func TestUserFlow(t *testing.T) {
+	fake := cognitofake.New("ap-southeast-1")
+	users := user.Config{Information: "test", CognitoClient: fake}
+	poolID, clientID := newPool(t, fake)
+	const email = "jo@example.com"
+
+	var accessToken, refreshToken string
+	steps := []struct {
+		name string
+		run  func() (int, string)
+	}{
+		{"signs up", func() (int, string) {
+			response := users.SignUp(user.SignUpRequest{Email: email, Password: "first-pass", Name: "Jo", ClientID: clientID})
+			return response.ResponseCode, response.Message
+		}},
+		{"cannot sign in unconfirmed", func() (int, string) {
+			response := users.AuthenticateUser(user.LoginRequest{Email: email, Password: "first-pass", ClientID: clientID})
+			if response.Error == nil || response.Error.Code != cognitoidentityprovider.ErrCodeUserNotConfirmedException {
+				t.Errorf("error = %v, want %s", response.Error, cognitoidentityprovider.ErrCodeUserNotConfirmedException)
+			}
+			return 200, ""
+		}},
+		{"confirms sign-up", func() (int, string) {
+			code, ok := fake.ConfirmationCode(poolID, email)
+			if !ok {
+				t.Fatal("no confirmation code sent")
+			}
+			response := users.ConfirmSignUp(user.SignUpRequest{Email: email, Code: code, ClientID: clientID})
+			return response.ResponseCode, response.Message
+		}},
+		{"signs in", func() (int, string) {
+			response := users.AuthenticateUser(user.LoginRequest{Email: email, Password: "first-pass", ClientID: clientID})
+			accessToken, refreshToken = response.AccessToken, response.RefreshToken
+			return response.ResponseCode, response.Message
+		}},
+		{"reads own profile", func() (int, string) {
+			response := users.GetAccount(user.AccountRequest{AccessToken: accessToken})
+			if len(response.UserList) != 1 || response.UserList[0].Email != email {
+				t.Errorf("users = %+v", response.UserList)
+			}
+			return response.ResponseCode, response.Message
+		}},
+		{"changes password", func() (int, string) {
+			response := users.ChangePassword(user.AccountRequest{AccessToken: accessToken, PreviousPassword: "first-pass", ProposedPassword: "second-pass"})
+			return response.ResponseCode, response.Message
+		}},
+		{"refreshes tokens", func() (int, string) {
+			response := users.RefreshToken(user.RefreshRequest{RefreshToken: refreshToken, ClientID: clientID})
+			return response.ResponseCode, response.Message
+		}},
+		{"disables user", func() (int, string) {
+			response := users.DisableUser(user.AdminUserRequest{UserPoolID: poolID, Email: email})
+			return response.ResponseCode, response.Message
+		}},
+		{"cannot sign in disabled", func() (int, string) {
+			response := users.AuthenticateUser(user.LoginRequest{Email: email, Password: "second-pass", ClientID: clientID})
+			if response.ResponseCode != 401 {
+				t.Errorf("response code = %d, want 401", response.ResponseCode)
+			}
+			return 200, ""
+		}},
+		{"deletes user", func() (int, string) {
+			response := users.DeleteUser(user.UserItem{UserPoolID: poolID, User: user.NewUserItem{Email: email}})
+			return response.ResponseCode, response.Message
+		}},
+	}
+	for _, step := range steps {
+		if status, message := step.run(); status != 200 {
+			t.Fatalf("%s: response code = %d (%s)", step.name, status, message)
+		}
+	}
+}
+
+// TestNotImplemented checks that operations the emulators lack fail with an
+// error instead of panicking.
+func TestNotImplemented(t *testing.T) {
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"cognito", func() error {
+			_, err := cognitofake.New("ap-southeast-1").DescribeRiskConfiguration(&cognitoidentityprovider.DescribeRiskConfigurationInput{
+				UserPoolId: aws.String("ap-southeast-1_test"),
+			})
+			return err
+		}},
+		{"iam", func() error {
+			_, err := cognitofake.NewIAM().ListRoles(&iam.ListRolesInput{})
+			return err
+		}},
+		{"s3", func() error {
+			_, err := cognitofake.NewS3().ListBuckets(&s3.ListBucketsInput{})
+			return err
+		}},
+	}
+	for _, test := range calls {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+			failure, ok := err.(awserr.RequestFailure)
+			if !ok || failure.Code() != cognitofake.ErrCodeNotImplemented || failure.StatusCode() != 501 {
+				t.Fatalf("error = %v, want a 501 %s", err, cognitofake.ErrCodeNotImplemented)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package cognitofake
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"time"
+)
+
+func (c *Cognito) GetUser(input *cognitoidentityprovider.GetUserInput) (*cognitoidentityprovider.GetUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &cognitoidentityprovider.GetUserOutput{
+		UserAttributes: u.toUserType().Attributes,
+		Username:       aws.String(u.username),
+	}
+	if u.totpEnabled {
+		output.UserMFASettingList = aws.StringSlice([]string{cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa})
+		if u.totpPreferred {
+			output.PreferredMfaSetting = aws.String(cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa)
+		}
+	}
+	return output, nil
+}
+
+// AssociateSoftwareToken generates a new TOTP secret for the user identified
+// by an access token or by the session of an MFA_SETUP challenge.
+func (c *Cognito) AssociateSoftwareToken(input *cognitoidentityprovider.AssociateSoftwareTokenInput) (*cognitoidentityprovider.AssociateSoftwareTokenOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u, err := c.softwareTokenUser(input.AccessToken, input.Session)
+	if err != nil {
+		return nil, err
+	}
+	u.totpSecret = randomString(base32Alphabet, 32)
+	u.totpVerified = false
+	return &cognitoidentityprovider.AssociateSoftwareTokenOutput{
+		SecretCode: aws.String(u.totpSecret),
+		Session:    input.Session,
+	}, nil
+}
+
+// VerifySoftwareToken checks a code against the associated secret. During an
+// MFA_SETUP challenge a successful check also enables TOTP for the user.
+func (c *Cognito) VerifySoftwareToken(input *cognitoidentityprovider.VerifySoftwareTokenInput) (*cognitoidentityprovider.VerifySoftwareTokenOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u, err := c.softwareTokenUser(input.AccessToken, input.Session)
+	if err != nil {
+		return nil, err
+	}
+	if u.totpSecret == "" {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"User has not associated a software token")
+	}
+	if !checkTOTP(u.totpSecret, aws.StringValue(input.UserCode), c.Now()) {
+		return nil, newError(cognitoidentityprovider.ErrCodeEnableSoftwareTokenMFAException,
+			"Code mismatch")
+	}
+
+	u.totpVerified = true
+	if input.Session != nil {
+		u.totpEnabled = true
+	}
+	u.modified = c.Now()
+	return &cognitoidentityprovider.VerifySoftwareTokenOutput{
+		Session: input.Session,
+		Status:  aws.String(cognitoidentityprovider.VerifySoftwareTokenResponseTypeSuccess),
+	}, nil
+}
+
+func (c *Cognito) SetUserMFAPreference(input *cognitoidentityprovider.SetUserMFAPreferenceInput) (*cognitoidentityprovider.SetUserMFAPreferenceOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, u, err := c.verifyAccessToken(input.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setSoftwareTokenPreference(u, input.SoftwareTokenMfaSettings); err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.SetUserMFAPreferenceOutput{}, nil
+}
+
+func (c *Cognito) AdminSetUserMFAPreference(input *cognitoidentityprovider.AdminSetUserMFAPreferenceInput) (*cognitoidentityprovider.AdminSetUserMFAPreferenceOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setSoftwareTokenPreference(u, input.SoftwareTokenMfaSettings); err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.AdminSetUserMFAPreferenceOutput{}, nil
+}
+
+func (c *Cognito) SetUserPoolMfaConfig(input *cognitoidentityprovider.SetUserPoolMfaConfigInput) (*cognitoidentityprovider.SetUserPoolMfaConfigOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	softwareToken := p.softwareTokenMfa
+	if input.SoftwareTokenMfaConfiguration != nil {
+		softwareToken = aws.BoolValue(input.SoftwareTokenMfaConfiguration.Enabled)
+	}
+	mfa := aws.StringValue(input.MfaConfiguration)
+	if mfa == "" {
+		mfa = aws.StringValue(p.MfaConfiguration)
+	}
+	if mfa != cognitoidentityprovider.UserPoolMfaTypeOff && !softwareToken && input.SmsMfaConfiguration == nil {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"At least one MFA method must be enabled when MFA is "+mfa)
+	}
+
+	p.softwareTokenMfa = softwareToken
+	p.MfaConfiguration = aws.String(mfa)
+	p.LastModifiedDate = aws.Time(c.Now())
+	return &cognitoidentityprovider.SetUserPoolMfaConfigOutput{
+		MfaConfiguration: aws.String(mfa),
+		SoftwareTokenMfaConfiguration: &cognitoidentityprovider.SoftwareTokenMfaConfigType{
+			Enabled: aws.Bool(softwareToken),
+		},
+	}, nil
+}
+
+// softwareTokenUser resolves the user an access token or MFA_SETUP session
+// belongs to.
+func (c *Cognito) softwareTokenUser(accessToken *string, token *string) (*user, error) {
+	if accessToken != nil {
+		_, u, err := c.verifyAccessToken(accessToken)
+		return u, err
+	}
+	challenge, ok := c.session[aws.StringValue(token)]
+	if !ok || challenge.challenge != cognitoidentityprovider.ChallengeNameTypeMfaSetup || c.Now().After(challenge.expiry) {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid session for the user.")
+	}
+	p, err := c.findPool(aws.String(challenge.poolID))
+	if err != nil {
+		return nil, err
+	}
+	return p.findUser(aws.String(challenge.username))
+}
+
+func (c *Cognito) setSoftwareTokenPreference(u *user, settings *cognitoidentityprovider.SoftwareTokenMfaSettingsType) error {
+	if settings == nil {
+		return nil
+	}
+	if aws.BoolValue(settings.Enabled) && !u.totpVerified {
+		return newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"User has not verified software token mfa")
+	}
+	u.totpEnabled = aws.BoolValue(settings.Enabled)
+	u.totpPreferred = u.totpEnabled && aws.BoolValue(settings.PreferredMfa)
+	u.modified = c.Now()
+	return nil
+}
+
+// TOTP returns the RFC 6238 code for a base32 SECRET at time T, as an
+// authenticator app would show it.
+func TOTP(secret string, t time.Time) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return ""
+	}
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000)
+}
+
+// checkTOTP accepts the code for the current step or either neighbour, to
+// allow for clock drift.
+func checkTOTP(secret string, code string, now time.Time) bool {
+	for _, drift := range []time.Duration{0, -30 * time.Second, 30 * time.Second} {
+		expected := TOTP(secret, now.Add(drift))
+		if expected != "" && hmac.Equal([]byte(expected), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
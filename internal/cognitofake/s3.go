@@ -0,0 +1,178 @@
+package cognitofake
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+// S3 emulates the object uploads and downloads user exports use. Every
+// bucket exists and starts out empty. Other S3 operations fail with
+// ErrCodeNotImplemented.
+type S3 struct {
+	s3iface.S3API
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int64][]byte
+}
+
+// NewS3 returns an S3 emulator with no objects.
+func NewS3() *S3 {
+	return &S3{
+		S3API:   unimplementedS3(),
+		objects: map[string][]byte{},
+		uploads: map[string]map[int64][]byte{},
+	}
+}
+
+func (s *S3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	var body []byte
+	if input.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(input.Body); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[objectKey(input.Bucket, input.Key)] = body
+	return &s3.PutObjectOutput{ETag: etag(body)}, nil
+}
+
+func (s *S3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, ok := s.objects[objectKey(input.Bucket, input.Key)]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchKey,
+			"The specified key does not exist.", nil), 404, newUUID())
+	}
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: aws.Int64(int64(len(body))),
+		ETag:          etag(body),
+	}, nil
+}
+
+func (s *S3) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := randomString(alphanumeric, 32)
+	s.uploads[id] = map[int64][]byte{}
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   input.Bucket,
+		Key:      input.Key,
+		UploadId: aws.String(id),
+	}, nil
+}
+
+func (s *S3) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	var body []byte
+	if input.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(input.Body); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts, err := s.findUpload(input.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	parts[aws.Int64Value(input.PartNumber)] = body
+	return &s3.UploadPartOutput{ETag: etag(body)}, nil
+}
+
+// CompleteMultipartUpload joins the parts in part number order. Parts left
+// out of the request are dropped, as S3 does.
+func (s *S3) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts, err := s.findUpload(input.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	var completed []*s3.CompletedPart
+	if input.MultipartUpload != nil {
+		completed = append(completed, input.MultipartUpload.Parts...)
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		return aws.Int64Value(completed[i].PartNumber) < aws.Int64Value(completed[j].PartNumber)
+	})
+	var body []byte
+	for _, part := range completed {
+		data, ok := parts[aws.Int64Value(part.PartNumber)]
+		if !ok {
+			return nil, awserr.NewRequestFailure(awserr.New("InvalidPart",
+				fmt.Sprintf("Part %d has not been uploaded.", aws.Int64Value(part.PartNumber)), nil), 400, newUUID())
+		}
+		body = append(body, data...)
+	}
+	delete(s.uploads, aws.StringValue(input.UploadId))
+	s.objects[objectKey(input.Bucket, input.Key)] = body
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket: input.Bucket,
+		ETag:   etag(body),
+		Key:    input.Key,
+	}, nil
+}
+
+func (s *S3) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.findUpload(input.UploadId); err != nil {
+		return nil, err
+	}
+	delete(s.uploads, aws.StringValue(input.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (s *S3) findUpload(id *string) (map[int64][]byte, error) {
+	parts, ok := s.uploads[aws.StringValue(id)]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchUpload,
+			"The specified upload does not exist.", nil), 404, newUUID())
+	}
+	return parts, nil
+}
+
+func objectKey(bucket *string, key *string) string {
+	return aws.StringValue(bucket) + "/" + aws.StringValue(key)
+}
+
+func etag(body []byte) *string {
+	return aws.String(fmt.Sprintf(`"%x"`, md5.Sum(body)))
+}
@@ -0,0 +1,142 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+// SignUp registers an UNCONFIRMED user and "sends" a confirmation code to
+// their email, readable through ConfirmationCode. Sign-up triggers are not
+// run.
+func (c *Cognito) SignUp(input *cognitoidentityprovider.SignUpInput) (*cognitoidentityprovider.SignUpOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSecretHash(client, input.Username, input.SecretHash); err != nil {
+		return nil, err
+	}
+	if p.AdminCreateUserConfig != nil && aws.BoolValue(p.AdminCreateUserConfig.AllowAdminCreateUserOnly) {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException,
+			"SignUp is not permitted for this user pool")
+	}
+	username := aws.StringValue(input.Username)
+	if _, exists := p.users[username]; exists {
+		return nil, newError(cognitoidentityprovider.ErrCodeUsernameExistsException,
+			"An account with the given email already exists.")
+	}
+	if err := p.checkUsername(username); err != nil {
+		return nil, err
+	}
+	if err := p.checkPassword(aws.StringValue(input.Password)); err != nil {
+		return nil, err
+	}
+
+	now := c.Now()
+	u := &user{
+		username: username,
+		password: aws.StringValue(input.Password),
+		status:   cognitoidentityprovider.UserStatusTypeUnconfirmed,
+		enabled:  true,
+		created:  now,
+		modified: now,
+	}
+	u.setAttribute("sub", newUUID())
+	for _, a := range input.UserAttributes {
+		u.setAttribute(aws.StringValue(a.Name), aws.StringValue(a.Value))
+	}
+	details, err := c.sendConfirmationCode(u)
+	if err != nil {
+		return nil, err
+	}
+	p.users[username] = u
+
+	return &cognitoidentityprovider.SignUpOutput{
+		CodeDeliveryDetails: details,
+		UserConfirmed:       aws.Bool(false),
+		UserSub:             aws.String(u.attribute("sub")),
+	}, nil
+}
+
+func (c *Cognito) ConfirmSignUp(input *cognitoidentityprovider.ConfirmSignUpInput) (*cognitoidentityprovider.ConfirmSignUpOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSecretHash(client, input.Username, input.SecretHash); err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if u.status != cognitoidentityprovider.UserStatusTypeUnconfirmed {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException,
+			"User cannot be confirmed. Current status is "+u.status)
+	}
+	if err := c.useCode(u, aws.StringValue(input.ConfirmationCode)); err != nil {
+		return nil, err
+	}
+
+	u.status = cognitoidentityprovider.UserStatusTypeConfirmed
+	u.setAttribute("email_verified", "true")
+	u.code = ""
+	u.modified = c.Now()
+	return &cognitoidentityprovider.ConfirmSignUpOutput{}, nil
+}
+
+func (c *Cognito) ResendConfirmationCode(input *cognitoidentityprovider.ResendConfirmationCodeInput) (*cognitoidentityprovider.ResendConfirmationCodeOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSecretHash(client, input.Username, input.SecretHash); err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if u.status != cognitoidentityprovider.UserStatusTypeUnconfirmed {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException, "User is already confirmed.")
+	}
+	details, err := c.sendConfirmationCode(u)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.ResendConfirmationCodeOutput{CodeDeliveryDetails: details}, nil
+}
+
+// sendConfirmationCode issues a new sign-up code to the user's email.
+func (c *Cognito) sendConfirmationCode(u *user) (*cognitoidentityprovider.CodeDeliveryDetailsType, error) {
+	email := u.attribute("email")
+	if email == "" {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Cannot send a confirmation code as the user has no email")
+	}
+	u.code = randomString(digits, 6)
+	u.codeExpiry = c.Now().Add(c.CodeTTL)
+	return &cognitoidentityprovider.CodeDeliveryDetailsType{
+		AttributeName:  aws.String("email"),
+		DeliveryMedium: aws.String(cognitoidentityprovider.DeliveryMediumTypeEmail),
+		Destination:    aws.String(maskEmail(email)),
+	}, nil
+}
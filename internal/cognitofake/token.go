@@ -0,0 +1,166 @@
+package cognitofake
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Issuer returns the iss claim of tokens issued for POOL_ID, matching the
+// format of a real pool.
+func (c *Cognito) Issuer(poolID string) string {
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", c.region, poolID)
+}
+
+// JWKS returns the JSON Web Key Set that verifies every token the emulator
+// issues, in the shape served at <issuer>/.well-known/jwks.json.
+func (c *Cognito) JWKS() ([]byte, error) {
+	public := c.key.PublicKey
+	return json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"alg": "RS256",
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+				"kid": c.keyID,
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				"use": "sig",
+			},
+		},
+	})
+}
+
+func (c *Cognito) idClaims(client *cognitoidentityprovider.UserPoolClientType, p *pool, u *user, originJTI string, now time.Time) map[string]interface{} {
+	claims := map[string]interface{}{
+		"aud":              aws.StringValue(client.ClientId),
+		"auth_time":        now.Unix(),
+		"cognito:username": u.username,
+		"event_id":         newUUID(),
+		"exp":              now.Add(c.TokenTTL).Unix(),
+		"iat":              now.Unix(),
+		"iss":              c.Issuer(aws.StringValue(p.Id)),
+		"origin_jti":       originJTI,
+		"token_use":        "id",
+	}
+	if groups := p.groupsOf(u); len(groups) > 0 {
+		claims["cognito:groups"] = groups
+	}
+	for _, a := range u.attributes {
+		name, value := aws.StringValue(a.Name), aws.StringValue(a.Value)
+		switch name {
+		case "email_verified", "phone_number_verified":
+			claims[name] = value == "true"
+		default:
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func (c *Cognito) accessClaims(client *cognitoidentityprovider.UserPoolClientType, p *pool, u *user, originJTI string, now time.Time) map[string]interface{} {
+	claims := map[string]interface{}{
+		"auth_time":  now.Unix(),
+		"client_id":  aws.StringValue(client.ClientId),
+		"event_id":   newUUID(),
+		"exp":        now.Add(c.TokenTTL).Unix(),
+		"iat":        now.Unix(),
+		"iss":        c.Issuer(aws.StringValue(p.Id)),
+		"jti":        newUUID(),
+		"origin_jti": originJTI,
+		"scope":      "aws.cognito.signin.user.admin",
+		"sub":        u.attribute("sub"),
+		"token_use":  "access",
+		"username":   u.username,
+	}
+	if groups := p.groupsOf(u); len(groups) > 0 {
+		claims["cognito:groups"] = groups
+	}
+	return claims
+}
+
+// sign encodes CLAIMS as an RS256 JWT with the emulator's key.
+func (c *Cognito) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": c.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verifyAccessToken checks an access token issued by the emulator and returns
+// the pool and user it belongs to.
+func (c *Cognito) verifyAccessToken(token *string) (*pool, *user, error) {
+	invalid := newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Invalid Access Token")
+	parts := strings.Split(aws.StringValue(token), ".")
+	if len(parts) != 3 {
+		return nil, nil, invalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, invalid
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(&c.key.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+		return nil, nil, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, invalid
+	}
+	var claims struct {
+		Exp       int64  `json:"exp"`
+		Iss       string `json:"iss"`
+		OriginJTI string `json:"origin_jti"`
+		TokenUse  string `json:"token_use"`
+		Username  string `json:"username"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.TokenUse != "access" {
+		return nil, nil, invalid
+	}
+	if c.Now().Unix() >= claims.Exp {
+		return nil, nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Access Token has expired")
+	}
+
+	p, ok := c.pools[strings.TrimPrefix(claims.Iss, c.Issuer(""))]
+	if !ok {
+		return nil, nil, invalid
+	}
+	u, ok := p.users[claims.Username]
+	if !ok {
+		return nil, nil, newError(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.")
+	}
+	if !c.activeOrigin(claims.OriginJTI) {
+		return nil, nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "Access Token has been revoked")
+	}
+	return p, u, nil
+}
+
+// activeOrigin reports whether the refresh session a token was issued from
+// still exists.
+func (c *Cognito) activeOrigin(originJTI string) bool {
+	for _, refresh := range c.refresh {
+		if refresh.originJTI == originJTI {
+			return true
+		}
+	}
+	return false
+}
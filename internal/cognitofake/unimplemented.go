@@ -0,0 +1,54 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awssession "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrCodeNotImplemented is the error code of operations the emulators do
+// not cover.
+const ErrCodeNotImplemented = "NotImplemented"
+
+// unimplementedSession configures the SDK clients the emulators embed. They
+// never reach AWS, so anonymous credentials do.
+func unimplementedSession() *awssession.Session {
+	return awssession.Must(awssession.NewSession(&aws.Config{
+		Credentials: credentials.AnonymousCredentials,
+		Region:      aws.String("local"),
+	}))
+}
+
+// notImplemented replaces every handler of an SDK client with one failing
+// the request, so operations an emulator lacks return an error rather than
+// reach AWS.
+func notImplemented(handlers *request.Handlers) {
+	handlers.Clear()
+	handlers.Send.PushBack(func(r *request.Request) {
+		r.Error = awserr.NewRequestFailure(awserr.New(ErrCodeNotImplemented,
+			"cognitofake does not emulate "+r.Operation.Name, nil), 501, newUUID())
+	})
+}
+
+func unimplementedCognito() *cognitoidentityprovider.CognitoIdentityProvider {
+	client := cognitoidentityprovider.New(unimplementedSession())
+	notImplemented(&client.Handlers)
+	return client
+}
+
+func unimplementedIAM() *iam.IAM {
+	client := iam.New(unimplementedSession())
+	notImplemented(&client.Handlers)
+	return client
+}
+
+func unimplementedS3() *s3.S3 {
+	client := s3.New(unimplementedSession())
+	notImplemented(&client.Handlers)
+	return client
+}
@@ -0,0 +1,485 @@
+package cognitofake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+)
+
+func (c *Cognito) AdminCreateUser(input *cognitoidentityprovider.AdminCreateUserInput) (*cognitoidentityprovider.AdminCreateUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+
+	username := aws.StringValue(input.Username)
+	existing, exists := p.users[username]
+	if aws.StringValue(input.MessageAction) == cognitoidentityprovider.MessageActionTypeResend {
+		if !exists {
+			return nil, newError(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.")
+		}
+		if existing.status != cognitoidentityprovider.UserStatusTypeForceChangePassword {
+			return nil, newError(cognitoidentityprovider.ErrCodeUnsupportedUserStateException,
+				"Resend not possible. "+username+" status is not FORCE_CHANGE_PASSWORD")
+		}
+		if err := checkDeliveryMediums(existing, input.DesiredDeliveryMediums); err != nil {
+			return nil, err
+		}
+		existing.password = randomPassword()
+		existing.modified = c.Now()
+		return &cognitoidentityprovider.AdminCreateUserOutput{User: existing.toUserType()}, nil
+	}
+	if exists {
+		return nil, newError(cognitoidentityprovider.ErrCodeUsernameExistsException, "User account already exists")
+	}
+	if err := p.checkUsername(username); err != nil {
+		return nil, err
+	}
+
+	password := aws.StringValue(input.TemporaryPassword)
+	if password == "" {
+		password = randomPassword()
+	} else if err := p.checkPassword(password); err != nil {
+		return nil, err
+	}
+
+	now := c.Now()
+	u := &user{
+		username: username,
+		password: password,
+		status:   cognitoidentityprovider.UserStatusTypeForceChangePassword,
+		enabled:  true,
+		created:  now,
+		modified: now,
+	}
+	u.setAttribute("sub", newUUID())
+	for _, a := range input.UserAttributes {
+		u.setAttribute(aws.StringValue(a.Name), aws.StringValue(a.Value))
+	}
+	if aws.StringValue(input.MessageAction) != cognitoidentityprovider.MessageActionTypeSuppress {
+		if err := checkDeliveryMediums(u, input.DesiredDeliveryMediums); err != nil {
+			return nil, err
+		}
+	}
+	p.users[username] = u
+
+	return &cognitoidentityprovider.AdminCreateUserOutput{User: u.toUserType()}, nil
+}
+
+func (c *Cognito) AdminSetUserPassword(input *cognitoidentityprovider.AdminSetUserPasswordInput) (*cognitoidentityprovider.AdminSetUserPasswordOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkPassword(aws.StringValue(input.Password)); err != nil {
+		return nil, err
+	}
+
+	u.password = aws.StringValue(input.Password)
+	if aws.BoolValue(input.Permanent) {
+		u.status = cognitoidentityprovider.UserStatusTypeConfirmed
+	} else {
+		u.status = cognitoidentityprovider.UserStatusTypeForceChangePassword
+	}
+	u.modified = c.Now()
+	return &cognitoidentityprovider.AdminSetUserPasswordOutput{}, nil
+}
+
+func (c *Cognito) AdminUpdateUserAttributes(input *cognitoidentityprovider.AdminUpdateUserAttributesInput) (*cognitoidentityprovider.AdminUpdateUserAttributesOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkWritable(input.UserAttributes); err != nil {
+		return nil, err
+	}
+	for _, a := range input.UserAttributes {
+		u.setAttribute(aws.StringValue(a.Name), aws.StringValue(a.Value))
+	}
+	u.modified = c.Now()
+	return &cognitoidentityprovider.AdminUpdateUserAttributesOutput{}, nil
+}
+
+func (c *Cognito) AdminDeleteUser(input *cognitoidentityprovider.AdminDeleteUserInput) (*cognitoidentityprovider.AdminDeleteUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.findUser(input.Username); err != nil {
+		return nil, err
+	}
+	delete(p.users, aws.StringValue(input.Username))
+	for _, g := range p.groups {
+		delete(g.members, aws.StringValue(input.Username))
+	}
+	return &cognitoidentityprovider.AdminDeleteUserOutput{}, nil
+}
+
+// AdminDisableUser also revokes the user's tokens, as Cognito does.
+func (c *Cognito) AdminDisableUser(input *cognitoidentityprovider.AdminDisableUserInput) (*cognitoidentityprovider.AdminDisableUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	u.enabled = false
+	u.modified = c.Now()
+	c.signOut(p, u)
+	return &cognitoidentityprovider.AdminDisableUserOutput{}, nil
+}
+
+func (c *Cognito) AdminEnableUser(input *cognitoidentityprovider.AdminEnableUserInput) (*cognitoidentityprovider.AdminEnableUserOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	u.enabled = true
+	u.modified = c.Now()
+	return &cognitoidentityprovider.AdminEnableUserOutput{}, nil
+}
+
+func (c *Cognito) AdminUserGlobalSignOut(input *cognitoidentityprovider.AdminUserGlobalSignOutInput) (*cognitoidentityprovider.AdminUserGlobalSignOutOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	c.signOut(p, u)
+	return &cognitoidentityprovider.AdminUserGlobalSignOutOutput{}, nil
+}
+
+func (c *Cognito) ListUsers(input *cognitoidentityprovider.ListUsersInput) (*cognitoidentityprovider.ListUsersOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	limit, err := pageLimit(input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := parseFilter(aws.StringValue(input.Filter))
+	if err != nil {
+		return nil, err
+	}
+	var usernames []string
+	for username, u := range p.users {
+		if match(u) {
+			usernames = append(usernames, username)
+		}
+	}
+	page, next := paginate(usernames, aws.StringValue(input.PaginationToken), limit)
+
+	output := &cognitoidentityprovider.ListUsersOutput{Users: []*cognitoidentityprovider.UserType{}}
+	for _, username := range page {
+		output.Users = append(output.Users, selectAttributes(p.users[username].toUserType(), input.AttributesToGet))
+	}
+	if next != "" {
+		output.PaginationToken = aws.String(next)
+	}
+	return output, nil
+}
+
+func (c *Cognito) ForgotPassword(input *cognitoidentityprovider.ForgotPasswordInput) (*cognitoidentityprovider.ForgotPasswordOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSecretHash(client, input.Username, input.SecretHash); err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if !u.enabled {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "User is disabled.")
+	}
+
+	details, err := c.sendResetCode(u)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.ForgotPasswordOutput{CodeDeliveryDetails: details}, nil
+}
+
+// AdminResetUserPassword leaves the user in RESET_REQUIRED, unable to sign
+// in until they confirm a new password with the code sent to them.
+func (c *Cognito) AdminResetUserPassword(input *cognitoidentityprovider.AdminResetUserPasswordInput) (*cognitoidentityprovider.AdminResetUserPasswordOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if !u.enabled {
+		return nil, newError(cognitoidentityprovider.ErrCodeNotAuthorizedException, "User is disabled.")
+	}
+	if _, err := c.sendResetCode(u); err != nil {
+		return nil, err
+	}
+	u.status = cognitoidentityprovider.UserStatusTypeResetRequired
+	u.modified = c.Now()
+	return &cognitoidentityprovider.AdminResetUserPasswordOutput{}, nil
+}
+
+// sendResetCode records a password reset code, delivered to the verified
+// email or else the verified phone number.
+func (c *Cognito) sendResetCode(u *user) (*cognitoidentityprovider.CodeDeliveryDetailsType, error) {
+	details := &cognitoidentityprovider.CodeDeliveryDetailsType{}
+	switch {
+	case u.attribute("email_verified") == "true":
+		details.AttributeName = aws.String("email")
+		details.DeliveryMedium = aws.String(cognitoidentityprovider.DeliveryMediumTypeEmail)
+		details.Destination = aws.String(maskEmail(u.attribute("email")))
+	case u.attribute("phone_number_verified") == "true":
+		details.AttributeName = aws.String("phone_number")
+		details.DeliveryMedium = aws.String(cognitoidentityprovider.DeliveryMediumTypeSms)
+		details.Destination = aws.String(maskPhone(u.attribute("phone_number")))
+	default:
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Cannot reset password for the user as there is no registered/verified email or phone_number")
+	}
+
+	u.code = randomString(digits, 6)
+	u.codeExpiry = c.Now().Add(c.CodeTTL)
+	return details, nil
+}
+
+// checkDeliveryMediums rejects an invitation by a medium the user has no
+// address for.
+func checkDeliveryMediums(u *user, mediums []*string) error {
+	for _, medium := range aws.StringValueSlice(mediums) {
+		if (medium == cognitoidentityprovider.DeliveryMediumTypeEmail && u.attribute("email") == "") ||
+			(medium == cognitoidentityprovider.DeliveryMediumTypeSms && u.attribute("phone_number") == "") {
+			return newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+				"User has no attribute matching desired delivery mediums")
+		}
+	}
+	return nil
+}
+
+func (c *Cognito) ConfirmForgotPassword(input *cognitoidentityprovider.ConfirmForgotPasswordInput) (*cognitoidentityprovider.ConfirmForgotPasswordOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, p, err := c.findClient(input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSecretHash(client, input.Username, input.SecretHash); err != nil {
+		return nil, err
+	}
+	u, err := p.findUser(input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.useCode(u, aws.StringValue(input.ConfirmationCode)); err != nil {
+		return nil, err
+	}
+	if err := p.checkPassword(aws.StringValue(input.Password)); err != nil {
+		return nil, err
+	}
+
+	u.password = aws.StringValue(input.Password)
+	u.status = cognitoidentityprovider.UserStatusTypeConfirmed
+	u.code = ""
+	u.modified = c.Now()
+	return &cognitoidentityprovider.ConfirmForgotPasswordOutput{}, nil
+}
+
+// useCode checks CODE against the last code sent to the user.
+func (c *Cognito) useCode(u *user, code string) error {
+	if u.code == "" || code != u.code {
+		return newError(cognitoidentityprovider.ErrCodeCodeMismatchException,
+			"Invalid verification code provided, please try again.")
+	}
+	if c.Now().After(u.codeExpiry) {
+		return newError(cognitoidentityprovider.ErrCodeExpiredCodeException,
+			"Invalid code provided, please request a code again.")
+	}
+	return nil
+}
+
+// randomPassword generates a temporary password that satisfies the default
+// pool policy.
+func randomPassword() string {
+	return randomString("ABCDEFGHJKLMNPQRSTUVWXYZ", 2) + randomString("abcdefghijkmnopqrstuvwxyz", 4) +
+		randomString(digits, 2) + randomString("!#$%&*+-=?@^_", 2)
+}
+
+func maskEmail(email string) string {
+	at := strings.Index(email, "@")
+	dot := strings.LastIndex(email, ".")
+	if at < 1 || dot < at+2 {
+		return "***"
+	}
+	return email[:1] + "***@" + email[at+1:at+2] + "***" + email[dot:]
+}
+
+func maskPhone(phone string) string {
+	if len(phone) < 4 {
+		return "***"
+	}
+	return "+*******" + phone[len(phone)-4:]
+}
+
+// filterAttributes are the attributes Cognito lets ListUsers filter on.
+var filterAttributes = map[string]bool{
+	"username": true, "email": true, "phone_number": true, "name": true,
+	"given_name": true, "family_name": true, "preferred_username": true,
+	"cognito:user_status": true, "status": true, "sub": true,
+}
+
+// parseFilter compiles a ListUsers filter of the form attribute = "value" or
+// attribute ^= "value".
+func parseFilter(filter string) (func(*user) bool, error) {
+	if strings.TrimSpace(filter) == "" {
+		return func(*user) bool { return true }, nil
+	}
+	invalid := newError(cognitoidentityprovider.ErrCodeInvalidParameterException, "Error while parsing filter.")
+
+	prefix := false
+	i := strings.Index(filter, "^=")
+	if i >= 0 {
+		prefix = true
+	} else if i = strings.Index(filter, "="); i < 0 {
+		return nil, invalid
+	}
+	name := strings.TrimSpace(filter[:i])
+	raw := strings.TrimSpace(filter[i+1:])
+	if prefix {
+		raw = strings.TrimSpace(filter[i+2:])
+	}
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return nil, invalid
+	}
+	value := raw[1 : len(raw)-1]
+	if !filterAttributes[name] {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"Invalid search attribute: "+name)
+	}
+
+	return func(u *user) bool {
+		var actual string
+		switch name {
+		case "username":
+			actual = u.username
+		case "cognito:user_status":
+			actual = u.status
+		case "status":
+			actual = "Disabled"
+			if u.enabled {
+				actual = "Enabled"
+			}
+		default:
+			actual = u.attribute(name)
+		}
+		if prefix {
+			return strings.HasPrefix(actual, value)
+		}
+		return actual == value
+	}, nil
+}
+
+// selectAttributes keeps only the attributes NAMES asks for, when given.
+func selectAttributes(u *cognitoidentityprovider.UserType, names []*string) *cognitoidentityprovider.UserType {
+	if len(names) == 0 {
+		return u
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[aws.StringValue(name)] = true
+	}
+	var attributes []*cognitoidentityprovider.AttributeType
+	for _, a := range u.Attributes {
+		if wanted[aws.StringValue(a.Name)] {
+			attributes = append(attributes, a)
+		}
+	}
+	u.Attributes = attributes
+	return u
+}
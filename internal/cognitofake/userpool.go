@@ -0,0 +1,323 @@
+package cognitofake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+func (c *Cognito) CreateUserPool(input *cognitoidentityprovider.CreateUserPoolInput) (*cognitoidentityprovider.CreateUserPoolOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.Now()
+	id := c.region + "_" + randomString(alphanumeric, 9)
+	p := &pool{
+		UserPoolType: &cognitoidentityprovider.UserPoolType{
+			AdminCreateUserConfig:    input.AdminCreateUserConfig,
+			AliasAttributes:          input.AliasAttributes,
+			Arn:                      aws.String(fmt.Sprintf("arn:aws:cognito-idp:%s:000000000000:userpool/%s", c.region, id)),
+			AutoVerifiedAttributes:   input.AutoVerifiedAttributes,
+			CreationDate:             aws.Time(now),
+			EmailVerificationMessage: input.EmailVerificationMessage,
+			EmailVerificationSubject: input.EmailVerificationSubject,
+			Id:                       aws.String(id),
+			LambdaConfig:             input.LambdaConfig,
+			LastModifiedDate:         aws.Time(now),
+			MfaConfiguration:         aws.String(cognitoidentityprovider.UserPoolMfaTypeOff),
+			Name:                     input.PoolName,
+			Policies:                 input.Policies,
+			SchemaAttributes:         poolSchema(input.Schema),
+			SmsAuthenticationMessage: input.SmsAuthenticationMessage,
+			SmsConfiguration:         input.SmsConfiguration,
+			SmsVerificationMessage:   input.SmsVerificationMessage,
+			Status:                   aws.String(cognitoidentityprovider.StatusTypeEnabled),
+			UsernameAttributes:       input.UsernameAttributes,
+		},
+		users:  map[string]*user{},
+		groups: map[string]*group{},
+	}
+	if input.MfaConfiguration != nil {
+		p.MfaConfiguration = input.MfaConfiguration
+	}
+	if p.Policies == nil || p.Policies.PasswordPolicy == nil {
+		// Cognito describes a pool created without a password policy with
+		// the default one it enforces.
+		p.Policies = &cognitoidentityprovider.UserPoolPolicyType{PasswordPolicy: defaultPasswordPolicy()}
+	}
+	c.pools[id] = p
+
+	return &cognitoidentityprovider.CreateUserPoolOutput{UserPool: p.UserPoolType}, nil
+}
+
+func (c *Cognito) DescribeUserPool(input *cognitoidentityprovider.DescribeUserPoolInput) (*cognitoidentityprovider.DescribeUserPoolOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, err := c.findPool(input.UserPoolId)
+	if err != nil {
+		return nil, err
+	}
+	described := *p.UserPoolType
+	described.EstimatedNumberOfUsers = aws.Int64(int64(len(p.users)))
+	return &cognitoidentityprovider.DescribeUserPoolOutput{UserPool: &described}, nil
+}
+
+func (c *Cognito) ListUserPools(input *cognitoidentityprovider.ListUserPoolsInput) (*cognitoidentityprovider.ListUserPoolsOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if max := aws.Int64Value(input.MaxResults); max > 60 {
+		return nil, newError(cognitoidentityprovider.ErrCodeInvalidParameterException,
+			"1 validation error detected: Value at 'maxResults' failed to satisfy constraint: Member must have value less than or equal to 60")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ids []string
+	for id := range c.pools {
+		ids = append(ids, id)
+	}
+	page, next := paginate(ids, aws.StringValue(input.NextToken), int(aws.Int64Value(input.MaxResults)))
+
+	output := &cognitoidentityprovider.ListUserPoolsOutput{}
+	for _, id := range page {
+		p := c.pools[id]
+		output.UserPools = append(output.UserPools, &cognitoidentityprovider.UserPoolDescriptionType{
+			CreationDate:     p.CreationDate,
+			Id:               p.Id,
+			LambdaConfig:     p.LambdaConfig,
+			LastModifiedDate: p.LastModifiedDate,
+			Name:             p.Name,
+			Status:           p.Status,
+		})
+	}
+	if next != "" {
+		output.NextToken = aws.String(next)
+	}
+	return output, nil
+}
+
+func (c *Cognito) CreateUserPoolClient(input *cognitoidentityprovider.CreateUserPoolClientInput) (*cognitoidentityprovider.CreateUserPoolClientOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.findPool(input.UserPoolId); err != nil {
+		return nil, err
+	}
+
+	now := c.Now()
+	client := &cognitoidentityprovider.UserPoolClientType{
+		AccessTokenValidity:             input.AccessTokenValidity,
+		AllowedOAuthFlows:               input.AllowedOAuthFlows,
+		AllowedOAuthFlowsUserPoolClient: input.AllowedOAuthFlowsUserPoolClient,
+		AllowedOAuthScopes:              input.AllowedOAuthScopes,
+		AnalyticsConfiguration:          input.AnalyticsConfiguration,
+		AuthSessionValidity:             input.AuthSessionValidity,
+		CallbackURLs:                    input.CallbackURLs,
+		ClientId:                        aws.String(randomString(lowerAlphanumeric, 26)),
+		ClientName:                      input.ClientName,
+		CreationDate:                    aws.Time(now),
+		DefaultRedirectURI:              input.DefaultRedirectURI,
+		EnablePropagateAdditionalUserContextData: input.EnablePropagateAdditionalUserContextData,
+		EnableTokenRevocation:           input.EnableTokenRevocation,
+		ExplicitAuthFlows:               input.ExplicitAuthFlows,
+		IdTokenValidity:                 input.IdTokenValidity,
+		LastModifiedDate:                aws.Time(now),
+		LogoutURLs:                      input.LogoutURLs,
+		PreventUserExistenceErrors:      input.PreventUserExistenceErrors,
+		ReadAttributes:                  input.ReadAttributes,
+		RefreshTokenValidity:            input.RefreshTokenValidity,
+		SupportedIdentityProviders:      input.SupportedIdentityProviders,
+		TokenValidityUnits:              input.TokenValidityUnits,
+		UserPoolId:                      input.UserPoolId,
+		WriteAttributes:                 input.WriteAttributes,
+	}
+	setClientDefaults(client)
+	if client.EnableTokenRevocation == nil {
+		client.EnableTokenRevocation = aws.Bool(true)
+	}
+	if aws.BoolValue(input.GenerateSecret) {
+		client.ClientSecret = aws.String(randomString(lowerAlphanumeric, 51))
+	}
+	c.clients[aws.StringValue(client.ClientId)] = client
+
+	return &cognitoidentityprovider.CreateUserPoolClientOutput{UserPoolClient: copyClient(client)}, nil
+}
+
+func (c *Cognito) ListUserPoolClients(input *cognitoidentityprovider.ListUserPoolClientsInput) (*cognitoidentityprovider.ListUserPoolClientsOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.findPool(input.UserPoolId); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for id, client := range c.clients {
+		if aws.StringValue(client.UserPoolId) == aws.StringValue(input.UserPoolId) {
+			ids = append(ids, id)
+		}
+	}
+	limit := int(aws.Int64Value(input.MaxResults))
+	if limit == 0 {
+		limit = 60
+	}
+	page, next := paginate(ids, aws.StringValue(input.NextToken), limit)
+
+	output := &cognitoidentityprovider.ListUserPoolClientsOutput{}
+	for _, id := range page {
+		client := c.clients[id]
+		output.UserPoolClients = append(output.UserPoolClients, &cognitoidentityprovider.UserPoolClientDescription{
+			ClientId:   client.ClientId,
+			ClientName: client.ClientName,
+			UserPoolId: client.UserPoolId,
+		})
+	}
+	if next != "" {
+		output.NextToken = aws.String(next)
+	}
+	return output, nil
+}
+
+func (c *Cognito) DescribeUserPoolClient(input *cognitoidentityprovider.DescribeUserPoolClientInput) (*cognitoidentityprovider.DescribeUserPoolClientOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, err := c.poolClient(input.UserPoolId, input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	return &cognitoidentityprovider.DescribeUserPoolClientOutput{UserPoolClient: copyClient(client)}, nil
+}
+
+// UpdateUserPoolClient replaces every updatable attribute, resetting those
+// missing from the input to their defaults just as Cognito does.
+func (c *Cognito) UpdateUserPoolClient(input *cognitoidentityprovider.UpdateUserPoolClientInput) (*cognitoidentityprovider.UpdateUserPoolClientOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, err := c.poolClient(input.UserPoolId, input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	client.AccessTokenValidity = input.AccessTokenValidity
+	client.AllowedOAuthFlows = input.AllowedOAuthFlows
+	client.AllowedOAuthFlowsUserPoolClient = input.AllowedOAuthFlowsUserPoolClient
+	client.AllowedOAuthScopes = input.AllowedOAuthScopes
+	client.AnalyticsConfiguration = input.AnalyticsConfiguration
+	client.AuthSessionValidity = input.AuthSessionValidity
+	client.CallbackURLs = input.CallbackURLs
+	client.ClientName = input.ClientName
+	client.DefaultRedirectURI = input.DefaultRedirectURI
+	client.EnablePropagateAdditionalUserContextData = input.EnablePropagateAdditionalUserContextData
+	if input.EnableTokenRevocation != nil {
+		client.EnableTokenRevocation = input.EnableTokenRevocation
+	}
+	client.ExplicitAuthFlows = input.ExplicitAuthFlows
+	client.IdTokenValidity = input.IdTokenValidity
+	client.LastModifiedDate = aws.Time(c.Now())
+	client.LogoutURLs = input.LogoutURLs
+	client.PreventUserExistenceErrors = input.PreventUserExistenceErrors
+	client.ReadAttributes = input.ReadAttributes
+	client.RefreshTokenValidity = input.RefreshTokenValidity
+	client.SupportedIdentityProviders = input.SupportedIdentityProviders
+	client.TokenValidityUnits = input.TokenValidityUnits
+	client.WriteAttributes = input.WriteAttributes
+	setClientDefaults(client)
+
+	return &cognitoidentityprovider.UpdateUserPoolClientOutput{UserPoolClient: copyClient(client)}, nil
+}
+
+func (c *Cognito) DeleteUserPoolClient(input *cognitoidentityprovider.DeleteUserPoolClientInput) (*cognitoidentityprovider.DeleteUserPoolClientOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	client, err := c.poolClient(input.UserPoolId, input.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	delete(c.clients, aws.StringValue(client.ClientId))
+	return &cognitoidentityprovider.DeleteUserPoolClientOutput{}, nil
+}
+
+// setClientDefaults fills in the values Cognito gives client attributes that
+// were left out: a 30 day refresh token, a 60 minute access and ID token, a
+// three minute auth session and legacy user existence errors.
+func setClientDefaults(client *cognitoidentityprovider.UserPoolClientType) {
+	if aws.Int64Value(client.RefreshTokenValidity) == 0 {
+		client.RefreshTokenValidity = aws.Int64(30)
+	}
+	// Copied, as the units came from the caller's input.
+	units := &cognitoidentityprovider.TokenValidityUnitsType{}
+	if client.TokenValidityUnits != nil {
+		*units = *client.TokenValidityUnits
+	}
+	client.TokenValidityUnits = units
+	if units.AccessToken == nil {
+		units.AccessToken = aws.String(cognitoidentityprovider.TimeUnitsTypeHours)
+	}
+	if units.IdToken == nil {
+		units.IdToken = aws.String(cognitoidentityprovider.TimeUnitsTypeHours)
+	}
+	if units.RefreshToken == nil {
+		units.RefreshToken = aws.String(cognitoidentityprovider.TimeUnitsTypeDays)
+	}
+	if aws.Int64Value(client.AccessTokenValidity) == 0 {
+		client.AccessTokenValidity = aws.Int64(60)
+		units.AccessToken = aws.String(cognitoidentityprovider.TimeUnitsTypeMinutes)
+	}
+	if aws.Int64Value(client.IdTokenValidity) == 0 {
+		client.IdTokenValidity = aws.Int64(60)
+		units.IdToken = aws.String(cognitoidentityprovider.TimeUnitsTypeMinutes)
+	}
+	if aws.Int64Value(client.AuthSessionValidity) == 0 {
+		client.AuthSessionValidity = aws.Int64(3)
+	}
+	if client.PreventUserExistenceErrors == nil {
+		client.PreventUserExistenceErrors = aws.String(cognitoidentityprovider.PreventUserExistenceErrorTypesLegacy)
+	}
+}
+
+// poolClient looks up CLIENT_ID and checks it belongs to POOL_ID.
+func (c *Cognito) poolClient(poolID *string, clientID *string) (*cognitoidentityprovider.UserPoolClientType, error) {
+	if _, err := c.findPool(poolID); err != nil {
+		return nil, err
+	}
+	client, _, err := c.findClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(client.UserPoolId) != aws.StringValue(poolID) {
+		return nil, newError(cognitoidentityprovider.ErrCodeResourceNotFoundException,
+			fmt.Sprintf("User pool client %s does not exist.", aws.StringValue(clientID)))
+	}
+	return client, nil
+}
+
+func copyClient(client *cognitoidentityprovider.UserPoolClientType) *cognitoidentityprovider.UserPoolClientType {
+	out := *client
+	return &out
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) DisableUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Disable User Handler")
+
+	item := user.AdminUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.DisableUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) EnableUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Enable User Handler")
+
+	item := user.AdminUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.EnableUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) AdminGlobalSignOut(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Admin Global Sign Out Handler")
+
+	item := user.AdminUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AdminGlobalSignOut(item)
+	return reply(request, response)
+}
+
+func (h Handler) LockUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Lock User Handler")
+
+	item := user.AdminUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.LockUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) ResetUserPassword(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Reset User Password Handler")
+
+	item := user.AdminUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ResetUserPassword(item)
+	return reply(request, response)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) GetAccount(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Get Account Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AccessToken(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.GetAccount(item)
+	return reply(request, response)
+}
+
+func (h Handler) ChangePassword(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Change Password Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ChangePassword(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ChangePassword(item)
+	return reply(request, response)
+}
+
+func (h Handler) UpdateUserAttributes(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Update User Attributes Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().UpdateUserAttributes(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.UpdateUserAttributes(item)
+	return reply(request, response)
+}
+
+func (h Handler) SendAttributeVerificationCode(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Send Attribute Verification Code Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().SendAttributeVerificationCode(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.SendAttributeVerificationCode(item)
+	return reply(request, response)
+}
+
+func (h Handler) VerifyUserAttribute(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Verify User Attribute Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().VerifyUserAttribute(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.VerifyUserAttribute(item)
+	return reply(request, response)
+}
+
+func (h Handler) GlobalSignOut(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Global Sign Out Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AccessToken(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.GlobalSignOut(item)
+	return reply(request, response)
+}
+
+func (h Handler) DeleteAccount(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Delete Account Handler")
+
+	item := user.AccountRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AccessToken(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.DeleteAccount(item)
+	return reply(request, response)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) AdminGetUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Admin Get User Handler")
+
+	item := user.AttributesRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminGetUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AdminGetUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) AdminUpdateUserAttributes(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Admin Update User Attributes Handler")
+
+	item := user.AttributesRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUpdateUserAttributes(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AdminUpdateUserAttributes(item)
+	return reply(request, response)
+}
+
+func (h Handler) AdminDeleteUserAttributes(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Admin Delete User Attributes Handler")
+
+	item := user.AttributesRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminDeleteUserAttributes(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AdminDeleteUserAttributes(item)
+	return reply(request, response)
+}
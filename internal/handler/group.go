@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) CreateGroup(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Create Group Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().CreateGroup(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.CreateGroup(item)
+	return reply(request, response)
+}
+
+func (h Handler) UpdateGroup(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Update Group Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().UpdateGroup(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.UpdateGroup(item)
+	return reply(request, response)
+}
+
+func (h Handler) DeleteGroup(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Delete Group Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().DeleteGroup(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.DeleteGroup(item)
+	return reply(request, response)
+}
+
+func (h Handler) ListGroups(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("List Groups Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ListGroups(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ListGroups(item)
+	return reply(request, response)
+}
+
+func (h Handler) AddUserToGroup(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Add User To Group Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AddUserToGroup(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AddUserToGroup(item)
+	return reply(request, response)
+}
+
+func (h Handler) RemoveUserFromGroup(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Remove User From Group Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().RemoveUserFromGroup(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.RemoveUserFromGroup(item)
+	return reply(request, response)
+}
+
+func (h Handler) ListGroupsForUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("List Groups For User Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ListGroupsForUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ListGroupsForUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) ListUsersInGroup(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("List Users In Group Handler")
+
+	item := user.GroupRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ListUsersInGroup(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ListUsersInGroup(item)
+	return reply(request, response)
+}
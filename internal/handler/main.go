@@ -0,0 +1,199 @@
+// Package handler holds the API Gateway and Cognito trigger handlers shared by
+// the single router binary and the per-function binaries under cmd.
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/authorizer"
+	"fp-apac-cognito-service/internal/user"
+	"fp-apac-cognito-service/internal/userpool"
+	"fp-apac-cognito-service/internal/validation"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"os"
+)
+
+// Handler serves the endpoints. When Authorizer is nil every route that
+// needs a bearer token is refused, as no token can be verified. ObjectStore
+// may be nil, when user exports can only be returned directly; otherwise
+// they are stored in ExportBucket. UserPoolID is the pool the service
+// serves; client secrets are only looked up for it.
+type Handler struct {
+	UserPoolID string
+	CognitoClient cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	IAMService iamiface.IAMAPI
+	ObjectStore s3iface.S3API
+	ExportBucket string
+	Authorizer *authorizer.Authorizer
+	Policy authorizer.Policy
+}
+
+// NewSession initializes a session that the SDK will use to load
+// configuration, credentials, and region from the shared config file
+// (~/.aws/config), with the region taken from AWS_REGION. Any configs given
+// are merged on top.
+func NewSession(configs ...*aws.Config) (*session.Session, error) {
+	region := os.Getenv("AWS_REGION")
+	config := aws.Config{
+		Region: &region,
+	}
+	config.MergeIn(configs...)
+
+	return session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
+// New builds a Handler with Cognito, IAM and S3 clients from NewSession and
+// the authorizer and route policy configured by authorizer.FromEnv and
+// authorizer.PolicyFromEnv. It fails when COGNITO_USER_POOL_ID is not set
+// rather than serve administration routes no one could be let into.
+// S3_ENDPOINT points the S3 client at an S3-compatible store instead, and
+// EXPORT_BUCKET names the bucket user exports are stored in.
+func New(configs ...*aws.Config) (Handler, error) {
+	sessions, err := NewSession(configs...)
+	if err != nil {
+		return Handler{}, err
+	}
+	tokens, err := authorizer.FromEnv()
+	if err != nil {
+		return Handler{}, err
+	}
+	if tokens == nil {
+		return Handler{}, errors.New("COGNITO_USER_POOL_ID must be set")
+	}
+	policy, err := LoadPolicy()
+	if err != nil {
+		return Handler{}, err
+	}
+	return Handler{
+		UserPoolID:    os.Getenv("COGNITO_USER_POOL_ID"),
+		CognitoClient: cognitoidentityprovider.New(sessions),
+		IAMService:    iam.New(sessions),
+		ObjectStore:   s3.New(sessions, ObjectStoreConfig(os.Getenv("S3_ENDPOINT"))),
+		ExportBucket:  os.Getenv("EXPORT_BUCKET"),
+		Authorizer:    tokens,
+		Policy:        policy,
+	}, nil
+}
+
+// LoadPolicy reads the route policy with authorizer.PolicyFromEnv and checks
+// that every rule names a route.
+func LoadPolicy() (authorizer.Policy, error) {
+	policy, err := authorizer.PolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ObjectStoreConfig points the S3 client at ENDPOINT, if given, using
+// path-style addressing as most S3-compatible stores require.
+func ObjectStoreConfig(endpoint string) *aws.Config {
+	config := &aws.Config{}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	return config
+}
+
+func (h Handler) userConfig(information string) user.Config {
+	return user.Config{
+		Information:   information,
+		CognitoClient: h.CognitoClient,
+		UserPoolID:    h.UserPoolID,
+		ObjectStore:   h.ObjectStore,
+		ExportBucket:  h.ExportBucket,
+	}
+}
+
+func (h Handler) validator() validation.Config {
+	return validation.Config{
+		CognitoClient: h.CognitoClient,
+	}
+}
+
+func (h Handler) userPoolConfig(information string) userpool.Config {
+	return userpool.Config{
+		Information:   information,
+		CognitoClient: h.CognitoClient,
+		IAMService:    h.IAMService,
+	}
+}
+
+// parse decodes the JSON request body into item. API Gateway, and the local
+// server, base64-encode bodies that are not valid UTF-8.
+func parse(request events.APIGatewayProxyRequest, item interface{}) error {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return err
+		}
+		body = decoded
+	}
+	return json.Unmarshal(body, item)
+}
+
+// malformed answers REQUEST when parse could not decode its body.
+func malformed(request events.APIGatewayProxyRequest, err error) (events.APIGatewayProxyResponse, error) {
+	return problem(request, apierror.Malformed(err), nil)
+}
+
+// problem answers REQUEST with the problem document for err. The members of
+// DETAILS, the response that failed if there is one, are kept in it.
+func problem(request events.APIGatewayProxyRequest, err error, details interface{}) (events.APIGatewayProxyResponse, error) {
+	body, status := apierror.ProblemBody(err, request.Path, details)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": apierror.ProblemContentType},
+		Body:       body,
+	}, nil
+}
+
+// outcome is implemented by every user and userpool response through the
+// apierror.Result they embed.
+type outcome interface {
+	Outcome() apierror.Result
+}
+
+// reply answers REQUEST with RESPONSE, or with a problem document when it
+// failed.
+func reply(request events.APIGatewayProxyRequest, response outcome) (events.APIGatewayProxyResponse, error) {
+	result := response.Outcome()
+	if result.Error != nil {
+		return problem(request, result.Error, response)
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		return respond("Unable to construct JSON", 500)
+	}
+	return respond(string(body), result.ResponseCode)
+}
+
+// respond takes the (body, status) pair returned by the user and userpool
+// methods and wraps it in a proxy response.
+func respond(body string, statusCode int) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode:        statusCode,
+		Headers:           nil,
+		MultiValueHeaders: nil,
+		Body:              body,
+		IsBase64Encoded:   false,
+	}, nil
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/authorizer"
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	body := `{"email_address": "jo@example.com"}`
+	tests := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+		wantErr bool
+	}{
+		{"plain body", events.APIGatewayProxyRequest{Body: body}, false},
+		{"base64 body", events.APIGatewayProxyRequest{Body: base64.StdEncoding.EncodeToString([]byte(body)), IsBase64Encoded: true}, false},
+		{"bad base64", events.APIGatewayProxyRequest{Body: "not base64!", IsBase64Encoded: true}, true},
+		{"bad JSON", events.APIGatewayProxyRequest{Body: "{"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var item struct {
+				Email string `json:"email_address"`
+			}
+			err := parse(test.request, &item)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("parse succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			if item.Email != "jo@example.com" {
+				t.Fatalf("email = %q", item.Email)
+			}
+		})
+	}
+}
+
+const password = "Passw0rd!"
+
+// newHandler returns a Handler on the emulator with the default route
+// policy, and the pool and client its tokens come from. The pool holds
+// admin@example.com in the admin group and jo@example.com in no group.
+func newHandler(t *testing.T) (Handler, string, string) {
+	fake := cognitofake.New("ap-southeast-1")
+	pool, err := fake.CreateUserPool(&cognitoidentityprovider.CreateUserPoolInput{
+		PoolName:           aws.String("test"),
+		UsernameAttributes: aws.StringSlice([]string{cognitoidentityprovider.UsernameAttributeTypeEmail}),
+	})
+	if err != nil {
+		t.Fatalf("creating pool: %s", err)
+	}
+	poolID := aws.StringValue(pool.UserPool.Id)
+	client, err := fake.CreateUserPoolClient(&cognitoidentityprovider.CreateUserPoolClientInput{
+		ClientName:        aws.String("test"),
+		ExplicitAuthFlows: aws.StringSlice([]string{"ALLOW_USER_PASSWORD_AUTH"}),
+		UserPoolId:        pool.UserPool.Id,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err)
+	}
+	if _, err := fake.CreateGroup(&cognitoidentityprovider.CreateGroupInput{GroupName: aws.String("admin"), UserPoolId: pool.UserPool.Id}); err != nil {
+		t.Fatalf("creating group: %s", err)
+	}
+
+	h := Handler{
+		UserPoolID:    poolID,
+		CognitoClient: fake,
+		IAMService:    cognitofake.NewIAM(),
+		Authorizer:    authorizer.New("ap-southeast-1", poolID),
+		Policy:        authorizer.DefaultPolicy(),
+	}
+	h.Authorizer.Keys = authorizer.NewKeySetFunc(fake.JWKS)
+	for _, email := range []string{"admin@example.com", "jo@example.com"} {
+		created := h.userConfig("test").AddUser(user.UserItem{
+			UserPoolID: poolID,
+			User:       user.NewUserItem{Email: email, Name: "Jo", Password: password},
+		})
+		if created.ResponseCode != 200 {
+			t.Fatalf("creating %s: %s", email, created.Message)
+		}
+	}
+	_, err = fake.AdminAddUserToGroup(&cognitoidentityprovider.AdminAddUserToGroupInput{
+		GroupName:  aws.String("admin"),
+		UserPoolId: pool.UserPool.Id,
+		Username:   aws.String("admin@example.com"),
+	})
+	if err != nil {
+		t.Fatalf("adding admin to group: %s", err)
+	}
+	return h, poolID, aws.StringValue(client.UserPoolClient.ClientId)
+}
+
+// signIn returns an access token for EMAIL.
+func signIn(t *testing.T, h Handler, clientID string, email string) string {
+	response := h.userConfig("test").AuthenticateUser(user.LoginRequest{Email: email, Password: password, ClientID: clientID})
+	if response.ResponseCode != 200 {
+		t.Fatalf("signing %s in: %s", email, response.Message)
+	}
+	return response.AccessToken
+}
+
+func post(path string, token string, body interface{}) events.APIGatewayProxyRequest {
+	encoded, _ := json.Marshal(body)
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Path:       path,
+		Headers:    map[string]string{},
+		Body:       string(encoded),
+	}
+	if token != "" {
+		request.Headers["Authorization"] = "Bearer " + token
+	}
+	return request
+}
+
+func TestRouterProtectsRoutes(t *testing.T) {
+	h, poolID, clientID := newHandler(t)
+	admin := signIn(t, h, clientID, "admin@example.com")
+	member := signIn(t, h, clientID, "jo@example.com")
+	api := h.Router()
+
+	listUsers := user.ListUserRequest{UserPoolID: poolID}
+	tests := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+		status  int
+	}{
+		{"serves public route without token", post("/api/v1/user/auth", "", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: clientID}), 200},
+		{"refuses protected route without token", post("/api/v1/users", "", listUsers), 401},
+		{"refuses protected route with bad token", post("/api/v1/users", "not.a.token", listUsers), 401},
+		{"refuses caller outside the admin group", post("/api/v1/users", member, listUsers), 403},
+		{"refuses user creation outside the admin group", post("/api/v1/user", member, user.UserItem{UserPoolID: poolID, User: user.NewUserItem{Email: "new@example.com", Name: "New", Password: password}}), 403},
+		{"refuses pool listing outside the admin group", get("/api/v1/userpools/10", member), 403},
+		{"serves admin", post("/api/v1/users", admin, listUsers), 200},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := api.Handle(test.request)
+			if err != nil {
+				t.Fatalf("Handle: %s", err)
+			}
+			if response.StatusCode != test.status {
+				t.Fatalf("status = %d (%s), want %d", response.StatusCode, response.Body, test.status)
+			}
+		})
+	}
+}
+
+func get(path string, token string) events.APIGatewayProxyRequest {
+	request := post(path, token, nil)
+	request.HTTPMethod = "GET"
+	request.Body = ""
+	return request
+}
+
+func TestDefaultPolicyCoversProtectedRoutes(t *testing.T) {
+	policy := authorizer.DefaultPolicy()
+	for _, route := range (Handler{}).Routes() {
+		if _, ok := policy[route.Name]; !ok && !publicRoutes[route.Name] {
+			t.Errorf("%s needs a token but has no rule, so everyone is refused it", route.Name)
+		}
+	}
+}
+
+func TestRouterWithoutAuthorizer(t *testing.T) {
+	h, poolID, clientID := newHandler(t)
+	h.Authorizer = nil
+	api := h.Router()
+
+	response, err := api.Handle(post("/api/v1/users", "", user.ListUserRequest{UserPoolID: poolID}))
+	if err != nil {
+		t.Fatalf("Handle: %s", err)
+	}
+	if response.StatusCode != 500 {
+		t.Fatalf("protected route status = %d (%s), want 500", response.StatusCode, response.Body)
+	}
+
+	response, err = api.Handle(post("/api/v1/user/auth", "", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: clientID}))
+	if err != nil {
+		t.Fatalf("Handle: %s", err)
+	}
+	if response.StatusCode != 200 {
+		t.Fatalf("public route status = %d (%s), want 200", response.StatusCode, response.Body)
+	}
+}
+
+func TestFunctionProtectsRoute(t *testing.T) {
+	h, poolID, _ := newHandler(t)
+	handle, err := h.Function("delete_user")
+	if err != nil {
+		t.Fatalf("Function: %s", err)
+	}
+	response, err := handle(post("/api/v1/user/delete", "", map[string]string{"user_pool_id": poolID, "email_address": "jo@example.com"}))
+	if err != nil {
+		t.Fatalf("handle: %s", err)
+	}
+	if response.StatusCode != 401 {
+		t.Fatalf("status = %d (%s), want 401", response.StatusCode, response.Body)
+	}
+
+	if _, err := h.Function("missing"); err == nil {
+		t.Fatalf("Function found a route that does not exist")
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	if err := checkPolicy(authorizer.DefaultPolicy()); err != nil {
+		t.Fatalf("default policy: %s", err)
+	}
+	if err := checkPolicy(authorizer.Policy{"list_users": {Groups: []string{"admin"}}}); err == nil {
+		t.Fatalf("accepted a rule for an unknown route")
+	}
+}
+
+func TestFailuresAreProblems(t *testing.T) {
+	h, poolID, clientID := newHandler(t)
+	admin := signIn(t, h, clientID, "admin@example.com")
+	api := h.Router()
+
+	tests := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+		status  int
+		code    string
+		errors  int
+	}{
+		{"invalid fields", post("/api/v1/user", admin, user.UserItem{}), 400, "InvalidRequest", 4},
+		{"malformed body", events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/api/v1/user/auth", Body: "{"}, 400, apierror.CodeMalformedRequest, 0},
+		{"Cognito error", post("/api/v1/user/auth", "", user.LoginRequest{Email: "jo@example.com", Password: "Wr0ng!pass", ClientID: clientID}), 401, cognitoidentityprovider.ErrCodeNotAuthorizedException, 0},
+		{"missing token", post("/api/v1/users", "", user.ListUserRequest{UserPoolID: poolID}), 401, "", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := api.Handle(test.request)
+			if err != nil {
+				t.Fatalf("Handle: %s", err)
+			}
+			if response.StatusCode != test.status || response.Headers["Content-Type"] != apierror.ProblemContentType {
+				t.Fatalf("response = %d %v (%s), want %d %s", response.StatusCode, response.Headers, response.Body, test.status, apierror.ProblemContentType)
+			}
+			var problem apierror.Problem
+			if err := json.Unmarshal([]byte(response.Body), &problem); err != nil {
+				t.Fatalf("body %s: %s", response.Body, err)
+			}
+			if problem.Status != test.status || test.code != "" && problem.Code != test.code || len(problem.Errors) != test.errors || problem.Instance != test.request.Path {
+				t.Errorf("problem = %+v, want %d %s with %d errors", problem, test.status, test.code, test.errors)
+			}
+		})
+	}
+}
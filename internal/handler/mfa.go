@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) AssociateSoftwareToken(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Associate Software Token Handler")
+
+	item := user.SoftwareTokenRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AssociateSoftwareToken(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AssociateSoftwareToken(item)
+	return reply(request, response)
+}
+
+func (h Handler) VerifySoftwareToken(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Verify Software Token Handler")
+
+	item := user.SoftwareTokenRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().VerifySoftwareToken(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.VerifySoftwareToken(item)
+	return reply(request, response)
+}
+
+func (h Handler) SetMFAPreference(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Set MFA Preference Handler")
+
+	item := user.MFAPreferenceRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().SetMFAPreference(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.SetMFAPreference(item)
+	return reply(request, response)
+}
+
+func (h Handler) AdminSetMFAPreference(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Admin Set MFA Preference Handler")
+
+	item := user.MFAPreferenceRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminSetMFAPreference(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AdminSetMFAPreference(item)
+	return reply(request, response)
+}
+
+func (h Handler) SetUserPoolMfaConfig(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("Set User Pool MFA Config Handler")
+
+	item := userpool.MfaConfigRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().SetUserPoolMfaConfig(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.SetUserPoolMfaConfig(item)
+	return reply(request, response)
+}
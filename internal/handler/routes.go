@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/authorizer"
+	"fp-apac-cognito-service/internal/router"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// publicRoutes are open to anyone or carry their own access token or session
+// in the body. Every other route needs a bearer token.
+var publicRoutes = map[string]bool{
+	"authenticate_user":                true,
+	"refresh_token":                    true,
+	"logout":                           true,
+	"respond_to_auth_challenge":        true,
+	"sign_up":                          true,
+	"confirm_sign_up":                  true,
+	"resend_confirmation_code":         true,
+	"confirm_forgot_password":          true,
+	"forgot_password":                  true,
+	"associate_software_token":         true,
+	"verify_software_token":            true,
+	"set_mfa_preference":               true,
+	"get_account":                      true,
+	"change_password":                  true,
+	"update_user_attributes":           true,
+	"send_attribute_verification_code": true,
+	"verify_user_attribute":            true,
+	"global_sign_out":                  true,
+	"delete_account":                   true,
+}
+
+// Routes mirrors the http events declared in serverless.yml. Names match the
+// serverless function names and the per-function binaries under cmd.
+func (h Handler) Routes() []router.Route {
+	return []router.Route{
+		{Name: "authenticate_user", Method: "POST", Path: "/api/v1/user/auth", Handler: h.AuthenticateUser},
+		{Name: "refresh_token", Method: "POST", Path: "/api/v1/user/auth/refresh", Handler: h.RefreshToken},
+		{Name: "logout", Method: "POST", Path: "/api/v1/user/logout", Handler: h.Logout},
+		{Name: "respond_to_auth_challenge", Method: "POST", Path: "/api/v1/user/auth/challenge", Handler: h.RespondToAuthChallenge},
+		{Name: "create_user", Method: "POST", Path: "/api/v1/user", Handler: h.CreateUser},
+		{Name: "delete_user", Method: "POST", Path: "/api/v1/user/delete", Handler: h.DeleteUser},
+		{Name: "get_user", Method: "POST", Path: "/api/v1/user/describe", Handler: h.GetUser},
+		{Name: "list_user", Method: "POST", Path: "/api/v1/users", Handler: h.ListUser},
+		{Name: "import_users", Method: "POST", Path: "/api/v1/users/import", Handler: h.ImportUsers},
+		{Name: "export_users", Method: "POST", Path: "/api/v1/users/export", Handler: h.ExportUsers},
+		{Name: "restore_users", Method: "POST", Path: "/api/v1/users/restore", Handler: h.RestoreUsers},
+		{Name: "bulk_delete_users", Method: "POST", Path: "/api/v1/users/delete", Handler: h.BulkDeleteUsers},
+		{Name: "bulk_disable_users", Method: "POST", Path: "/api/v1/users/disable", Handler: h.BulkDisableUsers},
+		{Name: "confirm_forgot_password", Method: "POST", Path: "/api/v1/user/password/forgot/confirm", Handler: h.ConfirmForgotPassword},
+		{Name: "forgot_password", Method: "POST", Path: "/api/v1/user/password/forgot", Handler: h.ForgotPassword},
+		{Name: "reset_user_password", Method: "POST", Path: "/api/v1/user/password/reset", Handler: h.ResetUserPassword},
+		{Name: "get_account", Method: "POST", Path: "/api/v1/user/me", Handler: h.GetAccount},
+		{Name: "change_password", Method: "POST", Path: "/api/v1/user/me/password", Handler: h.ChangePassword},
+		{Name: "update_user_attributes", Method: "PATCH", Path: "/api/v1/user/me/attributes", Handler: h.UpdateUserAttributes},
+		{Name: "send_attribute_verification_code", Method: "POST", Path: "/api/v1/user/me/attributes/code", Handler: h.SendAttributeVerificationCode},
+		{Name: "verify_user_attribute", Method: "POST", Path: "/api/v1/user/me/attributes/verify", Handler: h.VerifyUserAttribute},
+		{Name: "global_sign_out", Method: "POST", Path: "/api/v1/user/me/signout", Handler: h.GlobalSignOut},
+		{Name: "delete_account", Method: "POST", Path: "/api/v1/user/me/delete", Handler: h.DeleteAccount},
+		{Name: "admin_get_user", Method: "POST", Path: "/api/v1/user/get", Handler: h.AdminGetUser},
+		{Name: "admin_update_user_attributes", Method: "PATCH", Path: "/api/v1/user/attributes", Handler: h.AdminUpdateUserAttributes},
+		{Name: "admin_delete_user_attributes", Method: "POST", Path: "/api/v1/user/attributes/delete", Handler: h.AdminDeleteUserAttributes},
+		{Name: "disable_user", Method: "POST", Path: "/api/v1/user/disable", Handler: h.DisableUser},
+		{Name: "enable_user", Method: "POST", Path: "/api/v1/user/enable", Handler: h.EnableUser},
+		{Name: "admin_global_sign_out", Method: "POST", Path: "/api/v1/user/signout/admin", Handler: h.AdminGlobalSignOut},
+		{Name: "lock_user", Method: "POST", Path: "/api/v1/user/lock", Handler: h.LockUser},
+		{Name: "sign_up", Method: "POST", Path: "/api/v1/user/signup", Handler: h.SignUp},
+		{Name: "confirm_sign_up", Method: "POST", Path: "/api/v1/user/signup/confirm", Handler: h.ConfirmSignUp},
+		{Name: "resend_confirmation_code", Method: "POST", Path: "/api/v1/user/signup/resend", Handler: h.ResendConfirmationCode},
+		{Name: "associate_software_token", Method: "POST", Path: "/api/v1/user/mfa/totp/associate", Handler: h.AssociateSoftwareToken},
+		{Name: "verify_software_token", Method: "POST", Path: "/api/v1/user/mfa/totp/verify", Handler: h.VerifySoftwareToken},
+		{Name: "set_mfa_preference", Method: "POST", Path: "/api/v1/user/mfa/preference", Handler: h.SetMFAPreference},
+		{Name: "admin_set_mfa_preference", Method: "POST", Path: "/api/v1/user/mfa/preference/admin", Handler: h.AdminSetMFAPreference},
+		{Name: "create_group", Method: "POST", Path: "/api/v1/group", Handler: h.CreateGroup},
+		{Name: "update_group", Method: "PATCH", Path: "/api/v1/group/update", Handler: h.UpdateGroup},
+		{Name: "delete_group", Method: "POST", Path: "/api/v1/group/delete", Handler: h.DeleteGroup},
+		{Name: "list_groups", Method: "POST", Path: "/api/v1/groups", Handler: h.ListGroups},
+		{Name: "add_user_to_group", Method: "POST", Path: "/api/v1/group/user/add", Handler: h.AddUserToGroup},
+		{Name: "remove_user_from_group", Method: "POST", Path: "/api/v1/group/user/remove", Handler: h.RemoveUserFromGroup},
+		{Name: "list_users_in_group", Method: "POST", Path: "/api/v1/group/users", Handler: h.ListUsersInGroup},
+		{Name: "list_groups_for_user", Method: "POST", Path: "/api/v1/user/groups", Handler: h.ListGroupsForUser},
+		{Name: "create_userpool", Method: "POST", Path: "/api/v1/userpool", Handler: h.CreateUserPool},
+		{Name: "list_userpool", Method: "GET", Path: "/api/v1/userpools/{max}", Handler: h.ListUserPool},
+		{Name: "list_userpool_client", Method: "POST", Path: "/api/v1/userpools/client", Handler: h.ListUserPoolClient},
+		{Name: "describe_userpool_client", Method: "POST", Path: "/api/v1/userpools/client/describe", Handler: h.DescribeUserPoolClient},
+		{Name: "create_userpool_client", Method: "POST", Path: "/api/v1/userpools/client/create", Handler: h.CreateUserPoolClient},
+		{Name: "update_userpool_client", Method: "PATCH", Path: "/api/v1/userpools/client/update", Handler: h.UpdateUserPoolClient},
+		{Name: "set_userpool_mfa_config", Method: "POST", Path: "/api/v1/userpools/mfa", Handler: h.SetUserPoolMfaConfig},
+	}
+}
+
+// Router returns a router serving every route, with authentication and the
+// route policy applied to the non-public ones.
+func (h Handler) Router() *router.Router {
+	routes := h.Routes()
+	for i, route := range routes {
+		routes[i].Handler = h.protect(route)
+	}
+	return router.New(routes...)
+}
+
+// protect requires a valid bearer token for ROUTE, then enforces its policy
+// rule. Without an authorizer no token can be verified, so only public
+// routes are served.
+func (h Handler) protect(route router.Route) router.HandlerFunc {
+	if publicRoutes[route.Name] {
+		return route.Handler
+	}
+	if h.Authorizer == nil {
+		return unverifiable
+	}
+	return h.Authorizer.Wrap(h.Policy.Enforce(route.Name, route.Handler))
+}
+
+// unverifiable refuses a request to a protected route when the handler was
+// built without an authorizer.
+func unverifiable(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return problem(request, apierror.New(500, apierror.CodeInternalError, "Bearer tokens cannot be verified as no user pool is configured"), nil)
+}
+
+// Function returns the handler of the route NAME guarded as Router guards
+// it, for the binaries deploying a single endpoint as its own function.
+func (h Handler) Function(name string) (router.HandlerFunc, error) {
+	for _, route := range h.Routes() {
+		if route.Name == name {
+			return h.protect(route), nil
+		}
+	}
+	return nil, fmt.Errorf("no route named %s", name)
+}
+
+// checkPolicy rejects rules for routes that do not exist, which are most
+// likely typos that would leave the intended route unguarded.
+func checkPolicy(policy authorizer.Policy) error {
+	names := map[string]bool{}
+	for _, route := range (Handler{}).Routes() {
+		names[route.Name] = true
+	}
+	for name := range policy {
+		if !names[name] {
+			return fmt.Errorf("route policy names unknown route %s", name)
+		}
+	}
+	return nil
+}
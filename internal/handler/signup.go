@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) SignUp(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Sign Up Handler")
+
+	item := user.SignUpRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().SignUp(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.SignUp(item)
+	return reply(request, response)
+}
+
+func (h Handler) ConfirmSignUp(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Confirm Sign Up Handler")
+
+	item := user.SignUpRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ConfirmSignUp(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ConfirmSignUp(item)
+	return reply(request, response)
+}
+
+func (h Handler) ResendConfirmationCode(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Resend Confirmation Code Handler")
+
+	item := user.SignUpRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ResendConfirmationCode(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ResendConfirmationCode(item)
+	return reply(request, response)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+// AutoVerify is the pre sign-up trigger; it confirms self-registered users and
+// marks their email as verified without sending a code.
+func AutoVerify(event events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+	event.Response.AutoVerifyEmail = true
+	event.Response.AutoConfirmUser = true
+	return event, nil
+}
+
+// VerifyEmail is the post confirmation trigger; it sets email_verified on the
+// confirmed user.
+func (h Handler) VerifyEmail(event events.CognitoEventUserPoolsPostConfirmation) (events.CognitoEventUserPoolsPostConfirmation, error) {
+	listAttributes := []*cognitoidentityprovider.AttributeType{
+		{
+			Name:  aws.String("email_verified"),
+			Value: aws.String("true"),
+		},
+	}
+
+	params := &cognitoidentityprovider.AdminUpdateUserAttributesInput{
+		UserAttributes: listAttributes,
+		UserPoolId:     aws.String(event.UserPoolID),
+		Username:       aws.String(event.UserName),
+	}
+
+	_, err := h.CognitoClient.AdminUpdateUserAttributes(params)
+	if err != nil {
+		return event, err
+	}
+	return event, nil
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h Handler) CreateUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Create User Handler")
+
+	item := user.UserItem{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().CreateUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AddUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) DeleteUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Delete User Handler")
+
+	item := user.UserItem{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().DeleteUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.DeleteUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) GetUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Get User Handler")
+
+	item := user.AdminUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AdminUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.GetUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) ListUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("List User Handler")
+
+	item := user.ListUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ListUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ListUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) ImportUsers(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Import Users Handler")
+
+	item := user.ImportRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ImportUsers(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ImportUsers(item)
+	return reply(request, response)
+}
+
+func (h Handler) ExportUsers(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Export Users Handler")
+
+	item := user.ExportRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ExportUsers(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ExportUsers(item)
+	return reply(request, response)
+}
+
+func (h Handler) RestoreUsers(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Restore Users Handler")
+
+	item := user.RestoreRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().RestoreUsers(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.RestoreUsers(item)
+	return reply(request, response)
+}
+
+func (h Handler) BulkDeleteUsers(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Bulk Delete Users Handler")
+
+	item := user.BulkUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().BulkUsers(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.BulkDeleteUsers(item)
+	return reply(request, response)
+}
+
+func (h Handler) BulkDisableUsers(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Bulk Disable Users Handler")
+
+	item := user.BulkUserRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().BulkUsers(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.BulkDisableUsers(item)
+	return reply(request, response)
+}
+
+func (h Handler) AuthenticateUser(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Authenticate User Handler")
+
+	item := user.LoginRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().AuthenticateUser(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.AuthenticateUser(item)
+	return reply(request, response)
+}
+
+func (h Handler) RefreshToken(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Refresh Token Handler")
+
+	item := user.RefreshRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().RefreshToken(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.RefreshToken(item)
+	return reply(request, response)
+}
+
+func (h Handler) Logout(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Logout Handler")
+
+	item := user.LogoutRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().Logout(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.Logout(item)
+	return reply(request, response)
+}
+
+func (h Handler) RespondToAuthChallenge(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Respond To Auth Challenge Handler")
+
+	item := user.ChallengeRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().RespondToAuthChallenge(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.RespondToAuthChallenge(item)
+	return reply(request, response)
+}
+
+func (h Handler) ForgotPassword(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Forgot Password Handler")
+
+	item := user.LoginRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ForgotPassword(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ForgotPassword(item)
+	return reply(request, response)
+}
+
+func (h Handler) ConfirmForgotPassword(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userConfig("Confirm Forgot Password Handler")
+
+	item := user.ForgotPasswordRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ConfirmForgotPassword(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ConfirmForgotPassword(item)
+	return reply(request, response)
+}
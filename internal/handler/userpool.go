@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strconv"
+)
+
+func (h Handler) CreateUserPool(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("Create User Pool Handler")
+
+	item := userpool.CreatePoolRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().CreateUserPool(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.CreateUserPool(item)
+	return reply(request, response)
+}
+
+func (h Handler) ListUserPool(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("List User Pool Handler")
+
+	max, err := strconv.Atoi(request.PathParameters["max"])
+	if err != nil {
+		return problem(request, apierror.InvalidFields([]apierror.FieldError{
+			{Field: "max", Code: apierror.FieldMalformed, Message: "must be a number"},
+		}), nil)
+	}
+	if err := h.validator().ListUserPool(int64(max)); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.ListUserPool(int64(max))
+	return reply(request, response)
+}
+
+func (h Handler) ListUserPoolClient(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("List User Pool Client Handler")
+
+	item := userpool.ListUserPoolClientRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().ListUserPoolClient(item); err != nil {
+		return problem(request, err, nil)
+	}
+	query := &cognitoidentityprovider.ListUserPoolClientsInput{
+		MaxResults: aws.Int64(item.Max),
+		NextToken:  nil,
+		UserPoolId: aws.String(item.PoolID),
+	}
+	response := modelConfig.ListUserPoolClients(query)
+	return reply(request, response)
+}
+
+func (h Handler) DescribeUserPoolClient(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("Describe User Pool Client Handler")
+
+	item := userpool.ListUserPoolClientRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().DescribeUserPoolClient(item); err != nil {
+		return problem(request, err, nil)
+	}
+	query := &cognitoidentityprovider.DescribeUserPoolClientInput{
+		UserPoolId: aws.String(item.PoolID),
+		ClientId:   aws.String(item.ClientID),
+	}
+	response := modelConfig.DescribeUserPoolClient(query)
+	return reply(request, response)
+}
+
+func (h Handler) CreateUserPoolClient(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("Create User Pool Client Handler")
+
+	item := userpool.CreateUserPoolClientRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().CreateUserPoolClient(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.CreateUserPoolClient(item)
+	return reply(request, response)
+}
+
+func (h Handler) UpdateUserPoolClient(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	modelConfig := h.userPoolConfig("Update User Pool Client Handler")
+
+	item := userpool.UpdateUserPoolClientRequest{}
+	if err := parse(request, &item); err != nil {
+		return malformed(request, err)
+	}
+	if err := h.validator().UpdateUserPoolClient(item); err != nil {
+		return problem(request, err, nil)
+	}
+	response := modelConfig.UpdateUserPoolClient(item.CreateUserPoolClientRequest, item.ClientID)
+	return reply(request, response)
+}
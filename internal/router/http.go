@@ -0,0 +1,150 @@
+package router
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/aws/aws-lambda-go/events"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// ServeHTTP lets the router run on net/http for local development. Each
+// request is converted into the proxy event API Gateway would send and the
+// proxy response is written back out.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	event, err := NewProxyRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := r.Handle(event)
+	if err != nil {
+		// Lambda turns a handler error into a 502 from API Gateway.
+		http.Error(w, `{"message": "Internal server error"}`, http.StatusBadGateway)
+		return
+	}
+	WriteProxyResponse(w, response)
+}
+
+// NewProxyRequest builds an API Gateway proxy event from an HTTP request.
+// Path parameters and the resource are left for Handle to resolve from the
+// route table.
+func NewProxyRequest(req *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:                      req.Method,
+		Path:                            req.URL.Path,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID:  newRequestID(),
+			Stage:      "local",
+			HTTPMethod: req.Method,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  sourceIP(req),
+				UserAgent: req.UserAgent(),
+			},
+		},
+	}
+
+	for name, values := range req.Header {
+		event.Headers[name] = values[0]
+		event.MultiValueHeaders[name] = values
+	}
+	if req.Host != "" {
+		event.Headers["Host"] = req.Host
+		event.MultiValueHeaders["Host"] = []string{req.Host}
+	}
+	for name, values := range req.URL.Query() {
+		event.QueryStringParameters[name] = values[0]
+		event.MultiValueQueryStringParameters[name] = values
+	}
+
+	if utf8.Valid(body) {
+		event.Body = string(body)
+	} else {
+		event.Body = base64.StdEncoding.EncodeToString(body)
+		event.IsBase64Encoded = true
+	}
+	return event, nil
+}
+
+// WriteProxyResponse writes a proxy response the way API Gateway would.
+func WriteProxyResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for name, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			http.Error(w, `{"message": "Internal server error"}`, http.StatusBadGateway)
+			return
+		}
+		body = decoded
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// Logger wraps an http.Handler and logs each request with its status and
+// duration through logf.
+func Logger(next http.Handler, logf func(format string, v ...interface{})) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+		logf("%s %s %d %s", req.Method, req.URL.RequestURI(), recorder.status, time.Since(start))
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func sourceIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,131 @@
+// Package router dispatches API Gateway proxy requests to handlers by HTTP
+// method and resource path so a single Lambda can serve every endpoint.
+package router
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+)
+
+// HandlerFunc is the signature shared by every API Gateway proxy handler.
+type HandlerFunc func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Route binds a method and API Gateway resource path, such as
+// /api/v1/userpools/{max}, to a handler. Name matches the function name in
+// serverless.yml.
+type Route struct {
+	Name    string
+	Method  string
+	Path    string
+	Handler HandlerFunc
+}
+
+type Router struct {
+	routes []Route
+}
+
+func New(routes ...Route) *Router {
+	return &Router{routes: routes}
+}
+
+// Routes returns the route table in registration order.
+func (r *Router) Routes() []Route {
+	return append([]Route{}, r.routes...)
+}
+
+// Lookup finds the route for METHOD and PATH along with any path parameters
+// captured from it. Literal paths win over templated ones, so
+// /api/v1/userpools/client never matches /api/v1/userpools/{max}. The boolean
+// reports whether PATH exists at all, to tell a 404 from a 405.
+func (r *Router) Lookup(method string, path string) (*Route, map[string]string, bool) {
+	pathExists := false
+	var templated *Route
+	var templatedParams map[string]string
+
+	for i := range r.routes {
+		route := &r.routes[i]
+		params, ok := match(route.Path, path)
+		if !ok {
+			continue
+		}
+		pathExists = true
+		if !strings.EqualFold(route.Method, method) {
+			continue
+		}
+		if len(params) == 0 {
+			return route, params, true
+		}
+		if templated == nil {
+			templated, templatedParams = route, params
+		}
+	}
+	return templated, templatedParams, pathExists
+}
+
+// Handle is the Lambda entrypoint. Behind API Gateway the matched resource
+// template and path parameters are already on the request; other callers
+// are routed on the raw path.
+func (r *Router) Handle(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	for _, route := range r.routes {
+		if route.Path == request.Resource && strings.EqualFold(route.Method, request.HTTPMethod) {
+			return route.Handler(request)
+		}
+	}
+
+	route, params, pathExists := r.Lookup(request.HTTPMethod, request.Path)
+	if route == nil {
+		if pathExists {
+			return errorResult(request, apierror.New(405, apierror.CodeMethodNotAllowed, fmt.Sprintf("Method %s not allowed on %s", request.HTTPMethod, request.Path))), nil
+		}
+		return errorResult(request, apierror.New(404, apierror.CodeNotFound, fmt.Sprintf("No route for %s %s", request.HTTPMethod, request.Path))), nil
+	}
+
+	if len(params) > 0 {
+		merged := map[string]string{}
+		for k, v := range request.PathParameters {
+			merged[k] = v
+		}
+		for k, v := range params {
+			merged[k] = v
+		}
+		request.PathParameters = merged
+	}
+	request.Resource = route.Path
+	return route.Handler(request)
+}
+
+// match compares a route template against a request path segment by segment,
+// capturing {name} segments as path parameters.
+func match(template string, path string) (map[string]string, bool) {
+	templateParts := strings.Split(strings.Trim(template, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, part := range templateParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func errorResult(request events.APIGatewayProxyRequest, err *apierror.Error) events.APIGatewayProxyResponse {
+	body, status := apierror.ProblemBody(err, request.Path, nil)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": apierror.ProblemContentType},
+		Body:       body,
+	}
+}
@@ -0,0 +1,119 @@
+package user
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+// AdminUserRequest names a user for the admin access operations.
+type AdminUserRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Email string `json:"email_address"`
+}
+
+// StepResult reports one Cognito call made by a combined operation.
+type StepResult struct {
+	Step string `json:"step"`
+	apierror.Result
+}
+
+type LockResponse struct {
+	apierror.Result
+	Steps []StepResult `json:"steps"`
+}
+
+func (config Config) DisableUser(request AdminUserRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+	return config.disableUser(request)
+}
+
+func (config Config) EnableUser(request AdminUserRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+
+	_, err := config.CognitoClient.AdminEnableUser(&cognitoidentityprovider.AdminEnableUserInput{
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// AdminGlobalSignOut invalidates every refresh token issued to the user, and
+// the ID and access tokens issued alongside them.
+func (config Config) AdminGlobalSignOut(request AdminUserRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+	return config.globalSignOut(request)
+}
+
+// LockUser disables the user, so they cannot sign in or refresh, then signs
+// them out everywhere. Both steps are attempted and reported even if the
+// first fails; the response code is that of the first failure.
+func (config Config) LockUser(request AdminUserRequest) (response LockResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+
+	disabled := config.disableUser(request)
+	signedOut := config.globalSignOut(request)
+	response.Steps = []StepResult{
+		{Step: "disable_user", Result: disabled.Result},
+		{Step: "global_sign_out", Result: signedOut.Result},
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Account locked"
+	for _, step := range response.Steps {
+		if step.ResponseCode != 200 {
+			response.Fail(apierror.New(step.ResponseCode, step.Error.Code, "Account not fully locked, see steps"))
+			break
+		}
+	}
+	return
+}
+
+func (config Config) disableUser(request AdminUserRequest) (response StatusResponse) {
+	_, err := config.CognitoClient.AdminDisableUser(&cognitoidentityprovider.AdminDisableUserInput{
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func (config Config) globalSignOut(request AdminUserRequest) (response StatusResponse) {
+	_, err := config.CognitoClient.AdminUserGlobalSignOut(&cognitoidentityprovider.AdminUserGlobalSignOutInput{
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
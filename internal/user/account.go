@@ -0,0 +1,197 @@
+package user
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"sort"
+)
+
+// AccountRequest identifies the signed-in user by their access token for the
+// self-service operations. The other fields are read by the operations that
+// need them.
+type AccountRequest struct {
+	AccessToken string `json:"access_token"`
+	PreviousPassword string `json:"previous_password,omitempty"`
+	ProposedPassword string `json:"proposed_password,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	AttributeName string `json:"attribute_name,omitempty"`
+	Code string `json:"confirmation_code,omitempty"`
+}
+
+// UpdateAttributesResponse lists where Cognito sent codes to verify a changed
+// email or phone number.
+type UpdateAttributesResponse struct {
+	apierror.Result
+	CodeDeliveries []CodeDelivery `json:"code_deliveries,omitempty"`
+}
+
+// GetAccount returns the user the access token was issued to. Cognito does
+// not report status, enabled or dates for this call; GetUser does.
+func (config Config) GetAccount(request AccountRequest) (response UserResponse) {
+	if request.AccessToken == "" {
+		response.Invalid("You must supply an access token")
+		return
+	}
+
+	output, err := config.CognitoClient.GetUser(&cognitoidentityprovider.GetUserInput{
+		AccessToken: aws.String(request.AccessToken),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.UserList = []NewUserItem{newUserItem(&cognitoidentityprovider.UserType{
+		Attributes: output.UserAttributes,
+		MFAOptions: output.MFAOptions,
+		Username:   output.Username,
+	})}
+	return
+}
+
+func (config Config) ChangePassword(request AccountRequest) (response StatusResponse) {
+	if request.AccessToken == "" || request.PreviousPassword == "" || request.ProposedPassword == "" {
+		response.Invalid("You must supply an access token, previous password and proposed password")
+		return
+	}
+
+	_, err := config.CognitoClient.ChangePassword(&cognitoidentityprovider.ChangePasswordInput{
+		AccessToken:      aws.String(request.AccessToken),
+		PreviousPassword: aws.String(request.PreviousPassword),
+		ProposedPassword: aws.String(request.ProposedPassword),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// UpdateUserAttributes changes the user's own attributes. A changed email or
+// phone number stays unverified until confirmed with VerifyUserAttribute.
+func (config Config) UpdateUserAttributes(request AccountRequest) (response UpdateAttributesResponse) {
+	if request.AccessToken == "" || len(request.Attributes) == 0 {
+		response.Invalid("You must supply an access token and attributes")
+		return
+	}
+
+	names := make([]string, 0, len(request.Attributes))
+	for name := range request.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attributes := make([]*cognitoidentityprovider.AttributeType, 0, len(names))
+	for _, name := range names {
+		attributes = append(attributes, &cognitoidentityprovider.AttributeType{
+			Name:  aws.String(name),
+			Value: aws.String(request.Attributes[name]),
+		})
+	}
+
+	output, err := config.CognitoClient.UpdateUserAttributes(&cognitoidentityprovider.UpdateUserAttributesInput{
+		AccessToken:    aws.String(request.AccessToken),
+		UserAttributes: attributes,
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	for _, details := range output.CodeDeliveryDetailsList {
+		response.CodeDeliveries = append(response.CodeDeliveries, CodeDelivery{
+			Destination:    aws.StringValue(details.Destination),
+			DeliveryMedium: aws.StringValue(details.DeliveryMedium),
+			AttributeName:  aws.StringValue(details.AttributeName),
+		})
+	}
+	return
+}
+
+// SendAttributeVerificationCode sends a new code for verifying the email or
+// phone_number named by AttributeName.
+func (config Config) SendAttributeVerificationCode(request AccountRequest) (response CodeDeliveryResponse) {
+	if request.AccessToken == "" || request.AttributeName == "" {
+		response.Invalid("You must supply an access token and attribute name")
+		return
+	}
+
+	output, err := config.CognitoClient.GetUserAttributeVerificationCode(&cognitoidentityprovider.GetUserAttributeVerificationCodeInput{
+		AccessToken:   aws.String(request.AccessToken),
+		AttributeName: aws.String(request.AttributeName),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	return newCodeDeliveryResponse(output.CodeDeliveryDetails)
+}
+
+func (config Config) VerifyUserAttribute(request AccountRequest) (response StatusResponse) {
+	if request.AccessToken == "" || request.AttributeName == "" || request.Code == "" {
+		response.Invalid("You must supply an access token, attribute name and confirmation code")
+		return
+	}
+
+	_, err := config.CognitoClient.VerifyUserAttribute(&cognitoidentityprovider.VerifyUserAttributeInput{
+		AccessToken:   aws.String(request.AccessToken),
+		AttributeName: aws.String(request.AttributeName),
+		Code:          aws.String(request.Code),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// GlobalSignOut signs the user out of every device, invalidating their
+// refresh tokens and the tokens issued from them.
+func (config Config) GlobalSignOut(request AccountRequest) (response StatusResponse) {
+	if request.AccessToken == "" {
+		response.Invalid("You must supply an access token")
+		return
+	}
+
+	_, err := config.CognitoClient.GlobalSignOut(&cognitoidentityprovider.GlobalSignOutInput{
+		AccessToken: aws.String(request.AccessToken),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// DeleteAccount deletes the user the access token was issued to.
+func (config Config) DeleteAccount(request AccountRequest) (response StatusResponse) {
+	if request.AccessToken == "" {
+		response.Invalid("You must supply an access token")
+		return
+	}
+
+	_, err := config.CognitoClient.DeleteUser(&cognitoidentityprovider.DeleteUserInput{
+		AccessToken: aws.String(request.AccessToken),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
@@ -0,0 +1,213 @@
+package user
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AttributesRequest reads, sets or deletes a user's attributes by their
+// Cognito name, e.g. given_name or custom:department. With ValidateSchema the
+// names and values are checked against the pool's schema first, so a typo is
+// reported as a 400 naming the attribute.
+type AttributesRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Email string `json:"email_address"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	AttributeNames []string `json:"attribute_names,omitempty"`
+	ValidateSchema bool `json:"validate_schema,omitempty"`
+}
+
+// AttributesResponse carries the user along with every attribute, including
+// the standard ones NewUserItem has no field for.
+type AttributesResponse struct {
+	apierror.Result
+	User *NewUserItem `json:"user,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	PreferredMfa string `json:"preferred_mfa,omitempty"`
+	MfaSettings []string `json:"mfa_settings,omitempty"`
+}
+
+func (config Config) AdminGetUser(request AttributesRequest) (response AttributesResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+
+	output, err := config.CognitoClient.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	item := adminUserItem(output)
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.User = &item
+	response.Attributes = map[string]string{}
+	for _, a := range output.UserAttributes {
+		response.Attributes[aws.StringValue(a.Name)] = aws.StringValue(a.Value)
+	}
+	response.PreferredMfa = aws.StringValue(output.PreferredMfaSetting)
+	response.MfaSettings = aws.StringValueSlice(output.UserMFASettingList)
+	return
+}
+
+func (config Config) AdminUpdateUserAttributes(request AttributesRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" || len(request.Attributes) == 0 {
+		response.Invalid("You must supply a user pool ID, email address and attributes")
+		return
+	}
+
+	names := make([]string, 0, len(request.Attributes))
+	for name := range request.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if request.ValidateSchema {
+		if response = config.checkSchema(request.UserPoolID, names, request.Attributes); response.ResponseCode != 0 {
+			return
+		}
+	}
+
+	attributes := make([]*cognitoidentityprovider.AttributeType, 0, len(names))
+	for _, name := range names {
+		attributes = append(attributes, &cognitoidentityprovider.AttributeType{
+			Name:  aws.String(name),
+			Value: aws.String(request.Attributes[name]),
+		})
+	}
+	_, err := config.CognitoClient.AdminUpdateUserAttributes(&cognitoidentityprovider.AdminUpdateUserAttributesInput{
+		UserAttributes: attributes,
+		UserPoolId:     aws.String(request.UserPoolID),
+		Username:       aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func (config Config) AdminDeleteUserAttributes(request AttributesRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" || len(request.AttributeNames) == 0 {
+		response.Invalid("You must supply a user pool ID, email address and attribute names")
+		return
+	}
+
+	if request.ValidateSchema {
+		if response = config.checkSchema(request.UserPoolID, request.AttributeNames, nil); response.ResponseCode != 0 {
+			return
+		}
+	}
+
+	_, err := config.CognitoClient.AdminDeleteUserAttributes(&cognitoidentityprovider.AdminDeleteUserAttributesInput{
+		UserAttributeNames: aws.StringSlice(request.AttributeNames),
+		UserPoolId:         aws.String(request.UserPoolID),
+		Username:           aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// checkSchema looks every name up in the pool's schema and checks VALUES
+// against its type and constraints, or, without values, that the attributes
+// may be deleted. It leaves the response zero when everything passes.
+func (config Config) checkSchema(poolID string, names []string, values map[string]string) (response StatusResponse) {
+	output, err := config.CognitoClient.DescribeUserPool(&cognitoidentityprovider.DescribeUserPoolInput{
+		UserPoolId: aws.String(poolID),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	schema := map[string]*cognitoidentityprovider.SchemaAttributeType{}
+	for _, attribute := range output.UserPool.SchemaAttributes {
+		schema[aws.StringValue(attribute.Name)] = attribute
+	}
+
+	var problems []string
+	for _, name := range names {
+		attribute, ok := schema[name]
+		if !ok {
+			attribute, ok = schema["dev:"+name]
+		}
+		if !ok {
+			if _, custom := schema["custom:"+name]; custom {
+				problems = append(problems, fmt.Sprintf("%s is not in the schema, did you mean custom:%s", name, name))
+			} else {
+				problems = append(problems, fmt.Sprintf("%s is not in the schema", name))
+			}
+			continue
+		}
+		if values == nil {
+			if aws.BoolValue(attribute.Required) {
+				problems = append(problems, fmt.Sprintf("%s is required and cannot be deleted", name))
+			}
+			continue
+		}
+		if !aws.BoolValue(attribute.Mutable) {
+			problems = append(problems, fmt.Sprintf("%s is immutable", name))
+			continue
+		}
+		if problem := checkAttributeValue(attribute, values[name]); problem != "" {
+			problems = append(problems, name+" "+problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		response.Invalid("Invalid attributes: " + strings.Join(problems, "; "))
+	}
+	return
+}
+
+// checkAttributeValue describes how VALUE breaks the attribute's type or
+// constraints, or returns "" when it does not.
+func checkAttributeValue(attribute *cognitoidentityprovider.SchemaAttributeType, value string) string {
+	switch aws.StringValue(attribute.AttributeDataType) {
+	case cognitoidentityprovider.AttributeDataTypeBoolean:
+		if value != "true" && value != "false" {
+			return "must be true or false"
+		}
+	case cognitoidentityprovider.AttributeDataTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "must be a number"
+		}
+		if c := attribute.NumberAttributeConstraints; c != nil {
+			if min, err := strconv.ParseFloat(aws.StringValue(c.MinValue), 64); err == nil && number < min {
+				return "must be at least " + aws.StringValue(c.MinValue)
+			}
+			if max, err := strconv.ParseFloat(aws.StringValue(c.MaxValue), 64); err == nil && number > max {
+				return "must be at most " + aws.StringValue(c.MaxValue)
+			}
+		}
+	case cognitoidentityprovider.AttributeDataTypeString:
+		if c := attribute.StringAttributeConstraints; c != nil {
+			if min, err := strconv.Atoi(aws.StringValue(c.MinLength)); err == nil && len(value) < min {
+				return "must be at least " + aws.StringValue(c.MinLength) + " characters"
+			}
+			if max, err := strconv.Atoi(aws.StringValue(c.MaxLength)); err == nil && len(value) > max {
+				return "must be at most " + aws.StringValue(c.MaxLength) + " characters"
+			}
+		}
+	}
+	return ""
+}
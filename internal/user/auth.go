@@ -0,0 +1,276 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"sync"
+)
+
+// AuthResponse carries the tokens from a successful sign-in, or the challenge
+// Cognito wants answered before it will issue them.
+type AuthResponse struct {
+	apierror.Result
+	AccessToken string `json:"access_token,omitempty"`
+	IDToken string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ChallengeName string `json:"challenge_name,omitempty"`
+	Session string `json:"session,omitempty"`
+	ChallengeParameters map[string]string `json:"challenge_parameters,omitempty"`
+}
+
+type CodeDelivery struct {
+	Destination string `json:"destination"`
+	DeliveryMedium string `json:"delivery_medium"`
+	AttributeName string `json:"attribute_name"`
+}
+
+// CodeDeliveryResponse tells the caller where Cognito sent a confirmation code.
+type CodeDeliveryResponse struct {
+	apierror.Result
+	CodeDelivery *CodeDelivery `json:"code_delivery,omitempty"`
+}
+
+// RefreshRequest exchanges a refresh token for new ID and access tokens.
+// Clients created with a secret need either ClientSecret or UserPoolID, which
+// must be the service's own pool, so the secret can be looked up, plus the
+// Username the tokens were issued to.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	ClientID string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+func (config Config) RefreshToken(request RefreshRequest) (response AuthResponse) {
+	if request.RefreshToken == "" || request.ClientID == "" {
+		response.Invalid("You must supply a refresh token and client ID")
+		return
+	}
+
+	params := &cognitoidentityprovider.InitiateAuthInput{
+		AuthFlow: aws.String("REFRESH_TOKEN_AUTH"),
+		AuthParameters: map[string]*string{
+			"REFRESH_TOKEN": aws.String(request.RefreshToken),
+		},
+		ClientId: aws.String(request.ClientID),
+	}
+
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	if secret != "" {
+		if request.Username == "" {
+			response.Invalid("You must supply the username for a client with a secret")
+			return
+		}
+		params.AuthParameters["SECRET_HASH"] = aws.String(SecretHash(request.Username, request.ClientID, secret))
+	}
+	return config.initiateAuth(params)
+}
+
+// LogoutRequest revokes a refresh token. As with RefreshRequest, clients
+// created with a secret need ClientSecret or UserPoolID. With GlobalSignOut
+// the user's other sessions are ended too, which needs their AccessToken.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	ClientID string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
+	AccessToken string `json:"access_token,omitempty"`
+	GlobalSignOut bool `json:"global_sign_out,omitempty"`
+}
+
+// Logout revokes the refresh token and the tokens issued from it. The global
+// sign-out runs first, as revoking the refresh token would also invalidate
+// the access token it needs.
+func (config Config) Logout(request LogoutRequest) (response StatusResponse) {
+	if request.RefreshToken == "" || request.ClientID == "" {
+		response.Invalid("You must supply a refresh token and client ID")
+		return
+	}
+	if request.GlobalSignOut && request.AccessToken == "" {
+		response.Invalid("You must supply an access token to sign out globally")
+		return
+	}
+
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	if request.GlobalSignOut {
+		if response = config.GlobalSignOut(AccountRequest{AccessToken: request.AccessToken}); response.ResponseCode != 200 {
+			return
+		}
+	}
+
+	_, err = config.CognitoClient.RevokeToken(&cognitoidentityprovider.RevokeTokenInput{
+		ClientId:     aws.String(request.ClientID),
+		ClientSecret: userpool.OptionalString(secret),
+		Token:        aws.String(request.RefreshToken),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func newAuthResponse(result *cognitoidentityprovider.AuthenticationResultType, challengeName *string, session *string, challengeParameters map[string]*string) AuthResponse {
+	response := AuthResponse{
+		Result:        apierror.Result{ResponseCode: 200, Message: "Ok"},
+		ChallengeName: aws.StringValue(challengeName),
+		Session:       aws.StringValue(session),
+	}
+	if result != nil {
+		response.AccessToken = aws.StringValue(result.AccessToken)
+		response.IDToken = aws.StringValue(result.IdToken)
+		response.RefreshToken = aws.StringValue(result.RefreshToken)
+		response.ExpiresIn = aws.Int64Value(result.ExpiresIn)
+		response.TokenType = aws.StringValue(result.TokenType)
+	}
+	if response.ChallengeName != "" && len(challengeParameters) > 0 {
+		response.ChallengeParameters = aws.StringValueMap(challengeParameters)
+	}
+	return response
+}
+
+func newCodeDeliveryResponse(details *cognitoidentityprovider.CodeDeliveryDetailsType) CodeDeliveryResponse {
+	response := CodeDeliveryResponse{
+		Result: apierror.Result{ResponseCode: 200, Message: "Ok"},
+	}
+	if details != nil {
+		response.CodeDelivery = &CodeDelivery{
+			Destination:    aws.StringValue(details.Destination),
+			DeliveryMedium: aws.StringValue(details.DeliveryMedium),
+			AttributeName:  aws.StringValue(details.AttributeName),
+		}
+	}
+	return response
+}
+
+// ChallengeRequest answers a challenge returned by a sign-in, e.g.
+// NEW_PASSWORD_REQUIRED with {"NEW_PASSWORD": "..."} or SOFTWARE_TOKEN_MFA
+// with {"SOFTWARE_TOKEN_MFA_CODE": "123456"}. USERNAME is filled in from
+// Email when not given.
+type ChallengeRequest struct {
+	Email string `json:"email_address"`
+	ClientID string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
+	ChallengeName string `json:"challenge_name"`
+	Session string `json:"session"`
+	Responses map[string]string `json:"challenge_responses"`
+}
+
+// ChallengeAnswers lists the response each challenge cannot be answered without.
+var ChallengeAnswers = map[string]string{
+	"NEW_PASSWORD_REQUIRED": "NEW_PASSWORD",
+	"SMS_MFA":               "SMS_MFA_CODE",
+	"SOFTWARE_TOKEN_MFA":    "SOFTWARE_TOKEN_MFA_CODE",
+	"SELECT_MFA_TYPE":       "ANSWER",
+	"CUSTOM_CHALLENGE":      "ANSWER",
+	"MFA_SETUP":             "",
+}
+
+// RespondToAuthChallenge continues a sign-in that stopped at a challenge and
+// returns either the tokens or the next challenge.
+func (config Config) RespondToAuthChallenge(request ChallengeRequest) (response AuthResponse) {
+	if request.ClientID == "" || request.ChallengeName == "" || request.Session == "" {
+		response.Invalid("You must supply a client ID, challenge name and session")
+		return
+	}
+	answer, ok := ChallengeAnswers[request.ChallengeName]
+	if !ok {
+		response.Invalid(fmt.Sprintf("Unsupported challenge %s", request.ChallengeName))
+		return
+	}
+	if answer != "" && request.Responses[answer] == "" {
+		response.Invalid(fmt.Sprintf("challenge_responses must include %s for %s", answer, request.ChallengeName))
+		return
+	}
+
+	responses := map[string]string{}
+	for k, v := range request.Responses {
+		responses[k] = v
+	}
+	if responses["USERNAME"] == "" {
+		responses["USERNAME"] = request.Email
+	}
+
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	if secret != "" {
+		responses["SECRET_HASH"] = SecretHash(responses["USERNAME"], request.ClientID, secret)
+	}
+
+	output, err := config.CognitoClient.RespondToAuthChallenge(&cognitoidentityprovider.RespondToAuthChallengeInput{
+		ChallengeName:      aws.String(request.ChallengeName),
+		ChallengeResponses: aws.StringMap(responses),
+		ClientId:           aws.String(request.ClientID),
+		Session:            aws.String(request.Session),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	return newAuthResponse(output.AuthenticationResult, output.ChallengeName, output.Session, output.ChallengeParameters)
+}
+
+// SecretHash computes the SECRET_HASH Cognito requires from clients created
+// with a secret: Base64(HMAC_SHA256(secret, username + clientID)).
+func SecretHash(username string, clientID string, clientSecret string) string {
+	mac := hmac.New(sha256.New, []byte(clientSecret))
+	mac.Write([]byte(username + clientID))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// clientSecrets caches the secrets of the service pool's app clients, keyed
+// by client ID, as DescribeUserPoolClient has a low quota and secrets do not
+// change. Clients without a secret are cached as "".
+var clientSecrets sync.Map
+
+// clientSecret returns the supplied secret, or looks it up from the app
+// client when only the user pool ID is known. Secrets are only looked up
+// for the service's own pool, config.UserPoolID, since these routes are
+// public. Clients without a secret yield an empty string.
+func (config Config) clientSecret(secret string, userPoolID string, clientID string) (string, error) {
+	if secret != "" || userPoolID == "" {
+		return secret, nil
+	}
+	if config.UserPoolID == "" || userPoolID != config.UserPoolID {
+		return "", apierror.Invalid("Client secrets can only be looked up for the user pool this service serves; supply client_secret instead")
+	}
+	key := userPoolID + "/" + clientID
+	if cached, ok := clientSecrets.Load(key); ok {
+		return cached.(string), nil
+	}
+	output, err := config.CognitoClient.DescribeUserPoolClient(&cognitoidentityprovider.DescribeUserPoolClientInput{
+		ClientId:   aws.String(clientID),
+		UserPoolId: aws.String(userPoolID),
+	})
+	if err != nil {
+		return "", err
+	}
+	secret = aws.StringValue(output.UserPoolClient.ClientSecret)
+	clientSecrets.Store(key, secret)
+	return secret, nil
+}
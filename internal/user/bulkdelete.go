@@ -0,0 +1,351 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+	"time"
+)
+
+const (
+	BulkActionDelete = "delete"
+	BulkActionDisable = "disable"
+
+	// bulkActionCap is the most email addresses one bulk operation takes,
+	// and the most users a dry run lists in one call.
+	bulkActionCap = listAllCap
+	defaultBulkRate = 10
+	// MaxBulkRate is the highest RatePerSecond a bulk operation runs at.
+	MaxBulkRate = 100
+	// bulkCallSeconds is how long one call may spend calling Cognito at
+	// the requested rate, well within the API Gateway timeout. NextToken
+	// continues with the users it did not reach.
+	bulkCallSeconds = 15
+)
+
+// BulkUserRequest picks the users to delete or disable, either by Filter, a
+// ListUsers filter expression such as email ^= "test+", or by
+// EmailAddresses. DryRun only lists the users that would be affected. At
+// most Concurrency users are handled at once and no more than RatePerSecond,
+// at most MaxBulkRate, calls are made to Cognito each second, lookups
+// included. Each call handles as many users as
+// it can in bulkCallSeconds; NextToken, from the previous response,
+// continues with the rest and must be sent with the request unchanged.
+type BulkUserRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Filter string `json:"filter,omitempty"`
+	EmailAddresses []string `json:"email_addresses,omitempty"`
+	DryRun bool `json:"dry_run,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+	RatePerSecond int `json:"rate_per_second,omitempty"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+// BulkUserResult reports one user. Result is matched in a dry run, then
+// deleted, disabled, not_found or failed.
+type BulkUserResult struct {
+	Username string `json:"username"`
+	Email string `json:"email_address"`
+	UserStatus string `json:"user_status,omitempty"`
+	Enabled *bool `json:"enabled,omitempty"`
+	Result string `json:"result"`
+	ResponseCode int `json:"response_code"`
+	Message string `json:"message,omitempty"`
+}
+
+// BulkUserResponse reports the users one call handled. It is 200 when
+// every one of them was handled, 207 when some were not and 400 when none
+// were. NextToken is empty once no users are left.
+type BulkUserResponse struct {
+	apierror.Result
+	Action string `json:"action"`
+	DryRun bool `json:"dry_run"`
+	Total int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed int `json:"failed"`
+	Users []BulkUserResult `json:"users"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+// bulkCursor is what NextToken encodes: where ListUsers continues for a
+// filter, or how many of the email addresses have been handled.
+type bulkCursor struct {
+	PaginationToken string `json:"pagination_token,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+func (cursor bulkCursor) token() string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func parseBulkCursor(token string) (cursor bulkCursor, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(decoded, &cursor)
+	}
+	if err != nil || (cursor.PaginationToken == "" && cursor.Offset <= 0) {
+		return cursor, fmt.Errorf("The next token is not one a bulk operation returned")
+	}
+	return cursor, nil
+}
+
+// BulkDeleteUsers deletes every matching user with DeleteUser.
+func (config Config) BulkDeleteUsers(request BulkUserRequest) BulkUserResponse {
+	return config.bulkUsers(request, BulkActionDelete)
+}
+
+// BulkDisableUsers disables every matching user, leaving them in the pool.
+func (config Config) BulkDisableUsers(request BulkUserRequest) BulkUserResponse {
+	return config.bulkUsers(request, BulkActionDisable)
+}
+
+func (config Config) bulkUsers(request BulkUserRequest, action string) (response BulkUserResponse) {
+	response.Action = action
+	response.DryRun = request.DryRun
+	if request.UserPoolID == "" || (strings.TrimSpace(request.Filter) == "") == (len(request.EmailAddresses) == 0) {
+		response.Invalid("You must supply a user pool ID and either a filter or email addresses")
+		return
+	}
+	if request.RatePerSecond < 0 {
+		response.Invalid("You must supply a positive rate per second")
+		return
+	}
+
+	var cursor bulkCursor
+	if request.NextToken != "" {
+		var err error
+		if cursor, err = parseBulkCursor(request.NextToken); err != nil {
+			response.Invalid(err.Error())
+			return
+		}
+	}
+
+	rate := request.RatePerSecond
+	if rate == 0 {
+		rate = defaultBulkRate
+	}
+	if rate > MaxBulkRate {
+		rate = MaxBulkRate
+	}
+	// Every call to Cognito, lookups included, waits for the ticker.
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	batch := bulkBatch(request, rate*bulkCallSeconds)
+
+	var next bulkCursor
+	var err error
+	if request.Filter != "" {
+		response.Users, next, err = config.bulkMatchFilter(request, cursor, batch, ticker.C)
+	} else {
+		response.Users, next, err = config.bulkMatchEmails(request, cursor, batch, ticker.C)
+	}
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	if next != (bulkCursor{}) {
+		response.NextToken = next.token()
+	}
+
+	if !request.DryRun {
+		var matched []int
+		for i, result := range response.Users {
+			if result.Result == "matched" {
+				matched = append(matched, i)
+			}
+		}
+		forEachRow(request.Concurrency, matched, func(i int) {
+			result := &response.Users[i]
+			var done StatusResponse
+			for attempt := 0; attempt < importAttempts; attempt++ {
+				if attempt > 0 {
+					time.Sleep(importBackoff << uint(attempt-1))
+				}
+				<-ticker.C
+				if action == BulkActionDelete {
+					done = config.DeleteUser(UserItem{UserPoolID: request.UserPoolID, User: NewUserItem{Email: result.Username}})
+				} else {
+					done = config.disableUser(AdminUserRequest{UserPoolID: request.UserPoolID, Email: result.Username})
+				}
+				if done.Error == nil || done.Error.Code != cognitoidentityprovider.ErrCodeTooManyRequestsException {
+					break
+				}
+			}
+
+			result.ResponseCode = done.ResponseCode
+			if done.ResponseCode == 200 {
+				result.Result = action + "d"
+			} else {
+				result.Result = "failed"
+				result.Message = done.Message
+			}
+		})
+	}
+	response.summarise()
+	return
+}
+
+// bulkBatch is how many users one call takes on in CALLS calls to Cognito.
+// A filter lists up to 60 users a call, while each email address is looked
+// up on its own; outside a dry run each user then takes a call of its own.
+func bulkBatch(request BulkUserRequest, calls int) int {
+	batch := calls
+	switch {
+	case request.Filter != "" && request.DryRun:
+		batch = calls * 60
+	case request.Filter == "" && !request.DryRun:
+		batch = calls / 2
+	}
+	if batch > bulkActionCap {
+		batch = bulkActionCap
+	}
+	if batch < 1 {
+		batch = 1
+	}
+	return batch
+}
+
+// bulkMatchFilter lists up to BATCH of the users the filter matches,
+// continuing from CURSOR, and returns where the next call continues. Each
+// page waits for TICK.
+func (config Config) bulkMatchFilter(request BulkUserRequest, cursor bulkCursor, batch int, tick <-chan time.Time) ([]BulkUserResult, bulkCursor, error) {
+	input := &cognitoidentityprovider.ListUsersInput{
+		Filter:          aws.String(listUserFilter(request.Filter)),
+		PaginationToken: userpool.OptionalString(cursor.PaginationToken),
+		UserPoolId:      aws.String(request.UserPoolID),
+	}
+	results := []BulkUserResult{}
+	for {
+		// A page never runs past the batch, so its token is where the
+		// next call picks up.
+		limit := batch - len(results)
+		if limit > 60 {
+			limit = 60
+		}
+		input.Limit = aws.Int64(int64(limit))
+		var page *cognitoidentityprovider.ListUsersOutput
+		err := retryThrottled(func() (err error) {
+			<-tick
+			page, err = config.CognitoClient.ListUsers(input)
+			return
+		})
+		if err != nil {
+			return nil, bulkCursor{}, err
+		}
+		for _, user := range page.Users {
+			results = append(results, bulkUserResult(user.Username, user.UserStatus, user.Enabled, user.Attributes))
+		}
+		if page.PaginationToken == nil {
+			return results, bulkCursor{}, nil
+		}
+		if len(results) >= batch {
+			return results, bulkCursor{PaginationToken: aws.StringValue(page.PaginationToken)}, nil
+		}
+		input.PaginationToken = page.PaginationToken
+	}
+}
+
+// bulkMatchEmails looks up up to BATCH of the email addresses as usernames,
+// from the offset in CURSOR. Addresses with no user are reported as
+// not_found rather than failing the request. Each lookup waits for TICK.
+func (config Config) bulkMatchEmails(request BulkUserRequest, cursor bulkCursor, batch int, tick <-chan time.Time) ([]BulkUserResult, bulkCursor, error) {
+	if len(request.EmailAddresses) > bulkActionCap {
+		return nil, bulkCursor{}, apierror.Invalid(fmt.Sprintf("You must supply at most %d email addresses", bulkActionCap))
+	}
+	emails := request.EmailAddresses
+	if cursor.Offset > len(emails) {
+		return nil, bulkCursor{}, apierror.Invalid("The next token is not one a bulk operation returned")
+	}
+	var next bulkCursor
+	if end := cursor.Offset + batch; end < len(emails) {
+		next.Offset = end
+		emails = emails[:end]
+	}
+
+	results := []BulkUserResult{}
+	seen := map[string]bool{}
+	for _, email := range request.EmailAddresses[:cursor.Offset] {
+		seen[email] = true
+	}
+	for _, email := range emails[cursor.Offset:] {
+		if email == "" || seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		var output *cognitoidentityprovider.AdminGetUserOutput
+		err := retryThrottled(func() (err error) {
+			<-tick
+			output, err = config.CognitoClient.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+				UserPoolId: aws.String(request.UserPoolID),
+				Username:   aws.String(email),
+			})
+			return
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cognitoidentityprovider.ErrCodeUserNotFoundException {
+			results = append(results, BulkUserResult{
+				Username:     email,
+				Email:        email,
+				Result:       "not_found",
+				ResponseCode: 404,
+				Message:      "User does not exist",
+			})
+			continue
+		}
+		if err != nil {
+			return nil, bulkCursor{}, err
+		}
+		results = append(results, bulkUserResult(output.Username, output.UserStatus, output.Enabled, output.UserAttributes))
+	}
+	return results, next, nil
+}
+
+func bulkUserResult(username *string, status *string, enabled *bool, attributes []*cognitoidentityprovider.AttributeType) BulkUserResult {
+	result := BulkUserResult{
+		Username:   aws.StringValue(username),
+		UserStatus: aws.StringValue(status),
+		Enabled:    enabled,
+		Result:     "matched",
+	}
+	for _, a := range attributes {
+		if aws.StringValue(a.Name) == "email" {
+			result.Email = aws.StringValue(a.Value)
+		}
+	}
+	return result
+}
+
+func (response *BulkUserResponse) summarise() {
+	response.Total = len(response.Users)
+	for _, user := range response.Users {
+		if user.Result == "not_found" || user.Result == "failed" {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	switch {
+	case response.Total == 0:
+		response.ResponseCode = 200
+		response.Message = "No users matched"
+	case response.Failed == 0:
+		response.ResponseCode = 200
+		response.Message = "Ok"
+	case response.Succeeded == 0:
+		response.Fail(apierror.New(400, apierror.CodeBulkOperationFailed, fmt.Sprintf("All %d users failed", response.Failed)))
+	default:
+		response.ResponseCode = 207
+		response.Message = fmt.Sprintf("%d of %d users failed", response.Failed, response.Total)
+	}
+	if response.DryRun && response.Failed == 0 && response.Total > 0 {
+		response.Message = fmt.Sprintf("Ok, %d users would be %sd", response.Total, response.Action)
+	}
+}
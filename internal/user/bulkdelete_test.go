@@ -0,0 +1,59 @@
+package user_test
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/user"
+	"testing"
+)
+
+func TestBulkDeleteUsersContinuesByEmail(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	addUsers(t, config, poolID, 1)
+
+	// At 2 a second one call looks up and deletes 15 addresses, only one of
+	// which has a user to delete.
+	emails := []string{"user000@example.com"}
+	for i := 1; i < 17; i++ {
+		emails = append(emails, fmt.Sprintf("missing%02d@example.com", i))
+	}
+	request := user.BulkUserRequest{UserPoolID: poolID, EmailAddresses: emails, RatePerSecond: 2}
+	first := config.BulkDeleteUsers(request)
+	checkResult(t, first, 207, "")
+	if first.Total != 15 || first.Succeeded != 1 || first.NextToken == "" {
+		t.Fatalf("first call handled %d users, deleting %d, with next token %q; want 15, 1 and a token", first.Total, first.Succeeded, first.NextToken)
+	}
+
+	request.NextToken = first.NextToken
+	second := config.BulkDeleteUsers(request)
+	checkResult(t, second, 400, "BulkOperationFailed")
+	if second.Total != 2 || second.NextToken != "" {
+		t.Fatalf("second call handled %d users with next token %q, want the last 2 and no token", second.Total, second.NextToken)
+	}
+}
+
+func TestBulkDisableUsersDryRunPages(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	addUsers(t, config, poolID, 1001)
+
+	request := user.BulkUserRequest{UserPoolID: poolID, Filter: `email ^= "user"`, DryRun: true}
+	first := config.BulkDisableUsers(request)
+	checkResult(t, first, 200, "")
+	if first.Total != 1000 || first.NextToken == "" {
+		t.Fatalf("first call matched %d users with next token %q, want 1000 and a token", first.Total, first.NextToken)
+	}
+
+	request.NextToken = first.NextToken
+	second := config.BulkDisableUsers(request)
+	checkResult(t, second, 200, "")
+	if second.Total != 1 || second.NextToken != "" {
+		t.Fatalf("second call matched %d users with next token %q, want the last one and no token", second.Total, second.NextToken)
+	}
+	for _, matched := range first.Users {
+		if matched.Username == second.Users[0].Username {
+			t.Fatalf("second call matched %s again", matched.Username)
+		}
+	}
+
+	request.NextToken = "not-a-token"
+	checkResult(t, config.BulkDisableUsers(request), 400, "InvalidRequest")
+}
@@ -0,0 +1,484 @@
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ImportFormatCSV = "csv"
+	ImportFormatNDJSON = "ndjson"
+
+	defaultImportConcurrency = 4
+	maxImportConcurrency = 16
+	importAttempts = 5
+)
+
+// importBackoff is the wait before the first retry of a throttled row; it
+// doubles on each further attempt.
+var importBackoff = 250 * time.Millisecond
+
+// importUploadClient uploads the CSV of a native import job to its
+// pre-signed URL.
+var importUploadClient = &http.Client{Timeout: time.Minute}
+
+// ImportRequest creates every user in Data, one per row. CSV needs a header
+// naming its columns out of email_address, name, password, phone_number and
+// phone_number_verified;
+// NDJSON has one NewUserItem object per line. Mode and Invite apply to every
+// row as they do for AddUser. With DryRun the rows are only validated.
+// NativeJob hands the rows to a Cognito user import job instead, which
+// creates the users without passwords, in RESET_REQUIRED.
+type ImportRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Format string `json:"format"`
+	Data string `json:"data"`
+	Mode string `json:"mode,omitempty"`
+	Invite InviteOptions `json:"invite"`
+	DryRun bool `json:"dry_run,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+	NativeJob *NativeImportJob `json:"native_job,omitempty"`
+}
+
+// NativeImportJob names the Cognito import job and the role it logs to
+// CloudWatch with.
+type NativeImportJob struct {
+	JobName string `json:"job_name"`
+	CloudWatchLogsRoleArn string `json:"cloudwatch_logs_role_arn"`
+}
+
+// ImportRowResult reports one row; Row counts from 1, not including a CSV
+// header. Status is valid, created, invited, queued, restored, invalid or
+// failed.
+type ImportRowResult struct {
+	Row int `json:"row"`
+	Email string `json:"email_address"`
+	Status string `json:"status"`
+	ResponseCode int `json:"response_code"`
+	Message string `json:"message,omitempty"`
+}
+
+type ImportJob struct {
+	JobID string `json:"job_id"`
+	JobName string `json:"job_name"`
+	Status string `json:"status"`
+}
+
+// ImportResponse is 200 when every row succeeded, 207 when some failed and
+// 400 when none did.
+type ImportResponse struct {
+	apierror.Result
+	DryRun bool `json:"dry_run"`
+	Total int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed int `json:"failed"`
+	Rows []ImportRowResult `json:"rows"`
+	Job *ImportJob `json:"job,omitempty"`
+}
+
+func (config Config) ImportUsers(request ImportRequest) (response ImportResponse) {
+	response.DryRun = request.DryRun
+	if request.UserPoolID == "" || request.Data == "" {
+		response.Invalid("You must supply a user pool ID and data")
+		return
+	}
+	if request.Mode != "" && request.Mode != CreateModePassword && request.Mode != CreateModeInvite {
+		response.Invalid(fmt.Sprintf("Unknown mode %s, must be %s or %s", request.Mode, CreateModePassword, CreateModeInvite))
+		return
+	}
+	if job := request.NativeJob; job != nil && (job.JobName == "" || job.CloudWatchLogsRoleArn == "") {
+		response.Invalid("You must supply a job name and CloudWatch Logs role ARN for a native import job")
+		return
+	}
+
+	users, err := ParseImport(request.Format, strings.NewReader(request.Data))
+	if err != nil {
+		response.Invalid(err.Error())
+		return
+	}
+
+	needPassword := request.NativeJob == nil && (request.Mode == "" || request.Mode == CreateModePassword)
+	var policy *cognitoidentityprovider.PasswordPolicyType
+	if needPassword {
+		policy, err = userpool.Config{CognitoClient: config.CognitoClient}.PasswordPolicy(request.UserPoolID)
+		if err != nil {
+			response.Fail(err)
+			return
+		}
+	}
+	response.Rows = validateImport(users, needPassword, policy)
+	var valid []int
+	for i, row := range response.Rows {
+		if row.Status == "valid" {
+			valid = append(valid, i)
+		}
+	}
+
+	switch {
+	case request.DryRun:
+	case request.NativeJob != nil:
+		response.Job = config.runImportJob(request, users, valid, response.Rows)
+	default:
+		config.createImported(request, users, valid, response.Rows)
+	}
+	response.summarise()
+	return
+}
+
+// ParseImport reads users from CSV or NDJSON, failing on the first row that
+// cannot be parsed at all. Rows with missing or invalid values are left for
+// validation.
+func ParseImport(format string, data io.Reader) ([]NewUserItem, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseImportCSV(data)
+	case ImportFormatNDJSON:
+		return parseImportNDJSON(data)
+	}
+	return nil, fmt.Errorf("Unknown format %s, must be %s or %s", format, ImportFormatCSV, ImportFormatNDJSON)
+}
+
+func parseImportCSV(data io.Reader) ([]NewUserItem, error) {
+	reader := csv.NewReader(data)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the CSV header: %s", err)
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		switch header[i] {
+		case "email", "email_address", "name", "password", "phone_number", "phone_number_verified":
+		default:
+			return nil, fmt.Errorf("Unknown CSV column %s, must be email_address, name, password, phone_number or phone_number_verified", column)
+		}
+	}
+
+	var users []NewUserItem
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CSV row %d: %s", len(users)+1, err)
+		}
+		var item NewUserItem
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch header[i] {
+			case "email", "email_address":
+				item.Email = value
+			case "name":
+				item.Name = value
+			case "password":
+				item.Password = value
+			case "phone_number":
+				item.PhoneNumber = value
+			case "phone_number_verified":
+				item.PhoneNumberVerified = strings.ToLower(value)
+			}
+		}
+		users = append(users, item)
+	}
+}
+
+func parseImportNDJSON(data io.Reader) ([]NewUserItem, error) {
+	var users []NewUserItem
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var item NewUserItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("Could not parse line %d: %s", line, err)
+		}
+		users = append(users, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Could not read line %d: %s", line+1, err)
+	}
+	return users, nil
+}
+
+// validateImport checks every row before any user is created, including
+// for email addresses repeated in the file and passwords POLICY refuses.
+func validateImport(users []NewUserItem, needPassword bool, policy *cognitoidentityprovider.PasswordPolicyType) []ImportRowResult {
+	results := make([]ImportRowResult, len(users))
+	seen := map[string]int{}
+	for i, item := range users {
+		result := ImportRowResult{Row: i + 1, Email: item.Email, Status: "valid"}
+		var problems []string
+		if item.Email == "" {
+			problems = append(problems, "email address is missing")
+		} else if at := strings.Index(item.Email, "@"); at < 1 || at == len(item.Email)-1 || strings.ContainsAny(item.Email, " ,;") {
+			problems = append(problems, "email address is malformed")
+		} else if first, ok := seen[strings.ToLower(item.Email)]; ok {
+			problems = append(problems, fmt.Sprintf("email address repeats row %d", first))
+		} else {
+			seen[strings.ToLower(item.Email)] = i + 1
+		}
+		if item.Name == "" {
+			problems = append(problems, "name is missing")
+		}
+		if needPassword && item.Password == "" {
+			problems = append(problems, "password is missing")
+		} else if needPassword {
+			for _, fault := range userpool.PasswordFaults(policy, item.Password) {
+				problems = append(problems, "password "+fault.Message)
+			}
+		}
+		if item.PhoneNumber != "" && !strings.HasPrefix(item.PhoneNumber, "+") {
+			problems = append(problems, "phone number must be in E.164 format, e.g. +6512345678")
+		}
+		if v := item.PhoneNumberVerified; v != "" && v != "true" && v != "false" {
+			problems = append(problems, "phone_number_verified must be true or false")
+		}
+		if len(problems) > 0 {
+			result.Status = "invalid"
+			result.ResponseCode = 400
+			result.Message = strings.Join(problems, "; ")
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// createImported adds the valid rows with AddUser. Each Cognito call is
+// retried on its own when throttled, so a password that cannot be set is
+// retried without creating the user again.
+func (config Config) createImported(request ImportRequest, users []NewUserItem, valid []int, results []ImportRowResult) {
+	status := "created"
+	if request.Mode == CreateModeInvite {
+		status = "invited"
+	}
+	forEachRow(request.Concurrency, valid, func(i int) {
+		item := UserItem{UserPoolID: request.UserPoolID, User: users[i], Mode: request.Mode, Invite: request.Invite}
+		if added := config.addUser(item, retryThrottled); added.Error != nil {
+			results[i].Status = "failed"
+			results[i].ResponseCode = added.Error.Status
+			results[i].Message = added.Error.Message
+			return
+		}
+		results[i].Status = status
+		results[i].ResponseCode = 200
+	})
+}
+
+// retryThrottled calls call again, backing off between attempts, for as
+// long as Cognito answers TooManyRequestsException.
+func retryThrottled(call func() error) error {
+	var err error
+	for attempt := 0; attempt < importAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(importBackoff << uint(attempt-1))
+		}
+		err = call()
+		if !throttled(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// throttled reports whether err is Cognito's TooManyRequestsException,
+// either from the SDK or as a response recorded it.
+func throttled(err error) bool {
+	switch e := err.(type) {
+	case awserr.Error:
+		return e.Code() == cognitoidentityprovider.ErrCodeTooManyRequestsException
+	case *apierror.Error:
+		return e.Code == cognitoidentityprovider.ErrCodeTooManyRequestsException
+	}
+	return false
+}
+
+// forEachRow calls work for each of rows, at most concurrency at a time.
+func forEachRow(concurrency int, rows []int, work func(i int)) {
+	if concurrency <= 0 {
+		concurrency = defaultImportConcurrency
+	}
+	if concurrency > maxImportConcurrency {
+		concurrency = maxImportConcurrency
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, i := range rows {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			work(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// runImportJob uploads the valid rows as a CSV in the layout GetCSVHeader
+// gives for the pool, then starts the import job. Rows are only queued: the
+// job reports its own per-user failures to CloudWatch.
+func (config Config) runImportJob(request ImportRequest, users []NewUserItem, valid []int, results []ImportRowResult) *ImportJob {
+	fail := func(err error) *ImportJob {
+		failure := apierror.FromError(err)
+		for _, i := range valid {
+			results[i].Status = "failed"
+			results[i].ResponseCode = failure.Status
+			results[i].Message = failure.Message
+		}
+		return nil
+	}
+
+	header, err := config.CognitoClient.GetCSVHeader(&cognitoidentityprovider.GetCSVHeaderInput{
+		UserPoolId: aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		return fail(err)
+	}
+	var body bytes.Buffer
+	writer := csv.NewWriter(&body)
+	writer.Write(aws.StringValueSlice(header.CSVHeader))
+	for _, i := range valid {
+		writer.Write(importJobRecord(aws.StringValueSlice(header.CSVHeader), users[i]))
+	}
+	writer.Flush()
+
+	created, err := config.CognitoClient.CreateUserImportJob(&cognitoidentityprovider.CreateUserImportJobInput{
+		CloudWatchLogsRoleArn: aws.String(request.NativeJob.CloudWatchLogsRoleArn),
+		JobName:               aws.String(request.NativeJob.JobName),
+		UserPoolId:            aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		return fail(err)
+	}
+	if err := uploadImportCSV(aws.StringValue(created.UserImportJob.PreSignedUrl), body.Bytes()); err != nil {
+		return fail(err)
+	}
+	started, err := config.CognitoClient.StartUserImportJob(&cognitoidentityprovider.StartUserImportJobInput{
+		JobId:      created.UserImportJob.JobId,
+		UserPoolId: aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, i := range valid {
+		results[i].Status = "queued"
+		results[i].ResponseCode = 200
+	}
+	return &ImportJob{
+		JobID:   aws.StringValue(started.UserImportJob.JobId),
+		JobName: aws.StringValue(started.UserImportJob.JobName),
+		Status:  aws.StringValue(started.UserImportJob.Status),
+	}
+}
+
+// importJobRecord fills the columns of HEADER that a NewUserItem has values
+// for. The user's email address is their username and is marked verified,
+// and their phone number only when the row says so, as AddUser does.
+func importJobRecord(header []string, item NewUserItem) []string {
+	record := make([]string, len(header))
+	for i, column := range header {
+		switch column {
+		case "cognito:username", "email":
+			record[i] = item.Email
+		case "name":
+			record[i] = item.Name
+		case "phone_number":
+			record[i] = item.PhoneNumber
+		case "email_verified":
+			record[i] = "true"
+		case "phone_number_verified":
+			record[i] = fmt.Sprint(phoneNumberVerified(item))
+		case "cognito:mfa_enabled":
+			record[i] = "false"
+		}
+	}
+	return record
+}
+
+func uploadImportCSV(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-amz-server-side-encryption", "aws:kms")
+	resp, err := importUploadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Could not upload the import CSV: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Could not upload the import CSV: %s", resp.Status)
+	}
+	return nil
+}
+
+// failedStatus is the status of a bulk operation none of whose rows
+// succeeded, given the rows' own statuses: 400 when any row was refused as
+// a client error, else the first server error, else 429 when the rows were
+// throttled, so callers know whether retrying can help.
+func failedStatus(codes []int) int {
+	status := 0
+	for _, code := range codes {
+		switch {
+		case code >= 400 && code < 500 && code != 429:
+			return 400
+		case code >= 500 && status < 500:
+			status = code
+		case code == 429 && status == 0:
+			status = 429
+		}
+	}
+	if status == 0 {
+		return 400
+	}
+	return status
+}
+
+func (response *ImportResponse) summarise() {
+	response.Total = len(response.Rows)
+	for _, row := range response.Rows {
+		if row.Status == "invalid" || row.Status == "failed" {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	switch {
+	case response.Failed == 0:
+		response.ResponseCode = 200
+		response.Message = "Ok"
+	case response.Succeeded == 0:
+		codes := make([]int, len(response.Rows))
+		for i, row := range response.Rows {
+			codes[i] = row.ResponseCode
+		}
+		response.Fail(apierror.New(failedStatus(codes), apierror.CodeBulkOperationFailed, fmt.Sprintf("All %d rows failed", response.Failed)))
+	default:
+		response.ResponseCode = 207
+		response.Message = fmt.Sprintf("%d of %d rows failed", response.Failed, response.Total)
+	}
+	if response.DryRun && response.Failed == 0 {
+		response.Message = "Ok, no users were created"
+	}
+}
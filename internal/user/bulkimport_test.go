@@ -0,0 +1,235 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// throttling throttles the first call of each user to AdminSetUserPassword,
+// and counts the calls the import makes.
+type throttling struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	mu        sync.Mutex
+	throttled map[string]bool
+	creates   int
+	describes int
+}
+
+func (c *throttling) AdminCreateUser(input *cognitoidentityprovider.AdminCreateUserInput) (*cognitoidentityprovider.AdminCreateUserOutput, error) {
+	c.mu.Lock()
+	c.creates++
+	c.mu.Unlock()
+	return c.CognitoIdentityProviderAPI.AdminCreateUser(input)
+}
+
+func (c *throttling) AdminSetUserPassword(input *cognitoidentityprovider.AdminSetUserPasswordInput) (*cognitoidentityprovider.AdminSetUserPasswordOutput, error) {
+	c.mu.Lock()
+	first := !c.throttled[aws.StringValue(input.Username)]
+	c.throttled[aws.StringValue(input.Username)] = true
+	c.mu.Unlock()
+	if first {
+		return nil, awserr.New(cognitoidentityprovider.ErrCodeTooManyRequestsException, "Rate exceeded", nil)
+	}
+	return c.CognitoIdentityProviderAPI.AdminSetUserPassword(input)
+}
+
+func (c *throttling) DescribeUserPool(input *cognitoidentityprovider.DescribeUserPoolInput) (*cognitoidentityprovider.DescribeUserPoolOutput, error) {
+	c.mu.Lock()
+	c.describes++
+	c.mu.Unlock()
+	return c.CognitoIdentityProviderAPI.DescribeUserPool(input)
+}
+
+func TestImportUsersRetriesOnlyTheThrottledStep(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	cognito := &throttling{CognitoIdentityProviderAPI: config.CognitoClient, throttled: map[string]bool{}}
+	config.CognitoClient = cognito
+
+	response := config.ImportUsers(user.ImportRequest{
+		UserPoolID: poolID,
+		Format:     user.ImportFormatCSV,
+		Data:       "email_address,name,password\njo@example.com,Jo,Passw0rd!\nal@example.com,Al,Passw0rd!\n",
+	})
+	checkResult(t, response, 200, "")
+	if cognito.creates != 2 {
+		t.Errorf("AdminCreateUser called %d times, want once per row", cognito.creates)
+	}
+	for _, email := range []string{"jo@example.com", "al@example.com"} {
+		got, err := cognito.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+			UserPoolId: aws.String(poolID),
+			Username:   aws.String(email),
+		})
+		if err != nil {
+			t.Fatalf("getting %s: %s", email, err)
+		}
+		if status := aws.StringValue(got.UserStatus); status != cognitoidentityprovider.UserStatusTypeConfirmed {
+			t.Errorf("%s is %s, want CONFIRMED", email, status)
+		}
+	}
+}
+
+func TestImportUsersValidatesPasswordsUpFront(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	cognito := &throttling{CognitoIdentityProviderAPI: config.CognitoClient, throttled: map[string]bool{}}
+	config.CognitoClient = cognito
+	data := "email_address,name,password\njo@example.com,Jo,Passw0rd!\nal@example.com,Al,short\nsam@example.com,Sam,password\n"
+
+	dryRun := config.ImportUsers(user.ImportRequest{UserPoolID: poolID, Format: user.ImportFormatCSV, Data: data, DryRun: true})
+	checkResult(t, dryRun, 207, "")
+	if cognito.creates != 0 {
+		t.Fatalf("dry run created %d users", cognito.creates)
+	}
+	if cognito.describes != 1 {
+		t.Errorf("DescribeUserPool called %d times, want once per import", cognito.describes)
+	}
+	wants := []struct {
+		status  string
+		message string
+	}{
+		{"valid", ""},
+		{"invalid", "password must be at least 8 characters long"},
+		{"invalid", "password must contain an uppercase letter; password must contain a number; password must contain a symbol"},
+	}
+	for i, want := range wants {
+		row := dryRun.Rows[i]
+		if row.Status != want.status || !strings.Contains(row.Message, want.message) {
+			t.Errorf("row %d = %s %q, want %s %q", row.Row, row.Status, row.Message, want.status, want.message)
+		}
+	}
+
+	imported := config.ImportUsers(user.ImportRequest{UserPoolID: poolID, Format: user.ImportFormatCSV, Data: data})
+	checkResult(t, imported, 207, "")
+	if imported.Succeeded != 1 || imported.Failed != 2 || imported.Rows[0].Status != "created" {
+		t.Errorf("imported %d, failed %d, first row %s; want only jo@example.com created", imported.Succeeded, imported.Failed, imported.Rows[0].Status)
+	}
+	if cognito.creates != 1 {
+		t.Errorf("AdminCreateUser called %d times, want only for the valid row", cognito.creates)
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "taken@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+
+	tests := []struct {
+		name     string
+		request  user.ImportRequest
+		status   int
+		code     string
+		statuses string
+	}{
+		{"creates CSV rows", user.ImportRequest{Format: user.ImportFormatCSV,
+			Data: "Email, Name, Password, Phone_Number\njo@example.com, Jo, Passw0rd!, +6512345678\n"},
+			200, "", "created"},
+		{"creates NDJSON rows", user.ImportRequest{Format: user.ImportFormatNDJSON,
+			Data: `{"email_address":"al@example.com","name":"Al","password":"Passw0rd!"}` + "\n\n" + `{"email_address":"sam@example.com","name":"Sam","password":"Passw0rd!"}` + "\n"},
+			200, "", "created created"},
+		{"invites without passwords", user.ImportRequest{Format: user.ImportFormatCSV, Mode: user.CreateModeInvite,
+			Data: "email_address,name\nkim@example.com,Kim\n"},
+			200, "", "invited"},
+		{"reports invalid and failed rows", user.ImportRequest{Format: user.ImportFormatCSV,
+			Data: "email_address,name,password\nlee@example.com,Lee,Passw0rd!\nlee@example.com,Lee,Passw0rd!\nnot-an-email,X,Passw0rd!\n,No Email,Passw0rd!\ntaken@example.com,Taken,Passw0rd!\n"},
+			207, "", "created invalid invalid invalid failed"},
+		{"fails when every row does", user.ImportRequest{Format: user.ImportFormatCSV, Data: "email_address,name\nno-password@example.com,Np\n"},
+			400, "BulkOperationFailed", "invalid"},
+		{"rejects unknown column", user.ImportRequest{Format: user.ImportFormatCSV, Data: "email_address,age\njo@example.com,40\n"},
+			400, "InvalidRequest", ""},
+		{"rejects unknown format", user.ImportRequest{Format: "xml", Data: "<users/>"}, 400, "InvalidRequest", ""},
+		{"rejects unparseable NDJSON", user.ImportRequest{Format: user.ImportFormatNDJSON, Data: "{not json}\n"}, 400, "InvalidRequest", ""},
+		{"rejects unknown pool", user.ImportRequest{UserPoolID: "ap-southeast-1_missing", Format: user.ImportFormatCSV,
+			Data: "email_address,name,password\nx@example.com,X,Passw0rd!\n"},
+			404, cognitoidentityprovider.ErrCodeResourceNotFoundException, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.request.UserPoolID == "" {
+				test.request.UserPoolID = poolID
+			}
+			response := config.ImportUsers(test.request)
+			checkResult(t, response, test.status, test.code)
+			var statuses []string
+			for _, row := range response.Rows {
+				statuses = append(statuses, row.Status)
+			}
+			if got := strings.Join(statuses, " "); got != test.statuses {
+				t.Fatalf("row statuses = %q, want %q (%+v)", got, test.statuses, response.Rows)
+			}
+		})
+	}
+
+	listed := config.ListUser(user.ListUserRequest{UserPoolID: poolID, Filter: `email = "kim@example.com"`})
+	if len(listed.UserList) != 1 || listed.UserList[0].Status != cognitoidentityprovider.UserStatusTypeForceChangePassword {
+		t.Errorf("invited user = %+v, want FORCE_CHANGE_PASSWORD", listed.UserList)
+	}
+}
+
+func TestImportUsersNativeJob(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	fake := config.CognitoClient.(*cognitofake.Cognito)
+	uploads := httptest.NewServer(fake.ImportHandler())
+	defer uploads.Close()
+	fake.ImportURL = uploads.URL
+
+	response := config.ImportUsers(user.ImportRequest{
+		UserPoolID: poolID,
+		Format:     user.ImportFormatCSV,
+		Data:       "email_address,name\njo@example.com,Jo\nbad,Bad\n",
+		NativeJob:  &user.NativeImportJob{JobName: "onboarding", CloudWatchLogsRoleArn: "arn:aws:iam::123456789012:role/import"},
+	})
+	checkResult(t, response, 207, "")
+	if response.Job == nil || response.Job.Status != cognitoidentityprovider.UserImportJobStatusTypeSucceeded {
+		t.Fatalf("job = %+v, want a succeeded import job", response.Job)
+	}
+	if response.Rows[0].Status != "queued" || response.Rows[1].Status != "invalid" {
+		t.Errorf("rows = %+v, want the valid row queued and the other invalid", response.Rows)
+	}
+	got, err := fake.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: aws.String(poolID),
+		Username:   aws.String("jo@example.com"),
+	})
+	if err != nil {
+		t.Fatalf("imported user not found: %s", err)
+	}
+	if status := aws.StringValue(got.UserStatus); status != cognitoidentityprovider.UserStatusTypeResetRequired {
+		t.Errorf("imported user is %s, want RESET_REQUIRED", status)
+	}
+}
+
+// failing answers every AdminCreateUser with an internal error.
+type failing struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+}
+
+func (c failing) AdminCreateUser(input *cognitoidentityprovider.AdminCreateUserInput) (*cognitoidentityprovider.AdminCreateUserOutput, error) {
+	return nil, awserr.NewRequestFailure(awserr.New(cognitoidentityprovider.ErrCodeInternalErrorException, "Internal error", nil), 500, "req-1")
+}
+
+func TestImportUsersStatusWhenEveryRowFails(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	config.CognitoClient = failing{config.CognitoClient}
+
+	tests := []struct {
+		name   string
+		data   string
+		status int
+	}{
+		{"answers the server error when Cognito failed", "email_address,name,password\njo@example.com,Jo,Passw0rd!\n", 500},
+		{"answers 400 when any row was invalid", "email_address,name,password\njo@example.com,Jo,Passw0rd!\nbad,Bad,Passw0rd!\n", 400},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.ImportUsers(user.ImportRequest{UserPoolID: poolID, Format: user.ImportFormatCSV, Data: test.data})
+			checkResult(t, response, test.status, "BulkOperationFailed")
+		})
+	}
+}
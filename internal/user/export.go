@@ -0,0 +1,627 @@
+package user
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportPagesPerCall is how many pages of ListUsers one call exports, so
+// that it finishes well within the API Gateway timeout however large the
+// pool; NextToken continues from there.
+const exportPagesPerCall = 5
+
+// exportColumns lead every CSV export. The pool's attributes follow, in the
+// order GetCSVHeader gives them. groups and mfa_methods are separated by
+// spaces, which Cognito group names cannot contain.
+var exportColumns = []string{"username", "status", "enabled", "created_at", "updated_at", "groups", "preferred_mfa", "mfa_methods", "sub"}
+
+// ExportRequest snapshots the users of a pool, or those matching Filter,
+// a ListUsers filter expression. Each call exports up to
+// exportPagesPerCall pages of users; NextToken, from the previous response,
+// continues the export and must be sent with the rest of the request
+// unchanged. Without Store each call returns its users in Data; with it
+// each writes them to the next part object under Key in the service's
+// export bucket, by default under <user pool ID>/<time>.
+type ExportRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Format string `json:"format"`
+	Filter string `json:"filter,omitempty"`
+	Store bool `json:"store,omitempty"`
+	Key string `json:"key,omitempty"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+// ExportedUser is one user of an export, with every attribute Cognito holds
+// for them, sub included.
+type ExportedUser struct {
+	Username string `json:"username"`
+	Status string `json:"status"`
+	Enabled bool `json:"enabled"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Groups []string `json:"groups"`
+	PreferredMFA string `json:"preferred_mfa,omitempty"`
+	MFAMethods []string `json:"mfa_methods,omitempty"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ExportResponse holds one call's share of an export. Each Data, or part
+// object at Location, is a whole CSV or NDJSON file that RestoreUsers can
+// take on its own. NextToken is empty once every user has been exported.
+type ExportResponse struct {
+	apierror.Result
+	Format string `json:"format,omitempty"`
+	Count int `json:"count"`
+	Data string `json:"data,omitempty"`
+	Location string `json:"location,omitempty"`
+	Part int `json:"part,omitempty"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+// exportCursor is what NextToken encodes: where ListUsers continues and,
+// for a bucket export, the prefix its parts go under and the last part
+// written.
+type exportCursor struct {
+	PaginationToken string `json:"pagination_token"`
+	Key string `json:"key,omitempty"`
+	Part int `json:"part,omitempty"`
+}
+
+func (cursor exportCursor) token() string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func parseExportCursor(token string) (cursor exportCursor, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(decoded, &cursor)
+	}
+	if err != nil || cursor.PaginationToken == "" {
+		return cursor, fmt.Errorf("The next token is not one an export returned")
+	}
+	return cursor, nil
+}
+
+// RestoreRequest re-creates the users of an export, read from Data or from
+// Key in the service's export bucket. Passwords cannot be exported, so restored users are in
+// FORCE_CHANGE_PASSWORD: with Invite Cognito emails each a temporary
+// password, otherwise they must be invited later with InviteOptions.Resend.
+// Groups must already exist, and software token MFA must be set up again.
+// Each part of a large export is restored with a call of its own.
+type RestoreRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Format string `json:"format"`
+	Data string `json:"data,omitempty"`
+	Key string `json:"key,omitempty"`
+	Invite bool `json:"invite,omitempty"`
+	DryRun bool `json:"dry_run,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+func (config Config) ExportUsers(request ExportRequest) (response ExportResponse) {
+	if request.UserPoolID == "" {
+		response.Invalid("You must supply a user pool ID")
+		return
+	}
+	if request.Format != ImportFormatCSV && request.Format != ImportFormatNDJSON {
+		response.Invalid(fmt.Sprintf("Unknown format %s, must be %s or %s", request.Format, ImportFormatCSV, ImportFormatNDJSON))
+		return
+	}
+	if !request.Store && request.Key != "" {
+		response.Invalid("A key can only be given when storing the export")
+		return
+	}
+	response.Format = request.Format
+
+	var cursor exportCursor
+	if request.NextToken != "" {
+		var err error
+		if cursor, err = parseExportCursor(request.NextToken); err != nil {
+			response.Invalid(err.Error())
+			return
+		}
+	}
+
+	if !request.Store {
+		var data bytes.Buffer
+		count, next, err := config.exportUsers(request, &data, cursor.PaginationToken)
+		if err != nil {
+			response.Fail(err)
+			return
+		}
+		response.ResponseCode = 200
+		response.Message = "Ok"
+		response.Count = count
+		response.Data = data.String()
+		if next != "" {
+			response.NextToken = exportCursor{PaginationToken: next}.token()
+		}
+		return
+	}
+
+	if err := config.checkObjectStore(); err != nil {
+		response.Fail(err)
+		return
+	}
+	prefix := cursor.Key
+	if prefix == "" {
+		prefix = request.Key
+	}
+	if prefix == "" {
+		prefix = fmt.Sprintf("%s/%s", request.UserPoolID, time.Now().UTC().Format("20060102T150405Z"))
+	}
+	part := cursor.Part + 1
+	key := fmt.Sprintf("%s/part-%05d.%s", strings.TrimSuffix(prefix, "/"), part, request.Format)
+	contentType := "text/csv"
+	if request.Format == ImportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	object := newObjectWriter(config.ObjectStore, config.ExportBucket, key, contentType)
+	count, next, err := config.exportUsers(request, object, cursor.PaginationToken)
+	if err != nil {
+		object.Abort()
+		response.Fail(err)
+		return
+	}
+	if err := object.Close(); err != nil {
+		response.Fail(err)
+		return
+	}
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.Count = count
+	response.Location = objectLocation(config.ExportBucket, key)
+	response.Part = part
+	if next != "" {
+		response.NextToken = exportCursor{PaginationToken: next, Key: prefix, Part: part}.token()
+	}
+	return
+}
+
+// exportUsers writes up to exportPagesPerCall pages of users to w, starting
+// from the ListUsers pagination token FROM, and returns the token to carry
+// on from, which is empty after the last page.
+func (config Config) exportUsers(request ExportRequest, w io.Writer, from string) (int, string, error) {
+	var write func(ExportedUser) error
+	var flush func() error
+	if request.Format == ImportFormatCSV {
+		header, err := config.exportHeader(request.UserPoolID)
+		if err != nil {
+			return 0, "", err
+		}
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		write = func(user ExportedUser) error {
+			return writer.Write(user.csvRecord(header))
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	} else {
+		encoder := json.NewEncoder(w)
+		write = func(user ExportedUser) error {
+			return encoder.Encode(user)
+		}
+		flush = func() error { return nil }
+	}
+
+	input := &cognitoidentityprovider.ListUsersInput{
+		Filter:          userpool.OptionalString(listUserFilter(request.Filter)),
+		Limit:           aws.Int64(60),
+		PaginationToken: userpool.OptionalString(from),
+		UserPoolId:      aws.String(request.UserPoolID),
+	}
+	count := 0
+	for pages := 0; pages < exportPagesPerCall; pages++ {
+		var page *cognitoidentityprovider.ListUsersOutput
+		err := retryThrottled(func() (err error) {
+			page, err = config.CognitoClient.ListUsers(input)
+			return
+		})
+		if err != nil {
+			return count, "", err
+		}
+		exported, err := config.exportPage(request.UserPoolID, page.Users)
+		if err != nil {
+			return count, "", err
+		}
+		for _, user := range exported {
+			if err := write(user); err != nil {
+				return count, "", err
+			}
+			count++
+		}
+		if err := flush(); err != nil {
+			return count, "", err
+		}
+		if page.PaginationToken == nil {
+			return count, "", nil
+		}
+		input.PaginationToken = page.PaginationToken
+	}
+	return count, aws.StringValue(input.PaginationToken), nil
+}
+
+// exportPage looks up the details of a page of users a few at a time, and
+// returns them in the order ListUsers gave.
+func (config Config) exportPage(userPoolID string, users []*cognitoidentityprovider.UserType) ([]ExportedUser, error) {
+	exported := make([]ExportedUser, len(users))
+	errs := make([]error, len(users))
+	rows := make([]int, len(users))
+	for i := range rows {
+		rows[i] = i
+	}
+	forEachRow(defaultImportConcurrency, rows, func(i int) {
+		exported[i], errs[i] = config.exportUser(userPoolID, users[i])
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return exported, nil
+}
+
+// exportHeader is exportColumns followed by the pool's attributes.
+func (config Config) exportHeader(userPoolID string) ([]string, error) {
+	var output *cognitoidentityprovider.GetCSVHeaderOutput
+	err := retryThrottled(func() (err error) {
+		output, err = config.CognitoClient.GetCSVHeader(&cognitoidentityprovider.GetCSVHeaderInput{
+			UserPoolId: aws.String(userPoolID),
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := append([]string{}, exportColumns...)
+	for _, column := range aws.StringValueSlice(output.CSVHeader) {
+		if !strings.HasPrefix(column, "cognito:") && column != "sub" {
+			header = append(header, column)
+		}
+	}
+	return header, nil
+}
+
+// exportUser adds the user's groups and MFA settings, which ListUsers
+// leaves out.
+func (config Config) exportUser(userPoolID string, user *cognitoidentityprovider.UserType) (ExportedUser, error) {
+	exported := ExportedUser{
+		Username:   aws.StringValue(user.Username),
+		Status:     aws.StringValue(user.UserStatus),
+		Enabled:    aws.BoolValue(user.Enabled),
+		CreatedAt:  user.UserCreateDate,
+		UpdatedAt:  user.UserLastModifiedDate,
+		Groups:     []string{},
+		Attributes: map[string]string{},
+	}
+	for _, a := range user.Attributes {
+		exported.Attributes[aws.StringValue(a.Name)] = aws.StringValue(a.Value)
+	}
+
+	var details *cognitoidentityprovider.AdminGetUserOutput
+	err := retryThrottled(func() (err error) {
+		details, err = config.CognitoClient.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+			UserPoolId: aws.String(userPoolID),
+			Username:   user.Username,
+		})
+		return
+	})
+	if err != nil {
+		return exported, err
+	}
+	exported.PreferredMFA = aws.StringValue(details.PreferredMfaSetting)
+	exported.MFAMethods = aws.StringValueSlice(details.UserMFASettingList)
+
+	input := &cognitoidentityprovider.AdminListGroupsForUserInput{
+		Limit:      aws.Int64(60),
+		UserPoolId: aws.String(userPoolID),
+		Username:   user.Username,
+	}
+	for {
+		var groups *cognitoidentityprovider.AdminListGroupsForUserOutput
+		err := retryThrottled(func() (err error) {
+			groups, err = config.CognitoClient.AdminListGroupsForUser(input)
+			return
+		})
+		if err != nil {
+			return exported, err
+		}
+		for _, group := range groups.Groups {
+			exported.Groups = append(exported.Groups, aws.StringValue(group.GroupName))
+		}
+		if groups.NextToken == nil {
+			return exported, nil
+		}
+		input.NextToken = groups.NextToken
+	}
+}
+
+func (user ExportedUser) csvRecord(header []string) []string {
+	record := make([]string, len(header))
+	for i, column := range header {
+		switch column {
+		case "username":
+			record[i] = user.Username
+		case "status":
+			record[i] = user.Status
+		case "enabled":
+			record[i] = strconv.FormatBool(user.Enabled)
+		case "created_at":
+			record[i] = formatExportTime(user.CreatedAt)
+		case "updated_at":
+			record[i] = formatExportTime(user.UpdatedAt)
+		case "groups":
+			record[i] = strings.Join(user.Groups, " ")
+		case "preferred_mfa":
+			record[i] = user.PreferredMFA
+		case "mfa_methods":
+			record[i] = strings.Join(user.MFAMethods, " ")
+		default:
+			record[i] = user.Attributes[column]
+		}
+	}
+	return record
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// RestoreUsers validates every row of the export before re-creating any
+// user, then creates them as ImportUsers does and reports on each row.
+func (config Config) RestoreUsers(request RestoreRequest) (response ImportResponse) {
+	response.DryRun = request.DryRun
+	if request.UserPoolID == "" || (request.Data == "") == (request.Key == "") {
+		response.Invalid("You must supply a user pool ID and either data or the key of an export")
+		return
+	}
+
+	var data io.Reader = strings.NewReader(request.Data)
+	if request.Key != "" {
+		object, err := config.readObject(request.Key)
+		if err != nil {
+			response.Fail(err)
+			return
+		}
+		defer object.Close()
+		data = object
+	}
+	users, err := ParseExport(request.Format, data)
+	if err != nil {
+		response.Invalid(err.Error())
+		return
+	}
+
+	signIn, err := config.usernameAttributes(request.UserPoolID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.Rows = make([]ImportRowResult, len(users))
+	usernames := make([]string, len(users))
+	seen := map[string]int{}
+	var valid []int
+	for i, user := range users {
+		row := ImportRowResult{Row: i + 1, Email: user.Attributes["email"], Status: "valid"}
+		usernames[i] = restoreUsername(user, signIn)
+		if usernames[i] == "" && len(signIn) > 0 {
+			row.Message = strings.Join(signIn, " or ") + " is missing, which the pool signs in with"
+		} else if usernames[i] == "" {
+			row.Message = "username is missing"
+		} else if first, ok := seen[usernames[i]]; ok {
+			row.Message = fmt.Sprintf("username repeats row %d", first)
+		} else {
+			seen[usernames[i]] = i + 1
+			valid = append(valid, i)
+		}
+		if row.Message != "" {
+			row.Status = "invalid"
+			row.ResponseCode = 400
+		}
+		response.Rows[i] = row
+	}
+
+	if !request.DryRun {
+		forEachRow(request.Concurrency, valid, func(i int) {
+			if err := config.restoreUser(request, usernames[i], users[i]); err != nil {
+				failure := apierror.FromError(err)
+				response.Rows[i].Status = "failed"
+				response.Rows[i].ResponseCode = failure.Status
+				response.Rows[i].Message = failure.Message
+				return
+			}
+			response.Rows[i].Status = "restored"
+			response.Rows[i].ResponseCode = 200
+		})
+	}
+	response.summarise()
+	return
+}
+
+// usernameAttributes lists the attributes the pool's users sign in with in
+// place of a username, if any.
+func (config Config) usernameAttributes(userPoolID string) ([]string, error) {
+	var output *cognitoidentityprovider.DescribeUserPoolOutput
+	err := retryThrottled(func() (err error) {
+		output, err = config.CognitoClient.DescribeUserPool(&cognitoidentityprovider.DescribeUserPoolInput{
+			UserPoolId: aws.String(userPoolID),
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aws.StringValueSlice(output.UserPool.UsernameAttributes), nil
+}
+
+// restoreUsername is the name to re-create USER under. A pool that signs in
+// with email addresses or phone numbers names its users by their sub, and
+// refuses a sub as the name of a new user, so there the user is created
+// under the first of SIGNIN they have a value for.
+func restoreUsername(user ExportedUser, signIn []string) string {
+	if len(signIn) == 0 {
+		return user.Username
+	}
+	for _, attribute := range signIn {
+		if value := user.Attributes[attribute]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// restoreUser creates the user as USERNAME with every attribute but sub,
+// which Cognito assigns afresh, then disables them and adds them to their
+// groups as the export records.
+func (config Config) restoreUser(request RestoreRequest, username string, user ExportedUser) error {
+	input := &cognitoidentityprovider.AdminCreateUserInput{
+		DesiredDeliveryMediums: aws.StringSlice([]string{cognitoidentityprovider.DeliveryMediumTypeEmail}),
+		UserPoolId:             aws.String(request.UserPoolID),
+		Username:               aws.String(username),
+	}
+	if !request.Invite {
+		input.MessageAction = aws.String(cognitoidentityprovider.MessageActionTypeSuppress)
+	}
+	for name, value := range user.Attributes {
+		if name != "sub" && value != "" {
+			input.UserAttributes = append(input.UserAttributes, &cognitoidentityprovider.AttributeType{
+				Name:  aws.String(name),
+				Value: aws.String(value),
+			})
+		}
+	}
+	err := retryThrottled(func() error {
+		_, err := config.CognitoClient.AdminCreateUser(input)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !user.Enabled {
+		err := retryThrottled(func() error {
+			_, err := config.CognitoClient.AdminDisableUser(&cognitoidentityprovider.AdminDisableUserInput{
+				UserPoolId: aws.String(request.UserPoolID),
+				Username:   aws.String(username),
+			})
+			return err
+		})
+		if err != nil {
+			failure := apierror.FromError(err)
+			failure.Message = "Created, but got error disabling the user: " + failure.Message
+			return failure
+		}
+	}
+	for _, group := range user.Groups {
+		err := retryThrottled(func() error {
+			_, err := config.CognitoClient.AdminAddUserToGroup(&cognitoidentityprovider.AdminAddUserToGroupInput{
+				GroupName:  aws.String(group),
+				UserPoolId: aws.String(request.UserPoolID),
+				Username:   aws.String(username),
+			})
+			return err
+		})
+		if err != nil {
+			failure := apierror.FromError(err)
+			failure.Message = fmt.Sprintf("Created, but got error adding the user to group %s: %s", group, failure.Message)
+			return failure
+		}
+	}
+	return nil
+}
+
+// ParseExport reads back the users ExportUsers wrote. CSV columns other
+// than exportColumns are taken as attributes. Users are enabled unless the
+// export says otherwise.
+func ParseExport(format string, data io.Reader) ([]ExportedUser, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseExportCSV(data)
+	case ImportFormatNDJSON:
+		var users []ExportedUser
+		decoder := json.NewDecoder(data)
+		for {
+			user := ExportedUser{Enabled: true}
+			err := decoder.Decode(&user)
+			if err == io.EOF {
+				return users, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("Could not parse user %d: %s", len(users)+1, err)
+			}
+			users = append(users, user)
+		}
+	}
+	return nil, fmt.Errorf("Unknown format %s, must be %s or %s", format, ImportFormatCSV, ImportFormatNDJSON)
+}
+
+func parseExportCSV(data io.Reader) ([]ExportedUser, error) {
+	reader := csv.NewReader(data)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the CSV header: %s", err)
+	}
+
+	var users []ExportedUser
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CSV row %d: %s", len(users)+1, err)
+		}
+		user := ExportedUser{Enabled: true, Attributes: map[string]string{}}
+		for i, value := range record {
+			switch header[i] {
+			case "username":
+				user.Username = value
+			case "status":
+				user.Status = value
+			case "enabled":
+				user.Enabled = value != "false"
+			case "created_at":
+				user.CreatedAt = parseExportTime(value)
+			case "updated_at":
+				user.UpdatedAt = parseExportTime(value)
+			case "groups":
+				user.Groups = strings.Fields(value)
+			case "preferred_mfa":
+				user.PreferredMFA = value
+			case "mfa_methods":
+				user.MFAMethods = strings.Fields(value)
+			default:
+				if value != "" {
+					user.Attributes[header[i]] = value
+				}
+			}
+		}
+		users = append(users, user)
+	}
+}
+
+func parseExportTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
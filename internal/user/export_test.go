@@ -0,0 +1,148 @@
+package user_test
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+	"testing"
+)
+
+// addUsers creates COUNT users, user000@example.com and on.
+func addUsers(t *testing.T, config user.Config, poolID string, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if response := config.AddUser(newUser(poolID, fmt.Sprintf("user%03d@example.com", i))); response.ResponseCode != 200 {
+			t.Fatalf("seeding user %d: %s", i, response.Message)
+		}
+	}
+}
+
+func TestExportUsersPages(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	addUsers(t, config, poolID, 301)
+
+	request := user.ExportRequest{UserPoolID: poolID, Format: user.ImportFormatCSV}
+	first := config.ExportUsers(request)
+	checkResult(t, first, 200, "")
+	if first.Count != 300 || first.NextToken == "" {
+		t.Fatalf("first call exported %d users with next token %q, want 300 and a token", first.Count, first.NextToken)
+	}
+
+	request.NextToken = first.NextToken
+	second := config.ExportUsers(request)
+	checkResult(t, second, 200, "")
+	if second.Count != 1 || second.NextToken != "" {
+		t.Fatalf("second call exported %d users with next token %q, want the last one and no token", second.Count, second.NextToken)
+	}
+	for _, response := range []user.ExportResponse{first, second} {
+		if !strings.HasPrefix(response.Data, "username,") {
+			t.Errorf("part does not start with the CSV header: %.40q", response.Data)
+		}
+	}
+
+	request.NextToken = "not-a-token"
+	checkResult(t, config.ExportUsers(request), 400, "InvalidRequest")
+}
+
+func TestExportUsersToBucketInParts(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	config.ObjectStore = cognitofake.NewS3()
+	config.ExportBucket = "backups"
+	addUsers(t, config, poolID, 301)
+
+	request := user.ExportRequest{UserPoolID: poolID, Format: user.ImportFormatNDJSON, Store: true, Key: "nightly"}
+	var locations []string
+	total := 0
+	for {
+		response := config.ExportUsers(request)
+		checkResult(t, response, 200, "")
+		locations = append(locations, response.Location)
+		total += response.Count
+		if response.NextToken == "" {
+			break
+		}
+		request.NextToken = response.NextToken
+	}
+	want := []string{"s3://backups/nightly/part-00001.ndjson", "s3://backups/nightly/part-00002.ndjson"}
+	if strings.Join(locations, " ") != strings.Join(want, " ") || total != 301 {
+		t.Fatalf("exported %d users to %v, want 301 to %v", total, locations, want)
+	}
+
+	restore := user.RestoreRequest{UserPoolID: poolID, Format: user.ImportFormatNDJSON, Key: "nightly/part-00002.ndjson", DryRun: true}
+	restored := config.RestoreUsers(restore)
+	checkResult(t, restored, 200, "")
+	if restored.Total != 1 {
+		t.Errorf("the last part holds %d users, want 1", restored.Total)
+	}
+}
+
+func TestExportUsersWithoutBucket(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	config.ObjectStore = cognitofake.NewS3()
+
+	checkResult(t, config.ExportUsers(user.ExportRequest{UserPoolID: poolID, Format: user.ImportFormatCSV, Store: true}), 500, "InternalError")
+	checkResult(t, config.RestoreUsers(user.RestoreRequest{UserPoolID: poolID, Format: user.ImportFormatCSV, Key: "nightly/part-00001.csv"}), 500, "InternalError")
+}
+
+func TestRestoreUsersByEmailInEmailPool(t *testing.T) {
+	config, _, _ := newConfig(t)
+	pool, err := config.CognitoClient.CreateUserPool(&cognitoidentityprovider.CreateUserPoolInput{
+		PoolName:           aws.String("email"),
+		UsernameAttributes: aws.StringSlice([]string{cognitoidentityprovider.UsernameAttributeTypeEmail}),
+	})
+	if err != nil {
+		t.Fatalf("creating pool: %s", err)
+	}
+	poolID := aws.StringValue(pool.UserPool.Id)
+
+	// An email pool names its users by their sub.
+	data := `{"username":"0b5f1c2e-7d3a-4c1b-9e8f-2a6d4b3c1e0f","enabled":true,"groups":[],"attributes":{"email":"jo@example.com","name":"Jo","sub":"0b5f1c2e-7d3a-4c1b-9e8f-2a6d4b3c1e0f"}}
+{"username":"6e1d2c3b-4a5f-4e6d-8c7b-9a0f1e2d3c4b","enabled":true,"groups":[],"attributes":{"name":"No Email","sub":"6e1d2c3b-4a5f-4e6d-8c7b-9a0f1e2d3c4b"}}
+`
+	response := config.RestoreUsers(user.RestoreRequest{UserPoolID: poolID, Format: user.ImportFormatNDJSON, Data: data})
+	checkResult(t, response, 207, "")
+	if row := response.Rows[0]; row.Status != "restored" {
+		t.Errorf("row 1 = %s %q, want restored", row.Status, row.Message)
+	}
+	if row := response.Rows[1]; row.Status != "invalid" || !strings.Contains(row.Message, "email is missing") {
+		t.Errorf("row 2 = %s %q, want invalid for lacking an email address", row.Status, row.Message)
+	}
+	_, err = config.CognitoClient.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: aws.String(poolID),
+		Username:   aws.String("jo@example.com"),
+	})
+	if err != nil {
+		t.Errorf("restored user cannot be found by email address: %s", err)
+	}
+}
+
+func TestParseExportEnablesByDefault(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		enabled []bool
+	}{
+		{"NDJSON", user.ImportFormatNDJSON, `{"username":"jo"}` + "\n" + `{"username":"al","enabled":false}` + "\n", []bool{true, false}},
+		{"CSV", user.ImportFormatCSV, "username,enabled\njo,\nal,false\n", []bool{true, false}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users, err := user.ParseExport(test.format, strings.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("parsing: %s", err)
+			}
+			if len(users) != len(test.enabled) {
+				t.Fatalf("parsed %d users, want %d", len(users), len(test.enabled))
+			}
+			for i, enabled := range test.enabled {
+				if users[i].Enabled != enabled {
+					t.Errorf("%s enabled = %v, want %v", users[i].Username, users[i].Enabled, enabled)
+				}
+			}
+		})
+	}
+}
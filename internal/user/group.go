@@ -0,0 +1,259 @@
+package user
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"time"
+)
+
+// GroupRequest covers every group operation. Email names the member for the
+// membership operations; Limit and NextToken page through the list ones.
+type GroupRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	GroupName string `json:"group_name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Precedence *int64 `json:"precedence,omitempty"`
+	RoleArn string `json:"role_arn,omitempty"`
+	Email string `json:"email_address,omitempty"`
+	Limit int64 `json:"limit,omitempty"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+type GroupItem struct {
+	GroupName string `json:"group_name"`
+	Description string `json:"description,omitempty"`
+	Precedence *int64 `json:"precedence,omitempty"`
+	RoleArn string `json:"role_arn,omitempty"`
+	CreatedDate *time.Time `json:"created_date,omitempty"`
+	LastModifiedDate *time.Time `json:"last_modified_date,omitempty"`
+}
+
+type GroupResponse struct {
+	apierror.Result
+	Groups []GroupItem `json:"groups"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+func (config Config) CreateGroup(request GroupRequest) (response GroupResponse) {
+	if request.UserPoolID == "" || request.GroupName == "" {
+		response.Invalid("You must supply a user pool ID and group name")
+		return
+	}
+
+	output, err := config.CognitoClient.CreateGroup(&cognitoidentityprovider.CreateGroupInput{
+		Description: userpool.OptionalString(request.Description),
+		GroupName:   aws.String(request.GroupName),
+		Precedence:  request.Precedence,
+		RoleArn:     userpool.OptionalString(request.RoleArn),
+		UserPoolId:  aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.Groups = []GroupItem{newGroupItem(output.Group)}
+	return
+}
+
+// UpdateGroup changes only the fields given in the request. Cognito clears
+// any field missing from UpdateGroup, so the current group is fetched first
+// and used for everything not supplied.
+func (config Config) UpdateGroup(request GroupRequest) (response GroupResponse) {
+	if request.UserPoolID == "" || request.GroupName == "" {
+		response.Invalid("You must supply a user pool ID and group name")
+		return
+	}
+
+	current, err := config.CognitoClient.GetGroup(&cognitoidentityprovider.GetGroupInput{
+		GroupName:  aws.String(request.GroupName),
+		UserPoolId: aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	precedence := request.Precedence
+	if precedence == nil {
+		precedence = current.Group.Precedence
+	}
+
+	output, err := config.CognitoClient.UpdateGroup(&cognitoidentityprovider.UpdateGroupInput{
+		Description: userpool.OptionalString(userpool.CheckerString(request.Description, aws.StringValue(current.Group.Description))),
+		GroupName:   aws.String(request.GroupName),
+		Precedence:  precedence,
+		RoleArn:     userpool.OptionalString(userpool.CheckerString(request.RoleArn, aws.StringValue(current.Group.RoleArn))),
+		UserPoolId:  aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.Groups = []GroupItem{newGroupItem(output.Group)}
+	return
+}
+
+func (config Config) DeleteGroup(request GroupRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.GroupName == "" {
+		response.Invalid("You must supply a user pool ID and group name")
+		return
+	}
+
+	_, err := config.CognitoClient.DeleteGroup(&cognitoidentityprovider.DeleteGroupInput{
+		GroupName:  aws.String(request.GroupName),
+		UserPoolId: aws.String(request.UserPoolID),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func (config Config) ListGroups(request GroupRequest) (response GroupResponse) {
+	if request.UserPoolID == "" {
+		response.Invalid("You must supply a user pool ID")
+		return
+	}
+
+	input := &cognitoidentityprovider.ListGroupsInput{
+		NextToken:  userpool.OptionalString(request.NextToken),
+		UserPoolId: aws.String(request.UserPoolID),
+	}
+	if request.Limit > 0 {
+		input.Limit = aws.Int64(request.Limit)
+	}
+	output, err := config.CognitoClient.ListGroups(input)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	return newGroupResponse(output.Groups, output.NextToken)
+}
+
+func (config Config) AddUserToGroup(request GroupRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.GroupName == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID, group name and email address")
+		return
+	}
+
+	_, err := config.CognitoClient.AdminAddUserToGroup(&cognitoidentityprovider.AdminAddUserToGroupInput{
+		GroupName:  aws.String(request.GroupName),
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func (config Config) RemoveUserFromGroup(request GroupRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.GroupName == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID, group name and email address")
+		return
+	}
+
+	_, err := config.CognitoClient.AdminRemoveUserFromGroup(&cognitoidentityprovider.AdminRemoveUserFromGroupInput{
+		GroupName:  aws.String(request.GroupName),
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func (config Config) ListGroupsForUser(request GroupRequest) (response GroupResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+
+	input := &cognitoidentityprovider.AdminListGroupsForUserInput{
+		NextToken:  userpool.OptionalString(request.NextToken),
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	}
+	if request.Limit > 0 {
+		input.Limit = aws.Int64(request.Limit)
+	}
+	output, err := config.CognitoClient.AdminListGroupsForUser(input)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	return newGroupResponse(output.Groups, output.NextToken)
+}
+
+func (config Config) ListUsersInGroup(request GroupRequest) (response UserResponse) {
+	if request.UserPoolID == "" || request.GroupName == "" {
+		response.Invalid("You must supply a user pool ID and group name")
+		return
+	}
+
+	input := &cognitoidentityprovider.ListUsersInGroupInput{
+		GroupName:  aws.String(request.GroupName),
+		NextToken:  userpool.OptionalString(request.NextToken),
+		UserPoolId: aws.String(request.UserPoolID),
+	}
+	if request.Limit > 0 {
+		input.Limit = aws.Int64(request.Limit)
+	}
+	output, err := config.CognitoClient.ListUsersInGroup(input)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.UserList = []NewUserItem{}
+	for _, u := range output.Users {
+		response.UserList = append(response.UserList, newUserItem(u))
+	}
+	response.NextToken = aws.StringValue(output.NextToken)
+	return
+}
+
+func newGroupResponse(groups []*cognitoidentityprovider.GroupType, nextToken *string) GroupResponse {
+	response := GroupResponse{
+		Result:    apierror.Result{ResponseCode: 200, Message: "Ok"},
+		Groups:    []GroupItem{},
+		NextToken: aws.StringValue(nextToken),
+	}
+	for _, group := range groups {
+		response.Groups = append(response.Groups, newGroupItem(group))
+	}
+	return response
+}
+
+func newGroupItem(group *cognitoidentityprovider.GroupType) GroupItem {
+	return GroupItem{
+		GroupName:        aws.StringValue(group.GroupName),
+		Description:      aws.StringValue(group.Description),
+		Precedence:       group.Precedence,
+		RoleArn:          aws.StringValue(group.RoleArn),
+		CreatedDate:      group.CreationDate,
+		LastModifiedDate: group.LastModifiedDate,
+	}
+}
@@ -0,0 +1,107 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+	"testing"
+)
+
+func TestUpdateGroup(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	role := "arn:aws:iam::123456789012:role/support"
+	created := config.CreateGroup(user.GroupRequest{
+		UserPoolID:  poolID,
+		GroupName:   "support",
+		Description: "Support staff",
+		Precedence:  aws.Int64(5),
+		RoleArn:     role,
+	})
+	checkResult(t, created, 200, "")
+
+	tests := []struct {
+		name    string
+		request user.GroupRequest
+		status  int
+		code    string
+		want    user.GroupItem
+	}{
+		{"changes description only", user.GroupRequest{UserPoolID: poolID, GroupName: "support", Description: "Tier 1 support"}, 200, "",
+			user.GroupItem{GroupName: "support", Description: "Tier 1 support", Precedence: aws.Int64(5), RoleArn: role}},
+		{"changes precedence only", user.GroupRequest{UserPoolID: poolID, GroupName: "support", Precedence: aws.Int64(1)}, 200, "",
+			user.GroupItem{GroupName: "support", Description: "Tier 1 support", Precedence: aws.Int64(1), RoleArn: role}},
+		{"rejects unknown group", user.GroupRequest{UserPoolID: poolID, GroupName: "missing", Description: "x"}, 404, cognitoidentityprovider.ErrCodeResourceNotFoundException, user.GroupItem{}},
+		{"requires group name", user.GroupRequest{UserPoolID: poolID}, 400, "InvalidRequest", user.GroupItem{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.UpdateGroup(test.request)
+			checkResult(t, response, test.status, test.code)
+			if test.code != "" {
+				return
+			}
+			got := response.Groups[0]
+			if got.GroupName != test.want.GroupName || got.Description != test.want.Description ||
+				aws.Int64Value(got.Precedence) != aws.Int64Value(test.want.Precedence) || got.RoleArn != test.want.RoleArn {
+				t.Fatalf("group = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGroupMembership(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "jo@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+	group := func(name string) user.GroupRequest {
+		return user.GroupRequest{UserPoolID: poolID, GroupName: name}
+	}
+	member := func(name string, email string) user.GroupRequest {
+		return user.GroupRequest{UserPoolID: poolID, GroupName: name, Email: email}
+	}
+	names := func(groups []user.GroupItem) string {
+		var names []string
+		for _, g := range groups {
+			names = append(names, g.GroupName)
+		}
+		return strings.Join(names, " ")
+	}
+
+	checkResult(t, config.CreateGroup(group("admin")), 200, "")
+	checkResult(t, config.CreateGroup(group("support")), 200, "")
+	checkResult(t, config.CreateGroup(group("admin")), 409, cognitoidentityprovider.ErrCodeGroupExistsException)
+	checkResult(t, config.CreateGroup(user.GroupRequest{UserPoolID: poolID}), 400, "InvalidRequest")
+
+	listed := config.ListGroups(user.GroupRequest{UserPoolID: poolID})
+	checkResult(t, listed, 200, "")
+	if got := names(listed.Groups); got != "admin support" {
+		t.Errorf("groups = %s, want admin support", got)
+	}
+
+	checkResult(t, config.AddUserToGroup(member("admin", "jo@example.com")), 200, "")
+	checkResult(t, config.AddUserToGroup(member("missing", "jo@example.com")), 404, cognitoidentityprovider.ErrCodeResourceNotFoundException)
+	checkResult(t, config.AddUserToGroup(member("admin", "nobody@example.com")), 404, cognitoidentityprovider.ErrCodeUserNotFoundException)
+
+	forUser := config.ListGroupsForUser(member("", "jo@example.com"))
+	checkResult(t, forUser, 200, "")
+	if got := names(forUser.Groups); got != "admin" {
+		t.Errorf("groups for jo@example.com = %s, want admin", got)
+	}
+	inGroup := config.ListUsersInGroup(group("admin"))
+	checkResult(t, inGroup, 200, "")
+	if len(inGroup.UserList) != 1 || inGroup.UserList[0].Email != "jo@example.com" {
+		t.Errorf("admin members = %+v, want jo@example.com", inGroup.UserList)
+	}
+
+	checkResult(t, config.RemoveUserFromGroup(member("admin", "jo@example.com")), 200, "")
+	if after := config.ListUsersInGroup(group("admin")); len(after.UserList) != 0 {
+		t.Errorf("admin members after removal = %+v, want none", after.UserList)
+	}
+	checkResult(t, config.DeleteGroup(group("support")), 200, "")
+	checkResult(t, config.DeleteGroup(group("support")), 404, cognitoidentityprovider.ErrCodeResourceNotFoundException)
+	if got := names(config.ListGroups(user.GroupRequest{UserPoolID: poolID}).Groups); got != "admin" {
+		t.Errorf("groups after deletion = %s, want admin", got)
+	}
+}
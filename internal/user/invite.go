@@ -0,0 +1,109 @@
+package user
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+const (
+	CreateModePassword = "password"
+	CreateModeInvite = "invite"
+)
+
+// InviteOptions control the message sent in invite mode. DeliveryMediums
+// are EMAIL, the default, and SMS, which needs User.PhoneNumber. Without a
+// TemporaryPassword Cognito generates one. Resend sends a new temporary
+// password to a user who has not yet signed in.
+type InviteOptions struct {
+	DeliveryMediums []string `json:"delivery_mediums,omitempty"`
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+	Resend bool `json:"resend,omitempty"`
+}
+
+// InviteUser creates the user with a temporary password that Cognito sends
+// them, or resends it. They choose their own password at first sign-in, in
+// answer to the NEW_PASSWORD_REQUIRED challenge.
+func (config Config) InviteUser(user UserItem) (response UserResponse) {
+	return config.inviteUser(user, callOnce)
+}
+
+// inviteUser invites USER as InviteUser does, calling Cognito through CALL
+// as addUser does.
+func (config Config) inviteUser(user UserItem, call func(func() error) error) (response UserResponse) {
+	newUser := user.User
+	invite := user.Invite
+	if user.UserPoolID == "" || newUser.Email == "" || (newUser.Name == "" && !invite.Resend) {
+		response.Invalid("You must supply an email address, user pool ID, and user name")
+		return
+	}
+
+	mediums := invite.DeliveryMediums
+	if len(mediums) == 0 {
+		mediums = []string{cognitoidentityprovider.DeliveryMediumTypeEmail}
+	}
+	for _, medium := range mediums {
+		switch medium {
+		case cognitoidentityprovider.DeliveryMediumTypeEmail:
+		case cognitoidentityprovider.DeliveryMediumTypeSms:
+			if newUser.PhoneNumber == "" && !invite.Resend {
+				response.Invalid("You must supply a phone number to invite by SMS")
+				return
+			}
+		default:
+			response.Invalid(fmt.Sprintf("Unknown delivery medium %s, must be EMAIL or SMS", medium))
+			return
+		}
+	}
+
+	input := &cognitoidentityprovider.AdminCreateUserInput{
+		DesiredDeliveryMediums: aws.StringSlice(mediums),
+		UserPoolId:             aws.String(user.UserPoolID),
+		Username:               aws.String(newUser.Email),
+	}
+	if invite.Resend {
+		input.MessageAction = aws.String(cognitoidentityprovider.MessageActionTypeResend)
+	} else {
+		input.TemporaryPassword = userpool.OptionalString(invite.TemporaryPassword)
+		input.UserAttributes = newUserAttributes(newUser)
+	}
+
+	var output *cognitoidentityprovider.AdminCreateUserOutput
+	err := call(func() (err error) {
+		output, err = config.CognitoClient.AdminCreateUser(input)
+		return
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Invitation sent"
+	response.UserList = []NewUserItem{newUserItem(output.User)}
+	return
+}
+
+// ResetUserPassword invalidates the user's password and sends them a code
+// to choose a new one with ConfirmForgotPassword. Until then they cannot
+// sign in.
+func (config Config) ResetUserPassword(request AdminUserRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+
+	_, err := config.CognitoClient.AdminResetUserPassword(&cognitoidentityprovider.AdminResetUserPasswordInput{
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
@@ -0,0 +1,88 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"testing"
+)
+
+func TestInviteUser(t *testing.T) {
+	config, poolID, clientID := newConfig(t)
+	invite := func(email string, options user.InviteOptions) user.UserItem {
+		item := newUser(poolID, email)
+		item.Mode = user.CreateModeInvite
+		item.Invite = options
+		return item
+	}
+	sms := user.InviteOptions{DeliveryMediums: []string{cognitoidentityprovider.DeliveryMediumTypeSms}}
+
+	tests := []struct {
+		name   string
+		item   user.UserItem
+		status int
+		code   string
+	}{
+		{"invites by email", invite("jo@example.com", user.InviteOptions{}), 200, ""},
+		{"sets the temporary password", invite("al@example.com", user.InviteOptions{TemporaryPassword: "Temp0rary!"}), 200, ""},
+		{"rejects existing user", invite("jo@example.com", user.InviteOptions{}), 409, cognitoidentityprovider.ErrCodeUsernameExistsException},
+		{"requires phone number for SMS", invite("sam@example.com", sms), 400, "InvalidRequest"},
+		{"rejects unknown medium", invite("sam@example.com", user.InviteOptions{DeliveryMediums: []string{"PIGEON"}}), 400, "InvalidRequest"},
+		{"resends invitation", invite("jo@example.com", user.InviteOptions{Resend: true}), 200, ""},
+		{"cannot resend to unknown user", invite("nobody@example.com", user.InviteOptions{Resend: true}), 404, cognitoidentityprovider.ErrCodeUserNotFoundException},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.AddUser(test.item)
+			checkResult(t, response, test.status, test.code)
+			if test.status == 200 && response.UserList[0].Status != cognitoidentityprovider.UserStatusTypeForceChangePassword {
+				t.Errorf("invited user is %s, want FORCE_CHANGE_PASSWORD", response.UserList[0].Status)
+			}
+		})
+	}
+
+	// Only the email address, which the invitation goes to, is verified.
+	for _, test := range []struct {
+		email    string
+		asserted string
+		want     string
+	}{
+		{"sms@example.com", "", ""},
+		{"verified@example.com", "true", "true"},
+	} {
+		item := invite(test.email, sms)
+		item.User.PhoneNumber = "+6512345678"
+		item.User.PhoneNumberVerified = test.asserted
+		invited := config.AddUser(item)
+		checkResult(t, invited, 200, "")
+		got := invited.UserList[0]
+		if got.EmailVerified != "true" || got.PhoneNumberVerified != test.want {
+			t.Errorf("%s email_verified = %q, phone_number_verified = %q, want true and %q", test.email, got.EmailVerified, got.PhoneNumberVerified, test.want)
+		}
+	}
+
+	// An invited user chooses their password at first sign-in.
+	signedIn := config.AuthenticateUser(user.LoginRequest{Email: "al@example.com", Password: "Temp0rary!", ClientID: clientID})
+	checkResult(t, signedIn, 200, "")
+	if signedIn.ChallengeName != cognitoidentityprovider.ChallengeNameTypeNewPasswordRequired {
+		t.Fatalf("first sign-in = %s challenge, want NEW_PASSWORD_REQUIRED", signedIn.ChallengeName)
+	}
+	answered := config.RespondToAuthChallenge(user.ChallengeRequest{
+		Email:         "al@example.com",
+		ClientID:      clientID,
+		ChallengeName: signedIn.ChallengeName,
+		Session:       signedIn.Session,
+		Responses:     map[string]string{"NEW_PASSWORD": password},
+	})
+	checkResult(t, answered, 200, "")
+	got, err := config.CognitoClient.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: aws.String(poolID),
+		Username:   aws.String("al@example.com"),
+	})
+	if err != nil {
+		t.Fatalf("getting user: %s", err)
+	}
+	if status := aws.StringValue(got.UserStatus); status != cognitoidentityprovider.UserStatusTypeConfirmed {
+		t.Errorf("user is %s after choosing a password, want CONFIRMED", status)
+	}
+}
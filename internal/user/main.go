@@ -3,190 +3,415 @@ package user
 import (
 	"encoding/json"
 	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"strings"
+	"time"
 )
 
+// LoginRequest signs a user in or starts a password reset. As with
+// RefreshRequest, clients created with a secret need ClientSecret or
+// UserPoolID so the SECRET_HASH can be computed.
 type LoginRequest struct {
 	Email string `json:"email_address"`
 	Password string `json:"password,omitempty"`
 	ClientID string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
 }
 
 type ForgotPasswordRequest struct {
 	Email string `json:"email_address"`
 	Password string `json:"password,omitempty"`
 	ClientID string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
 	Code string `json:"confirmation_code"`
 }
 
 
+// UserItem creates a user. Mode "password", the default, sets User.Password
+// as their permanent password without messaging them; mode "invite" has
+// Cognito send them a temporary password instead, see InviteOptions.
 type UserItem struct {
 	UserPoolID string `json:"user_pool_id"`
 	User NewUserItem `json:"user"`
+	Mode string `json:"mode,omitempty"`
+	Invite InviteOptions `json:"invite"`
 }
 
+// NewUserItem is both the user to create and the user returned. Password is
+// only ever read from requests; MarshalJSON leaves it out. Custom holds the
+// custom:* attributes by their full name. A new user's phone number is only
+// marked verified when PhoneNumberVerified is "true".
 type NewUserItem struct {
+	Username string `json:"username,omitempty"`
+	Sub string `json:"sub,omitempty"`
 	Name string `json:"name"`
 	Email string `json:"email_address"`
-	Password string `json:"password, omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Password string `json:"password,omitempty"`
 	EmailVerified string `json:"email_verified"`
+	PhoneNumberVerified string `json:"phone_number_verified,omitempty"`
 	Confirmed string `json:"is_confirmed"`
+	Status string `json:"status,omitempty"`
+	Enabled *bool `json:"enabled,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	MFAOptions []MFAOption `json:"mfa_options,omitempty"`
+	Custom map[string]string `json:"custom_attributes,omitempty"`
+}
+
+type MFAOption struct {
+	DeliveryMedium string `json:"delivery_medium"`
+	AttributeName string `json:"attribute_name"`
 }
 
+func (item NewUserItem) MarshalJSON() ([]byte, error) {
+	type userItem NewUserItem
+	out := userItem(item)
+	out.Password = ""
+	return json.Marshal(out)
+}
+
+// Config depends on the Cognito API interface rather than the concrete
+// client so a fake can be substituted outside of AWS. ObjectStore, S3 or a
+// compatible store, holds exports too large to return directly in
+// ExportBucket, the only bucket they are written to or restored from.
+// UserPoolID is the pool the service serves, the only one whose client
+// secrets are looked up on behalf of callers.
 type Config struct {
 	Information string
-	CognitoClient cognitoidentityprovider.CognitoIdentityProvider
+	CognitoClient cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	UserPoolID string
+	ObjectStore s3iface.S3API
+	ExportBucket string
+}
+
+type StatusResponse struct {
+	apierror.Result
 }
 
+// ListUserRequest pages through a pool's users. Filter is a Cognito filter
+// expression such as email ^= "jo" or cognito:user_status = "CONFIRMED";
+// AttributesToGet limits the attributes returned. All follows
+// PaginationToken server-side until every user, or listAllCap of them, is
+// listed.
+type ListUserRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	Limit int64 `json:"limit,omitempty"`
+	PaginationToken string `json:"pagination_token,omitempty"`
+	Filter string `json:"filter,omitempty"`
+	AttributesToGet []string `json:"attributes_to_get,omitempty"`
+	All bool `json:"all,omitempty"`
+}
+
+const listAllCap = 1000
+
 type UserResponse struct {
-	ResponseCode int `json:"response_code"`
-	Message string `json:"message"`
+	apierror.Result
 	UserList []NewUserItem `json:"users"`
+	NextToken string `json:"next_token,omitempty"`
 }
 
-func (config Config) DeleteUser(user UserItem) (string, int) {
+func (config Config) DeleteUser(user UserItem) (response StatusResponse) {
+	if user.UserPoolID == "" || user.User.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
 	deleteUserInput := &cognitoidentityprovider.AdminDeleteUserInput{
 		UserPoolId: aws.String(user.UserPoolID),
 		Username:   aws.String(user.User.Email),
 	}
 	_ , err := config.CognitoClient.AdminDeleteUser(deleteUserInput)
 	if err != nil {
-		return err.Error(), 500
+		response.Fail(err)
+		return
 	}
-	return "Ok", 200
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
 }
 
 func (config Config) AddUser(user UserItem) (response UserResponse) {
+	return config.addUser(user, callOnce)
+}
+
+// callOnce makes a Cognito call without retrying it.
+func callOnce(call func() error) error {
+	return call()
+}
+
+// addUser creates USER as AddUser does, making each Cognito call through
+// CALL. Bulk imports pass retryThrottled, so a throttled step is retried on
+// its own and a user is never created twice.
+func (config Config) addUser(user UserItem, call func(func() error) error) (response UserResponse) {
+	switch user.Mode {
+	case "", CreateModePassword:
+	case CreateModeInvite:
+		return config.inviteUser(user, call)
+	default:
+		response.Invalid(fmt.Sprintf("Unknown mode %s, must be %s or %s", user.Mode, CreateModePassword, CreateModeInvite))
+		return
+	}
 	newUser := user.User
 
 	if newUser.Email == "" || user.UserPoolID == "" || newUser.Name == "" {
 		fmt.Println("You must supply an email address, user pool ID, and user name")
 		fmt.Println("Usage: go run CreateUser.go -e EMAIL-ADDRESS -p USER-POOL-ID -n USER-NAME")
-		response.Message = fmt.Sprintf("You must supply an email address, user pool ID, and user name")
-		response.ResponseCode = 500
+		response.Invalid("You must supply an email address, user pool ID, and user name")
+		return
+	}
+
+	var output *cognitoidentityprovider.AdminCreateUserOutput
+	err := call(func() (err error) {
+		output, err = config.CognitoClient.AdminCreateUser(newUserInput(user))
+		return
+	})
+	if err != nil {
+		response.Fail(err)
+		fmt.Println("Got error creating user:", err)
 		return
 	}
 
+	newUserItem := newUserItem(output.User)
+
+	err = call(func() (err error) {
+		_, err = config.CognitoClient.AdminSetUserPassword(permanentPasswordInput(user))
+		return
+	})
+	if err != nil {
+		failure := apierror.FromError(err)
+		response.Fail(apierror.New(failure.Status, failure.Code, "The user was created but their password could not be set: "+failure.Message))
+		return
+	}
+
+	newUserItem.Status = cognitoidentityprovider.UserStatusTypeConfirmed
+	newUserItem.Confirmed = "true"
+
+	response = UserResponse{
+		Result:   apierror.Result{ResponseCode: 200, Message: "Ok"},
+		UserList: append([]NewUserItem{}, newUserItem),
+	}
+	return
+}
+
+// newUserInput creates the user without a welcome message, ready for
+// permanentPasswordInput.
+func newUserInput(user UserItem) *cognitoidentityprovider.AdminCreateUserInput {
 	newUserData := &cognitoidentityprovider.AdminCreateUserInput{
 		DesiredDeliveryMediums: []*string{
 			aws.String("EMAIL"),
 		},
-		MessageAction: aws.String("SUPPRESS"),
-		UserAttributes: []*cognitoidentityprovider.AttributeType{
-			{
-				Name:  aws.String("email"),
-				Value: aws.String(user.User.Email),
-			},
-			{
-				Name:  aws.String("name"),
-				Value: aws.String(user.User.Name),
-			},
-			{
-				Name:  aws.String("email_verified"),
-				Value: aws.String("true"),
-			},
-		},
+		MessageAction:  aws.String("SUPPRESS"),
+		UserAttributes: newUserAttributes(user.User),
 	}
 
 	newUserData.SetUserPoolId(user.UserPoolID)
 	newUserData.SetUsername(user.User.Email)
+	return newUserData
+}
 
-	output, err := config.CognitoClient.AdminCreateUser(newUserData)
-	if err != nil {
-		response.ResponseCode = 500
-		response.Message = fmt.Sprintf("Got error creating user: %s ", err)
-		fmt.Println("Got error creating user:", err)
-		return
-	}
-
-	newUserItem := NewUserItem{
-		Name:  "",
-		Email: "",
+// newUserAttributes are the attributes a user is created with. Their email
+// address, which they are invited at and sign in with, is marked verified;
+// their phone number only when the request says it has been verified.
+func newUserAttributes(item NewUserItem) []*cognitoidentityprovider.AttributeType {
+	attributes := []*cognitoidentityprovider.AttributeType{
+		{
+			Name:  aws.String("email"),
+			Value: aws.String(item.Email),
+		},
+		{
+			Name:  aws.String("name"),
+			Value: aws.String(item.Name),
+		},
+		{
+			Name:  aws.String("email_verified"),
+			Value: aws.String("true"),
+		},
 	}
-	attributes := output.User.Attributes
-	for _, a := range attributes {
-		if *a.Name == "name" {
-			newUserItem.Name = *a.Value
-		} else if *a.Name == "email" {
-			newUserItem.Email = *a.Value
+	if item.PhoneNumber != "" {
+		attributes = append(attributes, &cognitoidentityprovider.AttributeType{
+			Name:  aws.String("phone_number"),
+			Value: aws.String(item.PhoneNumber),
+		})
+		if phoneNumberVerified(item) {
+			attributes = append(attributes, &cognitoidentityprovider.AttributeType{
+				Name:  aws.String("phone_number_verified"),
+				Value: aws.String("true"),
+			})
 		}
 	}
+	return attributes
+}
+
+// phoneNumberVerified reports whether the request asserts the user's phone
+// number has been verified.
+func phoneNumberVerified(item NewUserItem) bool {
+	return item.PhoneNumber != "" && item.PhoneNumberVerified == "true"
+}
 
-	passwordInput := &cognitoidentityprovider.AdminSetUserPasswordInput{
+// permanentPasswordInput sets the user's password, confirming them.
+func permanentPasswordInput(user UserItem) *cognitoidentityprovider.AdminSetUserPasswordInput {
+	return &cognitoidentityprovider.AdminSetUserPasswordInput{
 		Password:   aws.String(user.User.Password),
 		Permanent:  aws.Bool(true),
 		UserPoolId: aws.String(user.UserPoolID),
-		Username:  aws.String(user.User.Email),
+		Username:   aws.String(user.User.Email),
 	}
+}
 
-	_, err = config.CognitoClient.AdminSetUserPassword(passwordInput)
-	if err != nil {
-		response.ResponseCode = 400
-		response.Message = err.Error()
+// ListUser returns one page of users, or with All every page up to
+// listAllCap users. NextToken in the response continues from where the
+// listing stopped.
+func (config Config) ListUser(request ListUserRequest) (response UserResponse){
+	if request.UserPoolID == "" {
+		fmt.Println("You must supply a user pool ID")
+		response.Invalid("You must supply a user pool ID")
+		return
+	}
+	if request.Limit < 0 || request.Limit > 60 {
+		response.Invalid("You must supply a limit between 1 and 60")
 		return
 	}
 
+	input := &cognitoidentityprovider.ListUsersInput{
+		Filter:          userpool.OptionalString(listUserFilter(request.Filter)),
+		PaginationToken: userpool.OptionalString(request.PaginationToken),
+		UserPoolId:      aws.String(request.UserPoolID),
+	}
+	if request.Limit > 0 {
+		input.Limit = aws.Int64(request.Limit)
+	}
+	if len(request.AttributesToGet) > 0 {
+		input.AttributesToGet = aws.StringSlice(request.AttributesToGet)
+	}
+
+	usersList := []NewUserItem{}
+	for {
+		results, err := config.CognitoClient.ListUsers(input)
+		if err != nil {
+			response.Fail(err)
+			fmt.Println("Got error listing users:", err)
+			return
+		}
+		for _, user := range results.Users {
+			usersList = append(usersList, newUserItem(user))
+		}
+		input.PaginationToken = results.PaginationToken
+		if !request.All || results.PaginationToken == nil || len(usersList) >= listAllCap {
+			break
+		}
+	}
+
 	response = UserResponse{
-		ResponseCode: 200,
-		Message:      "Ok",
-		UserList:     append([]NewUserItem{}, newUserItem),
+		Result:    apierror.Result{ResponseCode: 200, Message: "Ok"},
+		UserList:  usersList,
+		NextToken: aws.StringValue(input.PaginationToken),
 	}
 	return
 }
 
-func (config Config) ListUser(item UserItem) (response UserResponse){
-	if item.UserPoolID == "" {
-		fmt.Println("You must supply a user pool ID")
-		response.ResponseCode = 500
-		response.Message = "You must supply a user pool ID"
+// listUserFilter lets status = "CONFIRMED" and the other user statuses stand
+// for cognito:user_status, since Cognito's status attribute only holds
+// Enabled or Disabled.
+func listUserFilter(filter string) string {
+	parts := strings.SplitN(filter, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) != "status" {
+		return filter
+	}
+	value := strings.Trim(strings.TrimSpace(parts[1]), `"`)
+	if value == "Enabled" || value == "Disabled" {
+		return filter
+	}
+	return `cognito:user_status = "` + value + `"`
+}
+
+// GetUser returns one user of a pool with their status, whether they are
+// enabled and when they were created and last modified.
+func (config Config) GetUser(request AdminUserRequest) (response UserResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
 		return
 	}
 
-	results, err := config.CognitoClient.ListUsers(
-		&cognitoidentityprovider.ListUsersInput{
-			UserPoolId: aws.String(item.UserPoolID)})
+	output, err := config.CognitoClient.AdminGetUser(&cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: aws.String(request.UserPoolID),
+		Username:   aws.String(request.Email),
+	})
 	if err != nil {
-		response.ResponseCode = 500
-		response.Message = "Got error listing users"
-		fmt.Println("Got error listing users")
+		response.Fail(err)
 		return
 	}
 
-	var usersList []NewUserItem
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.UserList = []NewUserItem{adminUserItem(output)}
+	return
+}
 
-	for _, user := range results.Users {
-		newUser := NewUserItem{
-			Name:  "",
-			Email: "",
-		}
-		fmt.Println("Users ", user.String())
-		attributes := user.Attributes
-		for _, a := range attributes {
-			if *a.Name == "name" {
-				newUser.Name = *a.Value
-			} else if *a.Name == "email" {
-				newUser.Email = *a.Value
-			} else if *a.Name == "email_verified" {
-				newUser.EmailVerified = *a.Value
-			} else if *a.Name == "is_confirmed" {
-				newUser.Confirmed = *a.Value
+// adminUserItem maps the user AdminGetUser returns onto NewUserItem.
+func adminUserItem(output *cognitoidentityprovider.AdminGetUserOutput) NewUserItem {
+	return newUserItem(&cognitoidentityprovider.UserType{
+		Attributes:           output.UserAttributes,
+		Enabled:              output.Enabled,
+		MFAOptions:           output.MFAOptions,
+		UserCreateDate:       output.UserCreateDate,
+		UserLastModifiedDate: output.UserLastModifiedDate,
+		UserStatus:           output.UserStatus,
+		Username:             output.Username,
+	})
+}
+
+// newUserItem maps a Cognito user onto NewUserItem. is_confirmed reflects
+// UserStatus, as Cognito has no such attribute.
+func newUserItem(user *cognitoidentityprovider.UserType) NewUserItem {
+	newUser := NewUserItem{
+		Username:  aws.StringValue(user.Username),
+		Status:    aws.StringValue(user.UserStatus),
+		Enabled:   user.Enabled,
+		CreatedAt: user.UserCreateDate,
+		UpdatedAt: user.UserLastModifiedDate,
+	}
+	if newUser.Status != "" {
+		newUser.Confirmed = fmt.Sprint(newUser.Status == cognitoidentityprovider.UserStatusTypeConfirmed)
+	}
+	for _, a := range user.Attributes {
+		name, value := aws.StringValue(a.Name), aws.StringValue(a.Value)
+		switch {
+		case name == "sub":
+			newUser.Sub = value
+		case name == "name":
+			newUser.Name = value
+		case name == "email":
+			newUser.Email = value
+		case name == "phone_number":
+			newUser.PhoneNumber = value
+		case name == "email_verified":
+			newUser.EmailVerified = value
+		case name == "phone_number_verified":
+			newUser.PhoneNumberVerified = value
+		case strings.HasPrefix(name, "custom:"):
+			if newUser.Custom == nil {
+				newUser.Custom = map[string]string{}
 			}
+			newUser.Custom[name] = value
 		}
-		usersList = append(usersList, newUser)
 	}
-
-	response = UserResponse{
-		ResponseCode: 200,
-		Message:      "Ok",
-		UserList:     usersList,
+	for _, option := range user.MFAOptions {
+		newUser.MFAOptions = append(newUser.MFAOptions, MFAOption{
+			DeliveryMedium: aws.StringValue(option.DeliveryMedium),
+			AttributeName:  aws.StringValue(option.AttributeName),
+		})
 	}
-
-	return
+	return newUser
 }
 
-func (config Config) AuthenticateUser(request LoginRequest) (string,int) {
-	fmt.Println("Object -> ", request)
+func (config Config) AuthenticateUser(request LoginRequest) (response AuthResponse) {
 	params := &cognitoidentityprovider.InitiateAuthInput{
 		AuthFlow: aws.String("USER_PASSWORD_AUTH"),
 		AuthParameters: map[string]*string{
@@ -195,46 +420,70 @@ func (config Config) AuthenticateUser(request LoginRequest) (string,int) {
 		},
 		ClientId: aws.String(request.ClientID),
 	}
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	if secret != "" {
+		params.AuthParameters["SECRET_HASH"] = aws.String(SecretHash(request.Email, request.ClientID, secret))
+	}
+	return config.initiateAuth(params)
+}
+
+func (config Config) initiateAuth(params *cognitoidentityprovider.InitiateAuthInput) (response AuthResponse) {
 	authResp, err := config.CognitoClient.InitiateAuth(params)
 	if err != nil {
-		return err.Error() , 400
+		response.Fail(err)
+		return
 	}
-	fmt.Println("Result ", authResp)
-	return authResp.String(), 200
+	return newAuthResponse(authResp.AuthenticationResult, authResp.ChallengeName, authResp.Session, authResp.ChallengeParameters)
 }
 
-func (config Config) ForgotPassword(request LoginRequest) (string, int) {
+func (config Config) ForgotPassword(request LoginRequest) (response CodeDeliveryResponse) {
 	input := &cognitoidentityprovider.ForgotPasswordInput{
 		ClientId:          aws.String(request.ClientID),
 		Username:          aws.String(request.Email),
 	}
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	if secret != "" {
+		input.SecretHash = aws.String(SecretHash(request.Email, request.ClientID, secret))
+	}
 	output, error := config.CognitoClient.ForgotPassword(input)
 	if error != nil {
-		return error.Error(), 500
+		response.Fail(error)
+		return
 	}
 
-	return output.String(), 200
+	return newCodeDeliveryResponse(output.CodeDeliveryDetails)
 }
 
-func (config Config) ConfirmForgotPassword(request ForgotPasswordRequest) (string, int) {
+func (config Config) ConfirmForgotPassword(request ForgotPasswordRequest) (response StatusResponse) {
 	input := &cognitoidentityprovider.ConfirmForgotPasswordInput{
 		ClientId:         aws.String(request.ClientID),
 		ConfirmationCode: aws.String(request.Code),
 		Username:         aws.String(request.Email),
 		Password: aws.String(request.Password),
 	}
-	output, error := config.CognitoClient.ConfirmForgotPassword(input)
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	if secret != "" {
+		input.SecretHash = aws.String(SecretHash(request.Email, request.ClientID, secret))
+	}
+	_, error := config.CognitoClient.ConfirmForgotPassword(input)
 	if error != nil {
-		return error.Error(), 500
+		response.Fail(error)
+		return
 	}
 
-	return output.String(), 200
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
 }
-
-func (config Config) ObjectToJsonString (response UserResponse) string {
-	responseJson, err := json.Marshal(response)
-	if err != nil {
-		return "Unable to construct JSON"
-	}
-	return string(responseJson)
-}
\ No newline at end of file
@@ -0,0 +1,248 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"testing"
+)
+
+const password = "Passw0rd!"
+
+// newConfig returns a Config backed by an empty emulated pool, with an app
+// client allowing password sign-in.
+func newConfig(t *testing.T) (user.Config, string, string) {
+	fake := cognitofake.New("ap-southeast-1")
+	pool, err := fake.CreateUserPool(&cognitoidentityprovider.CreateUserPoolInput{
+		PoolName: aws.String("test"),
+	})
+	if err != nil {
+		t.Fatalf("creating pool: %s", err)
+	}
+	client, err := fake.CreateUserPoolClient(&cognitoidentityprovider.CreateUserPoolClientInput{
+		ClientName:        aws.String("test"),
+		ExplicitAuthFlows: aws.StringSlice([]string{"ALLOW_USER_PASSWORD_AUTH", "ALLOW_REFRESH_TOKEN_AUTH"}),
+		UserPoolId:        pool.UserPool.Id,
+	})
+	if err != nil {
+		t.Fatalf("creating client: %s", err)
+	}
+	config := user.Config{Information: "test", CognitoClient: fake, UserPoolID: aws.StringValue(pool.UserPool.Id)}
+	return config, aws.StringValue(pool.UserPool.Id), aws.StringValue(client.UserPoolClient.ClientId)
+}
+
+func newUser(poolID string, email string) user.UserItem {
+	return user.UserItem{
+		UserPoolID: poolID,
+		User:       user.NewUserItem{Email: email, Name: "Jo", Password: password},
+	}
+}
+
+// checkResult fails the test unless the response has STATUS and, for a
+// failure, the error CODE.
+func checkResult(t *testing.T, result interface{ Outcome() apierror.Result }, status int, code string) {
+	t.Helper()
+	got := result.Outcome()
+	if got.ResponseCode != status {
+		t.Fatalf("response code = %d (%s), want %d", got.ResponseCode, got.Message, status)
+	}
+	switch {
+	case code == "" && got.Error != nil:
+		t.Fatalf("unexpected error %s", got.Error)
+	case code != "" && (got.Error == nil || got.Error.Code != code):
+		t.Fatalf("error = %v, want code %s", got.Error, code)
+	}
+}
+
+func TestAddUser(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "taken@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+
+	weak := newUser(poolID, "weak@example.com")
+	weak.User.Password = "short"
+	tests := []struct {
+		name   string
+		item   user.UserItem
+		status int
+		code   string
+	}{
+		{"creates confirmed user", newUser(poolID, "jo@example.com"), 200, ""},
+		{"rejects existing user", newUser(poolID, "taken@example.com"), 409, cognitoidentityprovider.ErrCodeUsernameExistsException},
+		{"requires email", newUser(poolID, ""), 400, "InvalidRequest"},
+		{"rejects unknown mode", user.UserItem{UserPoolID: poolID, Mode: "magic"}, 400, "InvalidRequest"},
+		{"rejects weak password", weak, 400, cognitoidentityprovider.ErrCodeInvalidPasswordException},
+		{"rejects unknown pool", newUser("ap-southeast-1_missing", "jo@example.com"), 404, cognitoidentityprovider.ErrCodeResourceNotFoundException},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.AddUser(test.item)
+			checkResult(t, response, test.status, test.code)
+			if test.status == 200 && response.UserList[0].Status != cognitoidentityprovider.UserStatusTypeConfirmed {
+				t.Fatalf("status = %s, want CONFIRMED", response.UserList[0].Status)
+			}
+		})
+	}
+}
+
+func TestListUser(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if response := config.AddUser(newUser(poolID, email)); response.ResponseCode != 200 {
+			t.Fatalf("seeding %s: %s", email, response.Message)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		request user.ListUserRequest
+		status  int
+		code    string
+		users   int
+	}{
+		{"lists every user", user.ListUserRequest{UserPoolID: poolID}, 200, "", 3},
+		{"pages by limit", user.ListUserRequest{UserPoolID: poolID, Limit: 2}, 200, "", 2},
+		{"follows every page", user.ListUserRequest{UserPoolID: poolID, Limit: 1, All: true}, 200, "", 3},
+		{"filters by email", user.ListUserRequest{UserPoolID: poolID, Filter: `email = "b@example.com"`}, 200, "", 1},
+		{"requires pool ID", user.ListUserRequest{}, 400, "InvalidRequest", 0},
+		{"rejects large limit", user.ListUserRequest{UserPoolID: poolID, Limit: 61}, 400, "InvalidRequest", 0},
+		{"rejects unknown pool", user.ListUserRequest{UserPoolID: "ap-southeast-1_missing"}, 404, cognitoidentityprovider.ErrCodeResourceNotFoundException, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.ListUser(test.request)
+			checkResult(t, response, test.status, test.code)
+			if len(response.UserList) != test.users {
+				t.Fatalf("listed %d users, want %d", len(response.UserList), test.users)
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "jo@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+
+	tests := []struct {
+		name   string
+		item   user.UserItem
+		status int
+		code   string
+	}{
+		{"deletes user", newUser(poolID, "jo@example.com"), 200, ""},
+		{"reports deleted user missing", newUser(poolID, "jo@example.com"), 404, cognitoidentityprovider.ErrCodeUserNotFoundException},
+		{"requires email", newUser(poolID, ""), 400, "InvalidRequest"},
+		{"requires pool ID", newUser("", "jo@example.com"), 400, "InvalidRequest"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkResult(t, config.DeleteUser(test.item), test.status, test.code)
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "jo@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+
+	got := config.GetUser(user.AdminUserRequest{UserPoolID: poolID, Email: "jo@example.com"})
+	checkResult(t, got, 200, "")
+	item := got.UserList[0]
+	if item.Status != cognitoidentityprovider.UserStatusTypeConfirmed || item.Confirmed != "true" || !aws.BoolValue(item.Enabled) {
+		t.Errorf("user = %s confirmed %s enabled %v, want CONFIRMED, true and enabled", item.Status, item.Confirmed, item.Enabled)
+	}
+	if item.CreatedAt == nil || item.UpdatedAt == nil || item.Sub == "" || item.Email != "jo@example.com" {
+		t.Errorf("user = %+v, want dates, sub and email", item)
+	}
+
+	checkResult(t, config.GetUser(user.AdminUserRequest{UserPoolID: poolID, Email: "nobody@example.com"}), 404, cognitoidentityprovider.ErrCodeUserNotFoundException)
+	checkResult(t, config.GetUser(user.AdminUserRequest{UserPoolID: poolID}), 400, "InvalidRequest")
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	config, poolID, clientID := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "jo@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+	secretClient, err := config.CognitoClient.CreateUserPoolClient(&cognitoidentityprovider.CreateUserPoolClientInput{
+		ClientName:        aws.String("server"),
+		ExplicitAuthFlows: aws.StringSlice([]string{"ALLOW_USER_PASSWORD_AUTH"}),
+		GenerateSecret:    aws.Bool(true),
+		UserPoolId:        aws.String(poolID),
+	})
+	if err != nil {
+		t.Fatalf("creating client with secret: %s", err)
+	}
+	secretClientID := aws.StringValue(secretClient.UserPoolClient.ClientId)
+	secret := aws.StringValue(secretClient.UserPoolClient.ClientSecret)
+
+	tests := []struct {
+		name    string
+		request user.LoginRequest
+		status  int
+		code    string
+	}{
+		{"issues tokens", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: clientID}, 200, ""},
+		{"rejects wrong password", user.LoginRequest{Email: "jo@example.com", Password: "Wr0ng!pass", ClientID: clientID}, 401, cognitoidentityprovider.ErrCodeNotAuthorizedException},
+		{"rejects unknown user", user.LoginRequest{Email: "nobody@example.com", Password: password, ClientID: clientID}, 404, cognitoidentityprovider.ErrCodeUserNotFoundException},
+		{"rejects unknown client", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: "missing"}, 404, cognitoidentityprovider.ErrCodeResourceNotFoundException},
+		{"signs secret client in with its secret", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: secretClientID, ClientSecret: secret}, 200, ""},
+		{"looks secret up by pool ID", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: secretClientID, UserPoolID: poolID}, 200, ""},
+		{"refuses to look secrets up in another pool", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: secretClientID, UserPoolID: "ap-southeast-1_other"}, 400, "InvalidRequest"},
+		{"rejects secret client without secret", user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: secretClientID}, 401, cognitoidentityprovider.ErrCodeNotAuthorizedException},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.AuthenticateUser(test.request)
+			checkResult(t, response, test.status, test.code)
+			if test.status == 200 && (response.AccessToken == "" || response.IDToken == "" || response.RefreshToken == "") {
+				t.Fatalf("missing tokens in %+v", response)
+			}
+		})
+	}
+}
+
+// describing counts the app clients described.
+type describing struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	describes int
+}
+
+func (c *describing) DescribeUserPoolClient(input *cognitoidentityprovider.DescribeUserPoolClientInput) (*cognitoidentityprovider.DescribeUserPoolClientOutput, error) {
+	c.describes++
+	return c.CognitoIdentityProviderAPI.DescribeUserPoolClient(input)
+}
+
+func TestClientSecretIsLookedUpOnce(t *testing.T) {
+	config, poolID, _ := newConfig(t)
+	if response := config.AddUser(newUser(poolID, "jo@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+	client, err := config.CognitoClient.CreateUserPoolClient(&cognitoidentityprovider.CreateUserPoolClientInput{
+		ClientName:        aws.String("server"),
+		ExplicitAuthFlows: aws.StringSlice([]string{"ALLOW_USER_PASSWORD_AUTH"}),
+		GenerateSecret:    aws.Bool(true),
+		UserPoolId:        aws.String(poolID),
+	})
+	if err != nil {
+		t.Fatalf("creating client with secret: %s", err)
+	}
+	cognito := &describing{CognitoIdentityProviderAPI: config.CognitoClient}
+	config.CognitoClient = cognito
+
+	login := user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: aws.StringValue(client.UserPoolClient.ClientId), UserPoolID: poolID}
+	for i := 0; i < 3; i++ {
+		checkResult(t, config.AuthenticateUser(login), 200, "")
+	}
+	if cognito.describes != 1 {
+		t.Errorf("DescribeUserPoolClient called %d times, want once", cognito.describes)
+	}
+}
@@ -0,0 +1,182 @@
+package user
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"net/url"
+)
+
+// SoftwareTokenRequest drives TOTP enrollment. Signed-in users pass their
+// AccessToken; users enrolling during sign-in (the MFA_SETUP challenge) pass
+// the challenge Session instead.
+type SoftwareTokenRequest struct {
+	AccessToken string `json:"access_token,omitempty"`
+	Session string `json:"session,omitempty"`
+	Email string `json:"email_address,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	Code string `json:"code,omitempty"`
+	FriendlyDeviceName string `json:"friendly_device_name,omitempty"`
+}
+
+// MFAPreferenceRequest enables or disables TOTP for a user, identified by
+// their AccessToken or, for the admin variant, by UserPoolID and Email.
+type MFAPreferenceRequest struct {
+	AccessToken string `json:"access_token,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
+	Email string `json:"email_address,omitempty"`
+	Enabled bool `json:"enabled"`
+	Preferred bool `json:"preferred"`
+}
+
+type SoftwareTokenResponse struct {
+	apierror.Result
+	SecretCode string `json:"secret_code,omitempty"`
+	OTPAuthURI string `json:"otpauth_uri,omitempty"`
+	Status string `json:"status,omitempty"`
+	Session string `json:"session,omitempty"`
+}
+
+const defaultIssuer = "fp-apac-cognito-service"
+
+// AssociateSoftwareToken starts TOTP enrollment and returns the shared secret
+// together with an otpauth:// URI an authenticator app can scan as a QR code.
+func (config Config) AssociateSoftwareToken(request SoftwareTokenRequest) (response SoftwareTokenResponse) {
+	if request.AccessToken == "" && request.Session == "" {
+		response.Invalid("You must supply an access token or session")
+		return
+	}
+
+	input := &cognitoidentityprovider.AssociateSoftwareTokenInput{}
+	if request.AccessToken != "" {
+		input.AccessToken = aws.String(request.AccessToken)
+	} else {
+		input.Session = aws.String(request.Session)
+	}
+	output, err := config.CognitoClient.AssociateSoftwareToken(input)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	label := request.Email
+	if label == "" && request.AccessToken != "" {
+		current, err := config.CognitoClient.GetUser(&cognitoidentityprovider.GetUserInput{
+			AccessToken: aws.String(request.AccessToken),
+		})
+		if err == nil {
+			label = aws.StringValue(current.Username)
+			for _, a := range current.UserAttributes {
+				if aws.StringValue(a.Name) == "email" {
+					label = aws.StringValue(a.Value)
+				}
+			}
+		}
+	}
+	issuer := request.Issuer
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.SecretCode = aws.StringValue(output.SecretCode)
+	response.OTPAuthURI = OTPAuthURI(issuer, label, response.SecretCode)
+	response.Session = aws.StringValue(output.Session)
+	return
+}
+
+// VerifySoftwareToken checks the first code from the authenticator app, which
+// completes enrollment.
+func (config Config) VerifySoftwareToken(request SoftwareTokenRequest) (response SoftwareTokenResponse) {
+	if (request.AccessToken == "" && request.Session == "") || request.Code == "" {
+		response.Invalid("You must supply a code and an access token or session")
+		return
+	}
+
+	input := &cognitoidentityprovider.VerifySoftwareTokenInput{
+		UserCode: aws.String(request.Code),
+	}
+	if request.AccessToken != "" {
+		input.AccessToken = aws.String(request.AccessToken)
+	} else {
+		input.Session = aws.String(request.Session)
+	}
+	if request.FriendlyDeviceName != "" {
+		input.FriendlyDeviceName = aws.String(request.FriendlyDeviceName)
+	}
+	output, err := config.CognitoClient.VerifySoftwareToken(input)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.Status = aws.StringValue(output.Status)
+	response.Session = aws.StringValue(output.Session)
+	if response.Status != cognitoidentityprovider.VerifySoftwareTokenResponseTypeSuccess {
+		response.Invalid("Software token verification failed")
+		return
+	}
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// SetMFAPreference enables or disables TOTP for the signed-in user.
+func (config Config) SetMFAPreference(request MFAPreferenceRequest) (response StatusResponse) {
+	if request.AccessToken == "" {
+		response.Invalid("You must supply an access token")
+		return
+	}
+
+	_, err := config.CognitoClient.SetUserMFAPreference(&cognitoidentityprovider.SetUserMFAPreferenceInput{
+		AccessToken:              aws.String(request.AccessToken),
+		SoftwareTokenMfaSettings: softwareTokenSettings(request),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+// AdminSetMFAPreference enables or disables TOTP for any user of the pool.
+func (config Config) AdminSetMFAPreference(request MFAPreferenceRequest) (response StatusResponse) {
+	if request.UserPoolID == "" || request.Email == "" {
+		response.Invalid("You must supply a user pool ID and email address")
+		return
+	}
+
+	_, err := config.CognitoClient.AdminSetUserMFAPreference(&cognitoidentityprovider.AdminSetUserMFAPreferenceInput{
+		SoftwareTokenMfaSettings: softwareTokenSettings(request),
+		UserPoolId:               aws.String(request.UserPoolID),
+		Username:                 aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func softwareTokenSettings(request MFAPreferenceRequest) *cognitoidentityprovider.SoftwareTokenMfaSettingsType {
+	return &cognitoidentityprovider.SoftwareTokenMfaSettingsType{
+		Enabled:      aws.Bool(request.Enabled),
+		PreferredMfa: aws.Bool(request.Enabled && request.Preferred),
+	}
+}
+
+// OTPAuthURI formats the Key URI understood by authenticator apps.
+func OTPAuthURI(issuer string, account string, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s", url.PathEscape(issuer), url.PathEscape(account), values.Encode())
+}
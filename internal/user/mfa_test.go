@@ -0,0 +1,70 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+	"testing"
+)
+
+func TestSoftwareTokenMFA(t *testing.T) {
+	config, poolID, clientID := newConfig(t)
+	fake := config.CognitoClient.(*cognitofake.Cognito)
+	_, err := fake.SetUserPoolMfaConfig(&cognitoidentityprovider.SetUserPoolMfaConfigInput{
+		MfaConfiguration:              aws.String(cognitoidentityprovider.UserPoolMfaTypeOptional),
+		SoftwareTokenMfaConfiguration: &cognitoidentityprovider.SoftwareTokenMfaConfigType{Enabled: aws.Bool(true)},
+		UserPoolId:                    aws.String(poolID),
+	})
+	if err != nil {
+		t.Fatalf("enabling MFA: %s", err)
+	}
+	if response := config.AddUser(newUser(poolID, "jo@example.com")); response.ResponseCode != 200 {
+		t.Fatalf("seeding user: %s", response.Message)
+	}
+	login := user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: clientID}
+	signedIn := config.AuthenticateUser(login)
+	checkResult(t, signedIn, 200, "")
+	token := signedIn.AccessToken
+
+	checkResult(t, config.AssociateSoftwareToken(user.SoftwareTokenRequest{}), 400, "InvalidRequest")
+	associated := config.AssociateSoftwareToken(user.SoftwareTokenRequest{AccessToken: token, Issuer: "Example"})
+	checkResult(t, associated, 200, "")
+	if associated.SecretCode == "" || !strings.HasPrefix(associated.OTPAuthURI, "otpauth://totp/Example:jo@example.com?") {
+		t.Fatalf("association = %+v, want a secret and an otpauth URI for jo@example.com", associated)
+	}
+
+	checkResult(t, config.AdminSetMFAPreference(user.MFAPreferenceRequest{UserPoolID: poolID, Email: "jo@example.com", Enabled: true}),
+		400, cognitoidentityprovider.ErrCodeInvalidParameterException)
+	checkResult(t, config.VerifySoftwareToken(user.SoftwareTokenRequest{AccessToken: token, Code: "000000"}),
+		400, cognitoidentityprovider.ErrCodeEnableSoftwareTokenMFAException)
+	code := cognitofake.TOTP(associated.SecretCode, fake.Now())
+	checkResult(t, config.VerifySoftwareToken(user.SoftwareTokenRequest{AccessToken: token, Code: code}), 200, "")
+	checkResult(t, config.SetMFAPreference(user.MFAPreferenceRequest{AccessToken: token, Enabled: true, Preferred: true}), 200, "")
+
+	challenged := config.AuthenticateUser(login)
+	checkResult(t, challenged, 200, "")
+	if challenged.ChallengeName != cognitoidentityprovider.ChallengeNameTypeSoftwareTokenMfa || challenged.AccessToken != "" {
+		t.Fatalf("sign-in = %+v, want a SOFTWARE_TOKEN_MFA challenge and no tokens", challenged)
+	}
+	answer := user.ChallengeRequest{
+		Email:         "jo@example.com",
+		ClientID:      clientID,
+		ChallengeName: challenged.ChallengeName,
+		Session:       challenged.Session,
+	}
+	checkResult(t, config.RespondToAuthChallenge(answer), 400, "InvalidRequest")
+	answer.Responses = map[string]string{"SOFTWARE_TOKEN_MFA_CODE": cognitofake.TOTP(associated.SecretCode, fake.Now())}
+	answered := config.RespondToAuthChallenge(answer)
+	checkResult(t, answered, 200, "")
+	if answered.AccessToken == "" {
+		t.Fatalf("answering the challenge issued no tokens: %+v", answered)
+	}
+
+	checkResult(t, config.AdminSetMFAPreference(user.MFAPreferenceRequest{UserPoolID: poolID, Email: "jo@example.com"}), 200, "")
+	checkResult(t, config.AuthenticateUser(login), 200, "")
+	if again := config.AuthenticateUser(login); again.ChallengeName != "" {
+		t.Errorf("sign-in after disabling MFA = %s challenge, want tokens", again.ChallengeName)
+	}
+}
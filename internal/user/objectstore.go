@@ -0,0 +1,141 @@
+package user
+
+import (
+	"bytes"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"io"
+)
+
+// objectPartSize is the smallest part S3 accepts in a multipart upload,
+// other than the last.
+const objectPartSize = 5 << 20
+
+// objectWriter streams an object to S3. Objects that fit in one part are
+// put in a single request; larger ones are sent as a multipart upload, one
+// part at a time, so no more than a part is ever held in memory. Close
+// completes the object and Abort discards it.
+type objectWriter struct {
+	client      s3iface.S3API
+	bucket      string
+	key         string
+	contentType string
+
+	buffer   bytes.Buffer
+	uploadID *string
+	parts    []*s3.CompletedPart
+}
+
+func newObjectWriter(client s3iface.S3API, bucket string, key string, contentType string) *objectWriter {
+	return &objectWriter{client: client, bucket: bucket, key: key, contentType: contentType}
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+	for w.buffer.Len() >= objectPartSize {
+		if err := w.uploadPart(w.buffer.Next(objectPartSize)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *objectWriter) Close() error {
+	if w.uploadID == nil {
+		_, err := w.client.PutObject(&s3.PutObjectInput{
+			Body:        bytes.NewReader(w.buffer.Bytes()),
+			Bucket:      aws.String(w.bucket),
+			ContentType: aws.String(w.contentType),
+			Key:         aws.String(w.key),
+		})
+		return err
+	}
+	if w.buffer.Len() > 0 {
+		if err := w.uploadPart(w.buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+	_, err := w.client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(w.bucket),
+		Key:             aws.String(w.key),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: w.parts},
+		UploadId:        w.uploadID,
+	})
+	if err != nil {
+		w.Abort()
+	}
+	return err
+}
+
+// Abort discards the parts uploaded so far, which S3 would otherwise keep
+// and bill for.
+func (w *objectWriter) Abort() {
+	if w.uploadID == nil {
+		return
+	}
+	w.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(w.bucket),
+		Key:      aws.String(w.key),
+		UploadId: w.uploadID,
+	})
+	w.uploadID = nil
+}
+
+func (w *objectWriter) uploadPart(data []byte) error {
+	if w.uploadID == nil {
+		created, err := w.client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(w.bucket),
+			ContentType: aws.String(w.contentType),
+			Key:         aws.String(w.key),
+		})
+		if err != nil {
+			return err
+		}
+		w.uploadID = created.UploadId
+	}
+	number := int64(len(w.parts) + 1)
+	uploaded, err := w.client.UploadPart(&s3.UploadPartInput{
+		Body:       bytes.NewReader(data),
+		Bucket:     aws.String(w.bucket),
+		Key:        aws.String(w.key),
+		PartNumber: aws.Int64(number),
+		UploadId:   w.uploadID,
+	})
+	if err != nil {
+		w.Abort()
+		return err
+	}
+	w.parts = append(w.parts, &s3.CompletedPart{ETag: uploaded.ETag, PartNumber: aws.Int64(number)})
+	return nil
+}
+
+// checkObjectStore fails unless exports can be stored, which needs both an
+// object store and the bucket to keep them in.
+func (config Config) checkObjectStore() error {
+	if config.ObjectStore == nil || config.ExportBucket == "" {
+		return apierror.New(500, apierror.CodeInternalError, "No export bucket is configured")
+	}
+	return nil
+}
+
+// readObject opens an object of the export bucket for reading; the caller
+// closes it.
+func (config Config) readObject(key string) (io.ReadCloser, error) {
+	if err := config.checkObjectStore(); err != nil {
+		return nil, err
+	}
+	output, err := config.ObjectStore.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(config.ExportBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func objectLocation(bucket string, key string) string {
+	return "s3://" + bucket + "/" + key
+}
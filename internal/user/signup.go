@@ -0,0 +1,134 @@
+package user
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+)
+
+// SignUpRequest registers, confirms or resends the code for a self-service
+// user. Clients created with a secret need either ClientSecret or UserPoolID
+// so the SECRET_HASH can be computed.
+type SignUpRequest struct {
+	Email string `json:"email_address"`
+	Password string `json:"password,omitempty"`
+	Name string `json:"name,omitempty"`
+	ClientID string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
+	Code string `json:"confirmation_code,omitempty"`
+}
+
+type SignUpResponse struct {
+	apierror.Result
+	UserSub string `json:"user_sub,omitempty"`
+	UserConfirmed bool `json:"user_confirmed"`
+	CodeDelivery *CodeDelivery `json:"code_delivery,omitempty"`
+}
+
+// SignUp creates an unconfirmed user with the given password. Cognito sends a
+// confirmation code unless a pre sign-up trigger confirms the user.
+func (config Config) SignUp(request SignUpRequest) (response SignUpResponse) {
+	if request.Email == "" || request.Password == "" || request.ClientID == "" {
+		response.Invalid("You must supply an email address, password and client ID")
+		return
+	}
+
+	input := &cognitoidentityprovider.SignUpInput{
+		ClientId: aws.String(request.ClientID),
+		Password: aws.String(request.Password),
+		UserAttributes: []*cognitoidentityprovider.AttributeType{
+			{
+				Name:  aws.String("email"),
+				Value: aws.String(request.Email),
+			},
+		},
+		Username: aws.String(request.Email),
+	}
+	if request.Name != "" {
+		input.UserAttributes = append(input.UserAttributes, &cognitoidentityprovider.AttributeType{
+			Name:  aws.String("name"),
+			Value: aws.String(request.Name),
+		})
+	}
+	secretHash, err := config.secretHash(request)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	input.SecretHash = secretHash
+
+	output, err := config.CognitoClient.SignUp(input)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	delivery := newCodeDeliveryResponse(output.CodeDeliveryDetails)
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.UserSub = aws.StringValue(output.UserSub)
+	response.UserConfirmed = aws.BoolValue(output.UserConfirmed)
+	response.CodeDelivery = delivery.CodeDelivery
+	return
+}
+
+func (config Config) ConfirmSignUp(request SignUpRequest) (response StatusResponse) {
+	if request.Email == "" || request.Code == "" || request.ClientID == "" {
+		response.Invalid("You must supply an email address, confirmation code and client ID")
+		return
+	}
+
+	secretHash, err := config.secretHash(request)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	_, err = config.CognitoClient.ConfirmSignUp(&cognitoidentityprovider.ConfirmSignUpInput{
+		ClientId:         aws.String(request.ClientID),
+		ConfirmationCode: aws.String(request.Code),
+		SecretHash:       secretHash,
+		Username:         aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	return
+}
+
+func (config Config) ResendConfirmationCode(request SignUpRequest) (response CodeDeliveryResponse) {
+	if request.Email == "" || request.ClientID == "" {
+		response.Invalid("You must supply an email address and client ID")
+		return
+	}
+
+	secretHash, err := config.secretHash(request)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	output, err := config.CognitoClient.ResendConfirmationCode(&cognitoidentityprovider.ResendConfirmationCodeInput{
+		ClientId:   aws.String(request.ClientID),
+		SecretHash: secretHash,
+		Username:   aws.String(request.Email),
+	})
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+	return newCodeDeliveryResponse(output.CodeDeliveryDetails)
+}
+
+// secretHash returns the SECRET_HASH for the request, or nil for clients
+// without a secret.
+func (config Config) secretHash(request SignUpRequest) (*string, error) {
+	secret, err := config.clientSecret(request.ClientSecret, request.UserPoolID, request.ClientID)
+	if err != nil || secret == "" {
+		return nil, err
+	}
+	return aws.String(SecretHash(request.Email, request.ClientID, secret)), nil
+}
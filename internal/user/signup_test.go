@@ -0,0 +1,57 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"testing"
+)
+
+func TestSignUp(t *testing.T) {
+	config, poolID, clientID := newConfig(t)
+	fake := config.CognitoClient.(*cognitofake.Cognito)
+	request := user.SignUpRequest{Email: "jo@example.com", Password: password, Name: "Jo", ClientID: clientID}
+
+	signedUp := config.SignUp(request)
+	checkResult(t, signedUp, 200, "")
+	if signedUp.UserConfirmed || signedUp.UserSub == "" || signedUp.CodeDelivery == nil {
+		t.Fatalf("sign-up = %+v, want an unconfirmed user and a code sent", signedUp)
+	}
+
+	tests := []struct {
+		name    string
+		request user.SignUpRequest
+		status  int
+		code    string
+	}{
+		{"rejects existing user", request, 409, cognitoidentityprovider.ErrCodeUsernameExistsException},
+		{"rejects weak password", user.SignUpRequest{Email: "weak@example.com", Password: "short", ClientID: clientID}, 400, cognitoidentityprovider.ErrCodeInvalidPasswordException},
+		{"requires client", user.SignUpRequest{Email: "al@example.com", Password: password}, 400, "InvalidRequest"},
+		{"rejects unknown client", user.SignUpRequest{Email: "al@example.com", Password: password, ClientID: "missing"}, 404, cognitoidentityprovider.ErrCodeResourceNotFoundException},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkResult(t, config.SignUp(test.request), test.status, test.code)
+		})
+	}
+
+	login := user.LoginRequest{Email: "jo@example.com", Password: password, ClientID: clientID}
+	checkResult(t, config.AuthenticateUser(login), 403, cognitoidentityprovider.ErrCodeUserNotConfirmedException)
+
+	wrong := request
+	wrong.Code = "000000"
+	checkResult(t, config.ConfirmSignUp(wrong), 400, cognitoidentityprovider.ErrCodeCodeMismatchException)
+
+	resent := config.ResendConfirmationCode(request)
+	checkResult(t, resent, 200, "")
+	if resent.CodeDelivery == nil || resent.CodeDelivery.AttributeName != "email" {
+		t.Errorf("code delivery = %+v, want by email", resent.CodeDelivery)
+	}
+	code, ok := fake.ConfirmationCode(poolID, "jo@example.com")
+	if !ok {
+		t.Fatalf("no confirmation code was sent")
+	}
+	request.Code = code
+	checkResult(t, config.ConfirmSignUp(request), 200, "")
+	checkResult(t, config.AuthenticateUser(login), 200, "")
+}
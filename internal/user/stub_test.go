@@ -0,0 +1,119 @@
+package user_test
+
+import (
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"testing"
+)
+
+// stub answers the calls a test makes with canned results and records the
+// operations called, in order. Any other call panics on the nil interface.
+type stub struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	calls     []string
+	createErr error
+	pages     [][]string
+}
+
+func (s *stub) AdminCreateUser(input *cognitoidentityprovider.AdminCreateUserInput) (*cognitoidentityprovider.AdminCreateUserOutput, error) {
+	s.calls = append(s.calls, "AdminCreateUser")
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	return &cognitoidentityprovider.AdminCreateUserOutput{User: &cognitoidentityprovider.UserType{
+		Username:   input.Username,
+		Attributes: input.UserAttributes,
+		UserStatus: aws.String(cognitoidentityprovider.UserStatusTypeForceChangePassword),
+	}}, nil
+}
+
+func (s *stub) AdminSetUserPassword(input *cognitoidentityprovider.AdminSetUserPasswordInput) (*cognitoidentityprovider.AdminSetUserPasswordOutput, error) {
+	s.calls = append(s.calls, "AdminSetUserPassword")
+	if !aws.BoolValue(input.Permanent) {
+		return nil, awserr.New(cognitoidentityprovider.ErrCodeInvalidParameterException, "want a permanent password", nil)
+	}
+	return &cognitoidentityprovider.AdminSetUserPasswordOutput{}, nil
+}
+
+func (s *stub) AdminDeleteUser(input *cognitoidentityprovider.AdminDeleteUserInput) (*cognitoidentityprovider.AdminDeleteUserOutput, error) {
+	s.calls = append(s.calls, "AdminDeleteUser")
+	return nil, awserr.New(cognitoidentityprovider.ErrCodeUserNotFoundException, "User does not exist.", nil)
+}
+
+// ListUsers serves s.pages, the usernames of each page, using the page
+// number as the pagination token.
+func (s *stub) ListUsers(input *cognitoidentityprovider.ListUsersInput) (*cognitoidentityprovider.ListUsersOutput, error) {
+	s.calls = append(s.calls, "ListUsers")
+	page := 0
+	if input.PaginationToken != nil {
+		page = int(aws.StringValue(input.PaginationToken)[0] - '0')
+	}
+	output := &cognitoidentityprovider.ListUsersOutput{}
+	for _, username := range s.pages[page] {
+		output.Users = append(output.Users, &cognitoidentityprovider.UserType{Username: aws.String(username)})
+	}
+	if page+1 < len(s.pages) {
+		output.PaginationToken = aws.String(string(rune('0' + page + 1)))
+	}
+	return output, nil
+}
+
+func TestAddUserCallsCognito(t *testing.T) {
+	tests := []struct {
+		name   string
+		stub   *stub
+		status int
+		calls  []string
+	}{
+		{"creates then sets a permanent password", &stub{}, 200, []string{"AdminCreateUser", "AdminSetUserPassword"}},
+		{"stops when the user cannot be created",
+			&stub{createErr: awserr.New(cognitoidentityprovider.ErrCodeUsernameExistsException, "exists", nil)},
+			409, []string{"AdminCreateUser"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := user.Config{CognitoClient: test.stub}
+			response := config.AddUser(newUser("pool", "jo@example.com"))
+			if response.ResponseCode != test.status {
+				t.Fatalf("response code = %d (%s), want %d", response.ResponseCode, response.Message, test.status)
+			}
+			if len(test.stub.calls) != len(test.calls) {
+				t.Fatalf("calls = %v, want %v", test.stub.calls, test.calls)
+			}
+			for i := range test.calls {
+				if test.stub.calls[i] != test.calls[i] {
+					t.Fatalf("calls = %v, want %v", test.stub.calls, test.calls)
+				}
+			}
+			if test.status == 200 && response.UserList[0].Status != cognitoidentityprovider.UserStatusTypeConfirmed {
+				t.Errorf("status = %s, want CONFIRMED", response.UserList[0].Status)
+			}
+		})
+	}
+}
+
+func TestDeleteUserMapsCognitoErrors(t *testing.T) {
+	config := user.Config{CognitoClient: &stub{}}
+	checkResult(t, config.DeleteUser(newUser("pool", "jo@example.com")), 404, cognitoidentityprovider.ErrCodeUserNotFoundException)
+	checkResult(t, config.DeleteUser(user.UserItem{}), 400, "InvalidRequest")
+}
+
+func TestListUserFollowsPages(t *testing.T) {
+	cognito := &stub{pages: [][]string{{"a", "b"}, {"c"}}}
+	config := user.Config{CognitoClient: cognito}
+
+	page := config.ListUser(user.ListUserRequest{UserPoolID: "pool"})
+	checkResult(t, page, 200, "")
+	if len(page.UserList) != 2 || page.NextToken != "1" {
+		t.Fatalf("first page = %d users, next %q; want 2 and a token", len(page.UserList), page.NextToken)
+	}
+
+	all := config.ListUser(user.ListUserRequest{UserPoolID: "pool", All: true})
+	checkResult(t, all, 200, "")
+	if len(all.UserList) != 3 || all.NextToken != "" {
+		t.Fatalf("all = %d users, next %q; want 3 and no token", len(all.UserList), all.NextToken)
+	}
+}
@@ -1,11 +1,13 @@
 package userpool
 
 import (
-	"encoding/json"
 	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"strings"
 	"time"
 )
@@ -18,12 +20,13 @@ type ListUserPoolClientRequest struct {
 
 type CreateUserPoolClientRequest struct {
 	AllowedOAuthFlows []string `json:"allowed_oauth_flows"`
-	AllowedOAuthFlowsUserPoolClient bool `json:"allowed_oauth_flows_userpool_client"`
+	AllowedOAuthFlowsUserPoolClient *bool `json:"allowed_oauth_flows_userpool_client"`
 	AllowedOAuthScopes []string `json:"allowed_oauth_scopes"`
-	AnalyticsConfiguration AnalyticsConfiguration `json:"analytics_config"`
+	AnalyticsConfiguration *AnalyticsConfiguration `json:"analytics_config"`
 	CallbackURLs []string `json:"callback_url"`
 	ClientName string `json:"client_name"`
 	DefaultRedirectURI string `json:"default_redirect_uri"`
+	EnableTokenRevocation *bool `json:"enable_token_revocation"`
 	ExplicitAuthFlows []string `json:"explicit_auth_flows"`
 	GenerateSecret bool `json:"generate_secret"`
 	LogoutURLs []string `json:"logout_urls"`
@@ -34,6 +37,13 @@ type CreateUserPoolClientRequest struct {
 	WriteAttributes []string `json:"write_attributes"`
 }
 
+// UpdateUserPoolClientRequest carries the client to update alongside the
+// fields to change. Fields left out of the request keep their current value.
+type UpdateUserPoolClientRequest struct {
+	ClientID string `json:"client_id"`
+	CreateUserPoolClientRequest
+}
+
 type AnalyticsConfiguration struct {
 	ApplicationId string `json:"application_id"`
 	ExternalId string `json:"external_id"`
@@ -42,14 +52,29 @@ type AnalyticsConfiguration struct {
 }
 
 type UserPoolClientResponse struct {
-	ResponseCode int `json:"response_code"`
-	Message string `json:"message"`
+	apierror.Result
 	Client []UserPoolClient `json:"clients"`
 }
 
 type UserPoolClient struct {
 	ClientID string `json:"client_id"`
 	ClientName string `json:"client_name"`
+	UserPoolID string `json:"user_pool_id,omitempty"`
+	AllowedOAuthFlows []string `json:"allowed_oauth_flows,omitempty"`
+	AllowedOAuthFlowsUserPoolClient bool `json:"allowed_oauth_flows_userpool_client,omitempty"`
+	AllowedOAuthScopes []string `json:"allowed_oauth_scopes,omitempty"`
+	AnalyticsConfiguration *AnalyticsConfiguration `json:"analytics_config,omitempty"`
+	CallbackURLs []string `json:"callback_url,omitempty"`
+	DefaultRedirectURI string `json:"default_redirect_uri,omitempty"`
+	EnableTokenRevocation *bool `json:"enable_token_revocation,omitempty"`
+	ExplicitAuthFlows []string `json:"explicit_auth_flows,omitempty"`
+	LogoutURLs []string `json:"logout_urls,omitempty"`
+	ReadAttributes []string `json:"read_attributes,omitempty"`
+	RefreshTokenValidity int64 `json:"refresh_token_validity,omitempty"`
+	SupportedIdentityProviders []string `json:"supported_identity_providers,omitempty"`
+	WriteAttributes []string `json:"write_attributes,omitempty"`
+	CreatedAt *time.Time `json:"created_date,omitempty"`
+	LastModifiedAt *time.Time `json:"last_modified_date,omitempty"`
 }
 
 type CreatePoolRequest struct {
@@ -72,15 +97,28 @@ type PoolItem struct {
 }
 
 type PoolResponse struct {
-	ResponseCode int `json:"response_code"`
-	Message string `json:"message"`
+	apierror.Result
 	Pools []PoolItem `json:"pools"`
 }
 
+type MfaConfigRequest struct {
+	UserPoolID string `json:"user_pool_id"`
+	MfaConfiguration string `json:"mfa_configuration"`
+	SoftwareTokenMfaEnabled bool `json:"software_token_mfa_enabled"`
+}
+
+type MfaConfigResponse struct {
+	apierror.Result
+	MfaConfiguration string `json:"mfa_configuration,omitempty"`
+	SoftwareTokenMfaEnabled bool `json:"software_token_mfa_enabled"`
+}
+
+// Config depends on the Cognito and IAM API interfaces rather than the
+// concrete clients so fakes can be substituted outside of AWS.
 type Config struct {
 	Information string
-	CognitoClient cognitoidentityprovider.CognitoIdentityProvider
-	IAMService iam.IAM
+	CognitoClient cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	IAMService iamiface.IAMAPI
 }
 
 func getRoleName(poolName string) string {
@@ -92,8 +130,7 @@ func getRoleName(poolName string) string {
 func (config Config) CreateUserPool(poolRequest CreatePoolRequest) (response PoolResponse) {
 	if len(poolRequest.PoolName) < 2 {
 		fmt.Println("Pool name is required")
-		response.ResponseCode = 500
-		response.Message = fmt.Sprintf("Pool name is required")
+		response.Invalid("Pool name is required")
 		return
 	}
 	doc := "{ \"Version\": \"2012-10-17\", \"Statement\": [ { \"Sid\": \"\", \"Effect\": \"Allow\", \"Principal\": { \"Service\": \"cognito-idp.amazonaws.com\" }, \"Action\": \"sts:AssumeRole\" } ] }"
@@ -109,8 +146,7 @@ func (config Config) CreateUserPool(poolRequest CreatePoolRequest) (response Poo
 
 	if iamErr != nil {
 		fmt.Println("Could not create role")
-		response.ResponseCode = 500
-		response.Message = fmt.Sprintf("Could not create role %s", iamErr.Error())
+		response.Fail(iamErr)
 		return
 	}
 
@@ -168,8 +204,7 @@ func (config Config) CreateUserPool(poolRequest CreatePoolRequest) (response Poo
 
 	if cgErr != nil {
 		fmt.Println("Could not create user pool")
-		response.ResponseCode = 500
-		response.Message = fmt.Sprintf("Could not create user pool %s", cgErr.Error())
+		response.Fail(cgErr)
 		return
 	}
 
@@ -191,8 +226,7 @@ func (config Config) ListUserPool(max int64) (response PoolResponse) {
 		}) // .ListBuckets(nil)
 	if err != nil {
 		fmt.Println("Could not list user pools")
-		response.ResponseCode = 500
-		response.Message = fmt.Sprintf("Could not list user pools %s", err.Error())
+		response.Fail(err)
 		return
 	}
 
@@ -207,9 +241,8 @@ func (config Config) ListUserPool(max int64) (response PoolResponse) {
 	}
 
 	response = PoolResponse{
-		ResponseCode: 200,
-		Message:      "Ok",
-		Pools:        poolList,
+		Result: apierror.Result{ResponseCode: 200, Message: "Ok"},
+		Pools:  poolList,
 	}
 
 	return
@@ -218,17 +251,13 @@ func (config Config) ListUserPool(max int64) (response PoolResponse) {
 func (config Config) CreateUserPoolClient (request CreateUserPoolClientRequest) (response UserPoolClientResponse){
 	clientInput := &cognitoidentityprovider.CreateUserPoolClientInput{
 		AllowedOAuthFlows:               aws.StringSlice(request.AllowedOAuthFlows),
-		AllowedOAuthFlowsUserPoolClient: aws.Bool(request.AllowedOAuthFlowsUserPoolClient),
+		AllowedOAuthFlowsUserPoolClient: request.AllowedOAuthFlowsUserPoolClient,
 		AllowedOAuthScopes:              aws.StringSlice(request.AllowedOAuthScopes),
-		AnalyticsConfiguration:          &cognitoidentityprovider.AnalyticsConfigurationType{
-			ApplicationId:  aws.String(request.AnalyticsConfiguration.ApplicationId),
-			ExternalId:     aws.String(request.AnalyticsConfiguration.ExternalId),
-			RoleArn:        aws.String(request.AnalyticsConfiguration.RoleArn),
-			UserDataShared: aws.Bool(request.AnalyticsConfiguration.UserDataShared),
-		},
+		AnalyticsConfiguration:          analyticsConfigurationInput(request.AnalyticsConfiguration),
 		CallbackURLs:                    aws.StringSlice(request.CallbackURLs),
 		ClientName:                      aws.String(request.ClientName),
-		DefaultRedirectURI:              aws.String(request.DefaultRedirectURI),
+		DefaultRedirectURI:              OptionalString(request.DefaultRedirectURI),
+		EnableTokenRevocation:           request.EnableTokenRevocation,
 		ExplicitAuthFlows:               aws.StringSlice(request.ExplicitAuthFlows),
 		GenerateSecret:                  aws.Bool(request.GenerateSecret),
 		LogoutURLs:                      aws.StringSlice(request.LogoutURLs),
@@ -240,9 +269,10 @@ func (config Config) CreateUserPoolClient (request CreateUserPoolClientRequest)
 	}
 	output, err := config.CognitoClient.CreateUserPoolClient(clientInput)
 	if err != nil {
-		response.Message = err.Error()
+		response.Fail(err)
 		return
 	}
+	response.ResponseCode = 200
 	response.Message = "Ok"
 	response.Client = append(response.Client, UserPoolClient{
 		ClientID:   aws.StringValue(output.UserPoolClient.ClientId),
@@ -254,8 +284,7 @@ func (config Config) CreateUserPoolClient (request CreateUserPoolClientRequest)
 func (config Config) ListUserPoolClients(request *cognitoidentityprovider.ListUserPoolClientsInput) (response UserPoolClientResponse) {
 	output, err := config.CognitoClient.ListUserPoolClients(request)
 	if err != nil {
-		response.ResponseCode = 500
-		response.Message = err.Error()
+		response.Fail(err)
 		return
 	}
 
@@ -272,67 +301,170 @@ func (config Config) ListUserPoolClients(request *cognitoidentityprovider.ListUs
 	return
 }
 
-func (config Config) UpdateUserPoolClient (request CreateUserPoolClientRequest, clientID string) string {
+// Updates CLIENT_ID with the fields supplied in the request. Cognito resets
+// any attribute missing from UpdateUserPoolClient to its default, so the
+// current client is described first and used for everything not supplied,
+// including the token validities and their units and the propagation of
+// user context data, which the request cannot change.
+func (config Config) UpdateUserPoolClient (request CreateUserPoolClientRequest, clientID string) (response UserPoolClientResponse) {
+	if clientID == "" || request.UserPoolId == "" {
+		response.Invalid("You must supply a client ID and user pool ID")
+		return
+	}
+
 	describeInput := &cognitoidentityprovider.DescribeUserPoolClientInput{
 		ClientId:   aws.String(clientID),
 		UserPoolId: aws.String(request.UserPoolId),
 	}
 
-	response , err := config.CognitoClient.DescribeUserPoolClient(describeInput)
+	described, err := config.CognitoClient.DescribeUserPoolClient(describeInput)
 	if err != nil {
-		return err.Error()
+		response.Fail(err)
+		return
 	}
+	current := described.UserPoolClient
 
 	updatedInput := &cognitoidentityprovider.UpdateUserPoolClientInput{
-		AllowedOAuthFlows:               aws.StringSlice(request.AllowedOAuthFlows),
-		AllowedOAuthFlowsUserPoolClient: aws.Bool(request.AllowedOAuthFlowsUserPoolClient),
-		AllowedOAuthScopes:              aws.StringSlice(request.AllowedOAuthScopes),
-		AnalyticsConfiguration:          &cognitoidentityprovider.AnalyticsConfigurationType{
-			ApplicationId:  aws.String(request.AnalyticsConfiguration.ApplicationId),
-			ExternalId:     aws.String(request.AnalyticsConfiguration.ExternalId),
-			RoleArn:        aws.String(request.AnalyticsConfiguration.RoleArn),
-			UserDataShared: aws.Bool(request.AnalyticsConfiguration.UserDataShared),
-		},
-		CallbackURLs:                    aws.StringSlice(request.CallbackURLs),
-		ClientName:                      aws.String(request.ClientName),
-		DefaultRedirectURI:              aws.String(request.DefaultRedirectURI),
-		ExplicitAuthFlows:               aws.StringSlice(request.ExplicitAuthFlows),
-		LogoutURLs:                      aws.StringSlice(request.LogoutURLs),
-		ReadAttributes:                  aws.StringSlice(request.ReadAttributes),
-		RefreshTokenValidity:            aws.Int64(request.RefreshTokenValidity),
-		SupportedIdentityProviders:      aws.StringSlice(request.SupportedIdentityProviders),
+		AccessTokenValidity:             current.AccessTokenValidity,
+		AllowedOAuthFlows:               aws.StringSlice(CheckerArrayString(request.AllowedOAuthFlows, aws.StringValueSlice(current.AllowedOAuthFlows))),
+		AllowedOAuthFlowsUserPoolClient: CheckerBool(request.AllowedOAuthFlowsUserPoolClient, current.AllowedOAuthFlowsUserPoolClient),
+		AllowedOAuthScopes:              aws.StringSlice(CheckerArrayString(request.AllowedOAuthScopes, aws.StringValueSlice(current.AllowedOAuthScopes))),
+		AnalyticsConfiguration:          current.AnalyticsConfiguration,
+		AuthSessionValidity:             current.AuthSessionValidity,
+		CallbackURLs:                    aws.StringSlice(CheckerArrayString(request.CallbackURLs, aws.StringValueSlice(current.CallbackURLs))),
+		ClientId:                        aws.String(clientID),
+		ClientName:                      aws.String(CheckerString(request.ClientName, aws.StringValue(current.ClientName))),
+		DefaultRedirectURI:              OptionalString(CheckerString(request.DefaultRedirectURI, aws.StringValue(current.DefaultRedirectURI))),
+		EnablePropagateAdditionalUserContextData: current.EnablePropagateAdditionalUserContextData,
+		EnableTokenRevocation:           CheckerBool(request.EnableTokenRevocation, current.EnableTokenRevocation),
+		ExplicitAuthFlows:               aws.StringSlice(CheckerArrayString(request.ExplicitAuthFlows, aws.StringValueSlice(current.ExplicitAuthFlows))),
+		IdTokenValidity:                 current.IdTokenValidity,
+		LogoutURLs:                      aws.StringSlice(CheckerArrayString(request.LogoutURLs, aws.StringValueSlice(current.LogoutURLs))),
+		PreventUserExistenceErrors:      current.PreventUserExistenceErrors,
+		ReadAttributes:                  aws.StringSlice(CheckerArrayString(request.ReadAttributes, aws.StringValueSlice(current.ReadAttributes))),
+		RefreshTokenValidity:            aws.Int64(CheckerInt(request.RefreshTokenValidity, aws.Int64Value(current.RefreshTokenValidity))),
+		SupportedIdentityProviders:      aws.StringSlice(CheckerArrayString(request.SupportedIdentityProviders, aws.StringValueSlice(current.SupportedIdentityProviders))),
+		TokenValidityUnits:              current.TokenValidityUnits,
 		UserPoolId:                      aws.String(request.UserPoolId),
-		WriteAttributes:                 aws.StringSlice(request.WriteAttributes),
+		WriteAttributes:                 aws.StringSlice(CheckerArrayString(request.WriteAttributes, aws.StringValueSlice(current.WriteAttributes))),
+	}
+	if request.AnalyticsConfiguration != nil {
+		updatedInput.AnalyticsConfiguration = analyticsConfigurationInput(request.AnalyticsConfiguration)
 	}
 
-	config.CognitoClient.UpdateUserPoolClient(updatedInput)
-	return response.String()
+	output, err := config.CognitoClient.UpdateUserPoolClient(updatedInput)
+	if err != nil {
+		response.Fail(err)
+		return
+	}
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.Client = append(response.Client, newUserPoolClient(output.UserPoolClient))
+	return
 }
 
-func (config Config) DescribeUserPoolClient (input *cognitoidentityprovider.DescribeUserPoolClientInput) string {
-	response , err := config.CognitoClient.DescribeUserPoolClient(input)
+func (config Config) DescribeUserPoolClient (input *cognitoidentityprovider.DescribeUserPoolClientInput) (response UserPoolClientResponse) {
+	output, err := config.CognitoClient.DescribeUserPoolClient(input)
 	if err != nil {
-		return err.Error()
+		response.Fail(err)
+		return
 	}
-	return response.String()
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.Client = append(response.Client, newUserPoolClient(output.UserPoolClient))
+	return
 }
 
-func (config Config) UserPoolClientResponseToJsonString (response UserPoolClientResponse) string {
-	responseJson, err := json.Marshal(response)
+// SetUserPoolMfaConfig turns MFA ON, OFF or OPTIONAL for the pool and
+// enables or disables TOTP software tokens as a second factor.
+func (config Config) SetUserPoolMfaConfig(request MfaConfigRequest) (response MfaConfigResponse) {
+	if request.UserPoolID == "" {
+		response.Invalid("You must supply a user pool ID")
+		return
+	}
+	switch request.MfaConfiguration {
+	case cognitoidentityprovider.UserPoolMfaTypeOff, cognitoidentityprovider.UserPoolMfaTypeOn, cognitoidentityprovider.UserPoolMfaTypeOptional:
+	default:
+		response.Invalid("You must supply an MFA configuration of OFF, ON or OPTIONAL")
+		return
+	}
+
+	output, err := config.CognitoClient.SetUserPoolMfaConfig(&cognitoidentityprovider.SetUserPoolMfaConfigInput{
+		MfaConfiguration: aws.String(request.MfaConfiguration),
+		SoftwareTokenMfaConfiguration: &cognitoidentityprovider.SoftwareTokenMfaConfigType{
+			Enabled: aws.Bool(request.SoftwareTokenMfaEnabled),
+		},
+		UserPoolId: aws.String(request.UserPoolID),
+	})
 	if err != nil {
-		return "Unable to construct JSON"
+		response.Fail(err)
+		return
 	}
-	return string(responseJson)
+
+	response.ResponseCode = 200
+	response.Message = "Ok"
+	response.MfaConfiguration = aws.StringValue(output.MfaConfiguration)
+	if output.SoftwareTokenMfaConfiguration != nil {
+		response.SoftwareTokenMfaEnabled = aws.BoolValue(output.SoftwareTokenMfaConfiguration.Enabled)
+	}
+	return
 }
 
-func (config Config) PoolResponseToJsonString (response PoolResponse) string {
-	responseJson, err := json.Marshal(response)
-	if err != nil {
-		return "Unable to construct JSON"
+func analyticsConfigurationInput(analytics *AnalyticsConfiguration) *cognitoidentityprovider.AnalyticsConfigurationType {
+	if analytics == nil {
+		return nil
+	}
+	return &cognitoidentityprovider.AnalyticsConfigurationType{
+		ApplicationId:  aws.String(analytics.ApplicationId),
+		ExternalId:     aws.String(analytics.ExternalId),
+		RoleArn:        aws.String(analytics.RoleArn),
+		UserDataShared: aws.Bool(analytics.UserDataShared),
 	}
-	return string(responseJson)
 }
 
+func newUserPoolClient(client *cognitoidentityprovider.UserPoolClientType) UserPoolClient {
+	item := UserPoolClient{
+		ClientID:                        aws.StringValue(client.ClientId),
+		ClientName:                      aws.StringValue(client.ClientName),
+		UserPoolID:                      aws.StringValue(client.UserPoolId),
+		AllowedOAuthFlows:               aws.StringValueSlice(client.AllowedOAuthFlows),
+		AllowedOAuthFlowsUserPoolClient: aws.BoolValue(client.AllowedOAuthFlowsUserPoolClient),
+		AllowedOAuthScopes:              aws.StringValueSlice(client.AllowedOAuthScopes),
+		CallbackURLs:                    aws.StringValueSlice(client.CallbackURLs),
+		DefaultRedirectURI:              aws.StringValue(client.DefaultRedirectURI),
+		EnableTokenRevocation:           client.EnableTokenRevocation,
+		ExplicitAuthFlows:               aws.StringValueSlice(client.ExplicitAuthFlows),
+		LogoutURLs:                      aws.StringValueSlice(client.LogoutURLs),
+		ReadAttributes:                  aws.StringValueSlice(client.ReadAttributes),
+		RefreshTokenValidity:            aws.Int64Value(client.RefreshTokenValidity),
+		SupportedIdentityProviders:      aws.StringValueSlice(client.SupportedIdentityProviders),
+		WriteAttributes:                 aws.StringValueSlice(client.WriteAttributes),
+		CreatedAt:                       client.CreationDate,
+		LastModifiedAt:                  client.LastModifiedDate,
+	}
+	if analytics := client.AnalyticsConfiguration; analytics != nil {
+		item.AnalyticsConfiguration = &AnalyticsConfiguration{
+			ApplicationId:  aws.StringValue(analytics.ApplicationId),
+			ExternalId:     aws.StringValue(analytics.ExternalId),
+			RoleArn:        aws.StringValue(analytics.RoleArn),
+			UserDataShared: aws.BoolValue(analytics.UserDataShared),
+		}
+	}
+	return item
+}
+
+// OptionalString leaves empty values out of the request, since Cognito rejects
+// empty strings for optional fields with a minimum length.
+func OptionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return aws.String(value)
+}
+
+// Checker helpers pick the requested value a when it was supplied and fall
+// back to the current value b otherwise.
 func CheckerArrayString(a []string, b []string) []string {
 	if a != nil {
 		return a
@@ -341,22 +473,22 @@ func CheckerArrayString(a []string, b []string) []string {
 }
 
 func CheckerString(a string, b string) string {
-	if len(a) <= 0 {
+	if len(a) > 0 {
 		return a
 	}
 	return b
 }
 
 func CheckerInt(a int64, b int64) int64 {
-	if a < 0 {
+	if a > 0 {
 		return a
 	}
 	return b
 }
 
-func CheckerBool(a bool, b bool) bool {
-	if a != b {
-		return b
+func CheckerBool(a *bool, b *bool) *bool {
+	if a != nil {
+		return a
 	}
-	return a
+	return b
 }
\ No newline at end of file
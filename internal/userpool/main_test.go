@@ -0,0 +1,176 @@
+package userpool_test
+
+import (
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"testing"
+)
+
+func newConfig() userpool.Config {
+	return userpool.Config{
+		Information:   "test",
+		CognitoClient: cognitofake.New("ap-southeast-1"),
+		IAMService:    cognitofake.NewIAM(),
+	}
+}
+
+// newPoolRequest fills in the messages Cognito requires alongside NAME.
+func newPoolRequest(name string) userpool.CreatePoolRequest {
+	return userpool.CreatePoolRequest{
+		EmailMessage:   "Your username is {username} and temporary password is {####}.",
+		EmailSubject:   "Welcome",
+		SMSMessage:     "Your username is {username} and temporary password is {####}.",
+		EmailVerifyMsg: "Your verification code is {####}.",
+		EmailVerifySub: "Verify your email",
+		SMSAuthMsg:     "Your authentication code is {####}.",
+		SMSVerifyMsg:   "Your verification code is {####}.",
+		PoolName:       name,
+		WaitDays:       7,
+	}
+}
+
+func TestCreateUserPool(t *testing.T) {
+	config := newConfig()
+	if response := config.CreateUserPool(newPoolRequest("taken")); response.ResponseCode != 200 {
+		t.Fatalf("seeding pool: %s", response.Message)
+	}
+
+	tests := []struct {
+		name    string
+		request userpool.CreatePoolRequest
+		status  int
+		code    string
+	}{
+		{"creates pool", newPoolRequest("customers"), 200, ""},
+		{"requires pool name", newPoolRequest(""), 400, "InvalidRequest"},
+		{"rejects empty messages", userpool.CreatePoolRequest{PoolName: "bare"}, 400, "InvalidParameter"},
+		{"rejects existing SMS role", newPoolRequest("taken"), 409, iam.ErrCodeEntityAlreadyExistsException},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.CreateUserPool(test.request)
+			if response.ResponseCode != test.status {
+				t.Fatalf("response code = %d (%s), want %d", response.ResponseCode, response.Message, test.status)
+			}
+			if test.code != "" {
+				if response.Error == nil || response.Error.Code != test.code {
+					t.Fatalf("error = %v, want code %s", response.Error, test.code)
+				}
+				return
+			}
+			if len(response.Pools) != 1 || response.Pools[0].PoolID == "" || response.Pools[0].PoolName != test.request.PoolName {
+				t.Fatalf("pools = %+v", response.Pools)
+			}
+		})
+	}
+}
+
+func TestCreateUserPoolClient(t *testing.T) {
+	config := newConfig()
+	created := config.CreateUserPool(newPoolRequest("customers"))
+	if created.ResponseCode != 200 {
+		t.Fatalf("seeding pool: %s", created.Message)
+	}
+	poolID := created.Pools[0].PoolID
+
+	tests := []struct {
+		name    string
+		request userpool.CreateUserPoolClientRequest
+		status  int
+		code    string
+	}{
+		{"creates client", userpool.CreateUserPoolClientRequest{UserPoolId: poolID, ClientName: "web"}, 200, ""},
+		{"creates client with secret", userpool.CreateUserPoolClientRequest{UserPoolId: poolID, ClientName: "server", GenerateSecret: true}, 200, ""},
+		{"requires client name", userpool.CreateUserPoolClientRequest{UserPoolId: poolID}, 400, "InvalidParameter"},
+		{"rejects unknown pool", userpool.CreateUserPoolClientRequest{UserPoolId: "ap-southeast-1_missing", ClientName: "web"}, 404, cognitoidentityprovider.ErrCodeResourceNotFoundException},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := config.CreateUserPoolClient(test.request)
+			if response.ResponseCode != test.status {
+				t.Fatalf("response code = %d (%s), want %d", response.ResponseCode, response.Message, test.status)
+			}
+			if test.code != "" {
+				if response.Error == nil || response.Error.Code != test.code {
+					t.Fatalf("error = %v, want code %s", response.Error, test.code)
+				}
+				return
+			}
+			if len(response.Client) != 1 || response.Client[0].ClientID == "" || response.Client[0].ClientName != test.request.ClientName {
+				t.Fatalf("clients = %+v", response.Client)
+			}
+		})
+	}
+}
+
+func TestUpdateUserPoolClientKeepsUnsuppliedFields(t *testing.T) {
+	config := newConfig()
+	created := config.CreateUserPool(newPoolRequest("customers"))
+	if created.ResponseCode != 200 {
+		t.Fatalf("seeding pool: %s", created.Message)
+	}
+	poolID := created.Pools[0].PoolID
+	client, err := config.CognitoClient.CreateUserPoolClient(&cognitoidentityprovider.CreateUserPoolClientInput{
+		AccessTokenValidity:                      aws.Int64(2),
+		AuthSessionValidity:                      aws.Int64(10),
+		CallbackURLs:                             aws.StringSlice([]string{"https://example.com/callback"}),
+		ClientName:                               aws.String("web"),
+		EnablePropagateAdditionalUserContextData: aws.Bool(true),
+		ExplicitAuthFlows:                        aws.StringSlice([]string{"ALLOW_USER_PASSWORD_AUTH", "ALLOW_REFRESH_TOKEN_AUTH"}),
+		IdTokenValidity:                          aws.Int64(3),
+		PreventUserExistenceErrors:               aws.String(cognitoidentityprovider.PreventUserExistenceErrorTypesEnabled),
+		RefreshTokenValidity:                     aws.Int64(12),
+		TokenValidityUnits: &cognitoidentityprovider.TokenValidityUnitsType{
+			AccessToken:  aws.String(cognitoidentityprovider.TimeUnitsTypeHours),
+			IdToken:      aws.String(cognitoidentityprovider.TimeUnitsTypeHours),
+			RefreshToken: aws.String(cognitoidentityprovider.TimeUnitsTypeHours),
+		},
+		UserPoolId: aws.String(poolID),
+	})
+	if err != nil {
+		t.Fatalf("seeding client: %s", err)
+	}
+	clientID := aws.StringValue(client.UserPoolClient.ClientId)
+
+	response := config.UpdateUserPoolClient(userpool.CreateUserPoolClientRequest{UserPoolId: poolID, ClientName: "renamed"}, clientID)
+	if response.ResponseCode != 200 {
+		t.Fatalf("response code = %d (%s), want 200", response.ResponseCode, response.Message)
+	}
+
+	described, err := config.CognitoClient.DescribeUserPoolClient(&cognitoidentityprovider.DescribeUserPoolClientInput{
+		ClientId:   aws.String(clientID),
+		UserPoolId: aws.String(poolID),
+	})
+	if err != nil {
+		t.Fatalf("describing client: %s", err)
+	}
+	got := described.UserPoolClient
+	if name := aws.StringValue(got.ClientName); name != "renamed" {
+		t.Errorf("client name = %s, want renamed", name)
+	}
+	checks := []struct {
+		field string
+		got   interface{}
+		want  interface{}
+	}{
+		{"access token validity", aws.Int64Value(got.AccessTokenValidity), int64(2)},
+		{"ID token validity", aws.Int64Value(got.IdTokenValidity), int64(3)},
+		{"refresh token validity", aws.Int64Value(got.RefreshTokenValidity), int64(12)},
+		{"auth session validity", aws.Int64Value(got.AuthSessionValidity), int64(10)},
+		{"prevent user existence errors", aws.StringValue(got.PreventUserExistenceErrors), cognitoidentityprovider.PreventUserExistenceErrorTypesEnabled},
+		{"access token units", aws.StringValue(got.TokenValidityUnits.AccessToken), cognitoidentityprovider.TimeUnitsTypeHours},
+		{"ID token units", aws.StringValue(got.TokenValidityUnits.IdToken), cognitoidentityprovider.TimeUnitsTypeHours},
+		{"refresh token units", aws.StringValue(got.TokenValidityUnits.RefreshToken), cognitoidentityprovider.TimeUnitsTypeHours},
+		{"propagate user context data", aws.BoolValue(got.EnablePropagateAdditionalUserContextData), true},
+		{"callback URLs", len(got.CallbackURLs), 1},
+		{"explicit auth flows", len(got.ExplicitAuthFlows), 2},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %v, want %v", check.field, check.got, check.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package userpool
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+	"unicode/utf8"
+)
+
+// PasswordSymbols are the characters Cognito counts as symbols.
+const PasswordSymbols = "^$*.[]{}()?\"!@#%&/\\,><':;|_~`=+- "
+
+// PasswordPolicy looks up the password policy of a user pool, which is nil
+// when the pool has none.
+func (config Config) PasswordPolicy(userPoolID string) (*cognitoidentityprovider.PasswordPolicyType, error) {
+	output, err := config.CognitoClient.DescribeUserPool(&cognitoidentityprovider.DescribeUserPoolInput{
+		UserPoolId: aws.String(userPoolID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.UserPool.Policies == nil {
+		return nil, nil
+	}
+	return output.UserPool.Policies.PasswordPolicy, nil
+}
+
+// PasswordFaults lists each rule of POLICY that PASSWORD breaks. The faults
+// name no field; callers fill in the one the password came from.
+func PasswordFaults(policy *cognitoidentityprovider.PasswordPolicyType, password string) []apierror.FieldError {
+	if policy == nil {
+		return nil
+	}
+	var faults []apierror.FieldError
+	fault := func(code string, message string) {
+		faults = append(faults, apierror.FieldError{Code: code, Message: message})
+	}
+	if min := aws.Int64Value(policy.MinimumLength); int64(utf8.RuneCountInString(password)) < min {
+		fault(apierror.FieldTooShort, fmt.Sprintf("must be at least %d characters long", min))
+	}
+	if aws.BoolValue(policy.RequireLowercase) && !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz") {
+		fault(apierror.FieldPasswordPolicy, "must contain a lowercase letter")
+	}
+	if aws.BoolValue(policy.RequireUppercase) && !strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+		fault(apierror.FieldPasswordPolicy, "must contain an uppercase letter")
+	}
+	if aws.BoolValue(policy.RequireNumbers) && !strings.ContainsAny(password, "0123456789") {
+		fault(apierror.FieldPasswordPolicy, "must contain a number")
+	}
+	if aws.BoolValue(policy.RequireSymbols) && !strings.ContainsAny(password, PasswordSymbols) {
+		fault(apierror.FieldPasswordPolicy, "must contain a symbol")
+	}
+	return faults
+}
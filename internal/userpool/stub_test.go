@@ -0,0 +1,69 @@
+package userpool_test
+
+import (
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"testing"
+)
+
+// stubIAM creates roles, or fails with err.
+type stubIAM struct {
+	iamiface.IAMAPI
+	err  error
+	role string
+}
+
+func (s *stubIAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	s.role = aws.StringValue(input.RoleName)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &iam.CreateRoleOutput{Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role/" + s.role), RoleId: aws.String("AROA1")}}, nil
+}
+
+// stubCognito creates pools, keeping the last request.
+type stubCognito struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	created *cognitoidentityprovider.CreateUserPoolInput
+}
+
+func (s *stubCognito) CreateUserPool(input *cognitoidentityprovider.CreateUserPoolInput) (*cognitoidentityprovider.CreateUserPoolOutput, error) {
+	s.created = input
+	return &cognitoidentityprovider.CreateUserPoolOutput{UserPool: &cognitoidentityprovider.UserPoolType{Id: aws.String("ap-southeast-1_stub"), Name: input.PoolName}}, nil
+}
+
+func TestCreateUserPoolCallsIAMThenCognito(t *testing.T) {
+	tests := []struct {
+		name    string
+		iam     *stubIAM
+		status  int
+		created bool
+	}{
+		{"creates the SMS role then the pool", &stubIAM{}, 200, true},
+		{"stops when the role cannot be created", &stubIAM{err: awserr.New(iam.ErrCodeEntityAlreadyExistsException, "exists", nil)}, 409, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cognito := &stubCognito{}
+			config := userpool.Config{CognitoClient: cognito, IAMService: test.iam}
+			response := config.CreateUserPool(newPoolRequest("my-pool"))
+			if response.ResponseCode != test.status {
+				t.Fatalf("response code = %d (%s), want %d", response.ResponseCode, response.Message, test.status)
+			}
+			if test.iam.role != "mypool-SMS-Role" {
+				t.Errorf("role name = %q, want mypool-SMS-Role", test.iam.role)
+			}
+			if (cognito.created != nil) != test.created {
+				t.Fatalf("pool created = %v, want %v", cognito.created != nil, test.created)
+			}
+			if test.created && aws.StringValue(cognito.created.SmsConfiguration.SnsCallerArn) != "arn:aws:iam::123456789012:role/mypool-SMS-Role" {
+				t.Errorf("SNS caller = %s, want the new role", aws.StringValue(cognito.created.SmsConfiguration.SnsCallerArn))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/user"
+	"sort"
+)
+
+// AccessToken checks the self-service requests that need nothing but the
+// user's access token: get user, global sign-out and account deletion.
+func (config Config) AccessToken(request user.AccountRequest) error {
+	var f fields
+	f.required("access_token", request.AccessToken)
+	return f.err()
+}
+
+// ChangePassword cannot check the pool's password policy, as the access
+// token does not say which pool the user is in.
+func (config Config) ChangePassword(request user.AccountRequest) error {
+	var f fields
+	f.required("access_token", request.AccessToken)
+	f.required("previous_password", request.PreviousPassword)
+	f.required("proposed_password", request.ProposedPassword)
+	return f.err()
+}
+
+func (config Config) UpdateUserAttributes(request user.AccountRequest) error {
+	var f fields
+	f.required("access_token", request.AccessToken)
+	if len(request.Attributes) == 0 {
+		f.add("attributes", apierror.FieldRequired, "must name at least one attribute")
+	}
+	f.attributes("attributes", request.Attributes)
+	return f.err()
+}
+
+func (config Config) SendAttributeVerificationCode(request user.AccountRequest) error {
+	var f fields
+	f.required("access_token", request.AccessToken)
+	f.required("attribute_name", request.AttributeName)
+	return f.err()
+}
+
+func (config Config) VerifyUserAttribute(request user.AccountRequest) error {
+	var f fields
+	f.required("access_token", request.AccessToken)
+	f.required("attribute_name", request.AttributeName)
+	f.required("confirmation_code", request.Code)
+	return f.err()
+}
+
+func (config Config) RefreshToken(request user.RefreshRequest) error {
+	var f fields
+	f.required("refresh_token", request.RefreshToken)
+	f.required("client_id", request.ClientID)
+	if request.ClientSecret != "" {
+		f.required("username", request.Username)
+	}
+	return f.err()
+}
+
+func (config Config) Logout(request user.LogoutRequest) error {
+	var f fields
+	f.required("refresh_token", request.RefreshToken)
+	f.required("client_id", request.ClientID)
+	if request.GlobalSignOut && request.AccessToken == "" {
+		f.add("access_token", apierror.FieldRequired, "is required to sign out globally")
+	}
+	return f.err()
+}
+
+func (config Config) RespondToAuthChallenge(request user.ChallengeRequest) error {
+	var f fields
+	f.required("client_id", request.ClientID)
+	f.required("session", request.Session)
+	if f.required("challenge_name", request.ChallengeName) {
+		var challenges []string
+		for challenge := range user.ChallengeAnswers {
+			challenges = append(challenges, challenge)
+		}
+		sort.Strings(challenges)
+		f.oneOf("challenge_name", request.ChallengeName, challenges...)
+	}
+	if answer := user.ChallengeAnswers[request.ChallengeName]; answer != "" {
+		f.required("challenge_responses."+answer, request.Responses[answer])
+	}
+	return f.err()
+}
+
+// SignUp checks the password against the pool's policy when the request
+// names the pool.
+func (config Config) SignUp(request user.SignUpRequest) error {
+	var f fields
+	if f.required("email_address", request.Email) {
+		f.email("email_address", request.Email)
+	}
+	f.required("client_id", request.ClientID)
+	if f.required("password", request.Password) {
+		if err := config.password(&f, "password", request.UserPoolID, request.Password); err != nil {
+			return err
+		}
+	}
+	return f.err()
+}
+
+func (config Config) ConfirmSignUp(request user.SignUpRequest) error {
+	var f fields
+	f.required("email_address", request.Email)
+	f.required("confirmation_code", request.Code)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+func (config Config) ResendConfirmationCode(request user.SignUpRequest) error {
+	var f fields
+	f.required("email_address", request.Email)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+func (config Config) AssociateSoftwareToken(request user.SoftwareTokenRequest) error {
+	var f fields
+	if request.AccessToken == "" && request.Session == "" {
+		f.add("access_token", apierror.FieldRequired, "or session is required")
+	}
+	return f.err()
+}
+
+func (config Config) VerifySoftwareToken(request user.SoftwareTokenRequest) error {
+	var f fields
+	if request.AccessToken == "" && request.Session == "" {
+		f.add("access_token", apierror.FieldRequired, "or session is required")
+	}
+	f.required("code", request.Code)
+	return f.err()
+}
+
+func (config Config) SetMFAPreference(request user.MFAPreferenceRequest) error {
+	var f fields
+	f.required("access_token", request.AccessToken)
+	return f.err()
+}
+
+func (config Config) AdminSetMFAPreference(request user.MFAPreferenceRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	f.required("email_address", request.Email)
+	return f.err()
+}
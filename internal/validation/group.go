@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/user"
+)
+
+// group checks the fields every group operation shares, requiring the
+// group name and member email address where the operation needs them.
+func group(request user.GroupRequest, needGroup bool, needEmail bool) fields {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	if needGroup {
+		f.required("group_name", request.GroupName)
+	}
+	if needEmail {
+		f.required("email_address", request.Email)
+	}
+	if request.Precedence != nil && *request.Precedence < 0 {
+		f.add("precedence", apierror.FieldOutOfRange, "must not be negative")
+	}
+	f.limit("limit", request.Limit)
+	return f
+}
+
+func (config Config) CreateGroup(request user.GroupRequest) error {
+	return group(request, true, false).err()
+}
+
+func (config Config) UpdateGroup(request user.GroupRequest) error {
+	return group(request, true, false).err()
+}
+
+func (config Config) DeleteGroup(request user.GroupRequest) error {
+	return group(request, true, false).err()
+}
+
+func (config Config) ListGroups(request user.GroupRequest) error {
+	return group(request, false, false).err()
+}
+
+func (config Config) AddUserToGroup(request user.GroupRequest) error {
+	return group(request, true, true).err()
+}
+
+func (config Config) RemoveUserFromGroup(request user.GroupRequest) error {
+	return group(request, true, true).err()
+}
+
+func (config Config) ListUsersInGroup(request user.GroupRequest) error {
+	return group(request, true, false).err()
+}
+
+func (config Config) ListGroupsForUser(request user.GroupRequest) error {
+	return group(request, false, true).err()
+}
@@ -0,0 +1,155 @@
+// Package validation checks request bodies before they reach the user and
+// userpool packages. Every invalid field is reported at once, named by its
+// JSON path, rather than only the first one a model method or Cognito
+// rejects.
+package validation
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config looks up the password policy of a user pool, so a weak password
+// is reported with every rule it breaks. Without a CognitoClient passwords
+// are only checked for presence.
+type Config struct {
+	CognitoClient cognitoidentityprovideriface.CognitoIdentityProviderAPI
+}
+
+// maxLimit is the most items Cognito returns in one page.
+const maxLimit = 60
+
+// passwordPolicyTTL is how long a pool's password policy is trusted before
+// it is described again. A policy changed meanwhile is still enforced by
+// Cognito, only reported less fully.
+const passwordPolicyTTL = 10 * time.Minute
+
+// passwordPolicies caches cachedPolicy by user pool ID, as DescribeUserPool
+// has a low quota and would otherwise be called on every create.
+var passwordPolicies sync.Map
+
+type cachedPolicy struct {
+	policy *cognitoidentityprovider.PasswordPolicyType
+	fetched time.Time
+}
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// fields collects the invalid fields of one request.
+type fields []apierror.FieldError
+
+func (f *fields) add(field string, code string, message string) {
+	*f = append(*f, apierror.FieldError{Field: field, Code: code, Message: message})
+}
+
+// required reports VALUE if it is blank, and whether it was given.
+func (f *fields) required(field string, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		f.add(field, apierror.FieldRequired, "is required")
+		return false
+	}
+	return true
+}
+
+// email reports VALUE if it is given but is not a bare email address.
+func (f *fields) email(field string, value string) {
+	if value == "" {
+		return
+	}
+	if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+		f.add(field, apierror.FieldMalformed, "must be an email address, e.g. jo@example.com")
+	}
+}
+
+// phone reports VALUE if it is given but is not in E.164 format.
+func (f *fields) phone(field string, value string) {
+	if value != "" && !e164.MatchString(value) {
+		f.add(field, apierror.FieldMalformed, "must be in E.164 format, e.g. +6512345678")
+	}
+}
+
+// oneOf reports VALUE if it is given but is not one of ALLOWED.
+func (f *fields) oneOf(field string, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	f.add(field, apierror.FieldUnsupported, "must be one of "+strings.Join(allowed, ", "))
+}
+
+// between reports VALUE if it lies outside MIN to MAX.
+func (f *fields) between(field string, value int64, min int64, max int64) {
+	if value < min || value > max {
+		f.add(field, apierror.FieldOutOfRange, fmt.Sprintf("must be between %d and %d", min, max))
+	}
+}
+
+// limit reports a page size Cognito would refuse; zero leaves it to Cognito.
+func (f *fields) limit(field string, value int64) {
+	if value != 0 {
+		f.between(field, value, 1, maxLimit)
+	}
+}
+
+// attributes checks the values of the standard attributes that have a
+// format, keyed as in the request.
+func (f *fields) attributes(field string, attributes map[string]string) {
+	for name, value := range attributes {
+		switch name {
+		case "email":
+			f.email(field+"."+name, value)
+		case "phone_number":
+			f.phone(field+"."+name, value)
+		}
+	}
+}
+
+func (f fields) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return apierror.InvalidFields(f)
+}
+
+// password checks PASSWORD against the password policy of the user pool.
+// Without a user pool ID there is no policy to check, and Cognito has the
+// last word.
+func (config Config) password(f *fields, field string, userPoolID string, password string) error {
+	if password == "" || userPoolID == "" || config.CognitoClient == nil {
+		return nil
+	}
+	policy, err := config.passwordPolicy(userPoolID)
+	if err != nil {
+		return err
+	}
+	for _, fault := range userpool.PasswordFaults(policy, password) {
+		f.add(field, fault.Code, fault.Message)
+	}
+	return nil
+}
+
+// passwordPolicy returns the password policy of the user pool, described at
+// most once every passwordPolicyTTL.
+func (config Config) passwordPolicy(userPoolID string) (*cognitoidentityprovider.PasswordPolicyType, error) {
+	if cached, ok := passwordPolicies.Load(userPoolID); ok && time.Since(cached.(cachedPolicy).fetched) < passwordPolicyTTL {
+		return cached.(cachedPolicy).policy, nil
+	}
+	policy, err := userpool.Config{CognitoClient: config.CognitoClient}.PasswordPolicy(userPoolID)
+	if err != nil {
+		return nil, err
+	}
+	passwordPolicies.Store(userPoolID, cachedPolicy{policy: policy, fetched: time.Now()})
+	return policy, nil
+}
@@ -0,0 +1,203 @@
+package validation
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/user"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"strings"
+)
+
+func (config Config) AuthenticateUser(request user.LoginRequest) error {
+	var f fields
+	f.required("email_address", request.Email)
+	f.required("password", request.Password)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+func (config Config) ForgotPassword(request user.LoginRequest) error {
+	var f fields
+	f.required("email_address", request.Email)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+func (config Config) ConfirmForgotPassword(request user.ForgotPasswordRequest) error {
+	var f fields
+	f.required("email_address", request.Email)
+	f.required("password", request.Password)
+	f.required("confirmation_code", request.Code)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+// CreateUser checks the user to create and, in password mode, their
+// password against the pool's policy. Resending an invitation only needs
+// the email address.
+func (config Config) CreateUser(item user.UserItem) error {
+	var f fields
+	f.required("user_pool_id", item.UserPoolID)
+	f.oneOf("mode", item.Mode, user.CreateModePassword, user.CreateModeInvite)
+	if f.required("user.email_address", item.User.Email) {
+		f.email("user.email_address", item.User.Email)
+	}
+	f.phone("user.phone_number", item.User.PhoneNumber)
+	f.oneOf("user.phone_number_verified", item.User.PhoneNumberVerified, "true", "false")
+
+	password := "user.password"
+	if item.Mode == user.CreateModeInvite {
+		password = "invite.temporary_password"
+		if !item.Invite.Resend {
+			f.required("user.name", item.User.Name)
+		}
+		for i, medium := range item.Invite.DeliveryMediums {
+			field := fmt.Sprintf("invite.delivery_mediums[%d]", i)
+			f.oneOf(field, medium, cognitoidentityprovider.DeliveryMediumTypeEmail, cognitoidentityprovider.DeliveryMediumTypeSms)
+			if medium == cognitoidentityprovider.DeliveryMediumTypeSms && item.User.PhoneNumber == "" && !item.Invite.Resend {
+				f.add("user.phone_number", apierror.FieldRequired, "is required to invite by SMS")
+			}
+		}
+		if err := config.password(&f, password, item.UserPoolID, item.Invite.TemporaryPassword); err != nil {
+			return err
+		}
+		return f.err()
+	}
+
+	f.required("user.name", item.User.Name)
+	if f.required(password, item.User.Password) {
+		if err := config.password(&f, password, item.UserPoolID, item.User.Password); err != nil {
+			return err
+		}
+	}
+	return f.err()
+}
+
+func (config Config) DeleteUser(item user.UserItem) error {
+	var f fields
+	f.required("user_pool_id", item.UserPoolID)
+	f.required("user.email_address", item.User.Email)
+	return f.err()
+}
+
+func (config Config) ListUser(request user.ListUserRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	f.limit("limit", request.Limit)
+	return f.err()
+}
+
+// AdminUser checks the requests naming one user of a pool: disable, enable,
+// lock, sign out and password reset.
+func (config Config) AdminUser(request user.AdminUserRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	f.required("email_address", request.Email)
+	return f.err()
+}
+
+func (config Config) AdminGetUser(request user.AttributesRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	f.required("email_address", request.Email)
+	return f.err()
+}
+
+func (config Config) AdminUpdateUserAttributes(request user.AttributesRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	f.required("email_address", request.Email)
+	if len(request.Attributes) == 0 {
+		f.add("attributes", apierror.FieldRequired, "must name at least one attribute")
+	}
+	f.attributes("attributes", request.Attributes)
+	return f.err()
+}
+
+func (config Config) AdminDeleteUserAttributes(request user.AttributesRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	f.required("email_address", request.Email)
+	if len(request.AttributeNames) == 0 {
+		f.add("attribute_names", apierror.FieldRequired, "must name at least one attribute")
+	}
+	for i, name := range request.AttributeNames {
+		f.required(fmt.Sprintf("attribute_names[%d]", i), name)
+	}
+	return f.err()
+}
+
+func (config Config) ImportUsers(request user.ImportRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	if f.required("format", request.Format) {
+		f.oneOf("format", request.Format, user.ImportFormatCSV, user.ImportFormatNDJSON)
+	}
+	f.required("data", request.Data)
+	f.oneOf("mode", request.Mode, user.CreateModePassword, user.CreateModeInvite)
+	for i, medium := range request.Invite.DeliveryMediums {
+		f.oneOf(fmt.Sprintf("invite.delivery_mediums[%d]", i), medium, cognitoidentityprovider.DeliveryMediumTypeEmail, cognitoidentityprovider.DeliveryMediumTypeSms)
+	}
+	if request.Concurrency < 0 {
+		f.add("concurrency", apierror.FieldOutOfRange, "must not be negative")
+	}
+	if job := request.NativeJob; job != nil {
+		f.required("native_job.job_name", job.JobName)
+		f.required("native_job.cloudwatch_logs_role_arn", job.CloudWatchLogsRoleArn)
+	}
+	return f.err()
+}
+
+func (config Config) ExportUsers(request user.ExportRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	if f.required("format", request.Format) {
+		f.oneOf("format", request.Format, user.ImportFormatCSV, user.ImportFormatNDJSON)
+	}
+	if request.Key != "" && !request.Store {
+		f.add("store", apierror.FieldRequired, "is required with a key")
+	}
+	return f.err()
+}
+
+func (config Config) RestoreUsers(request user.RestoreRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	if f.required("format", request.Format) {
+		f.oneOf("format", request.Format, user.ImportFormatCSV, user.ImportFormatNDJSON)
+	}
+	switch {
+	case request.Data == "" && request.Key == "":
+		f.add("data", apierror.FieldRequired, "or a key is required")
+	case request.Data != "" && request.Key != "":
+		f.add("key", apierror.FieldConflict, "cannot be given with data")
+	}
+	if request.Concurrency < 0 {
+		f.add("concurrency", apierror.FieldOutOfRange, "must not be negative")
+	}
+	return f.err()
+}
+
+// BulkUsers checks a bulk delete or disable, which picks its users by
+// either a filter or a list of email addresses.
+func (config Config) BulkUsers(request user.BulkUserRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	hasFilter := strings.TrimSpace(request.Filter) != ""
+	switch {
+	case !hasFilter && len(request.EmailAddresses) == 0:
+		f.add("filter", apierror.FieldRequired, "or email_addresses is required")
+	case hasFilter && len(request.EmailAddresses) > 0:
+		f.add("email_addresses", apierror.FieldConflict, "cannot be given with a filter")
+	}
+	for i, email := range request.EmailAddresses {
+		f.email(fmt.Sprintf("email_addresses[%d]", i), email)
+	}
+	if request.Concurrency < 0 {
+		f.add("concurrency", apierror.FieldOutOfRange, "must not be negative")
+	}
+	if request.RatePerSecond < 0 || request.RatePerSecond > user.MaxBulkRate {
+		f.add("rate_per_second", apierror.FieldOutOfRange, fmt.Sprintf("must be between 0 and %d", user.MaxBulkRate))
+	}
+	return f.err()
+}
@@ -0,0 +1,189 @@
+package validation_test
+
+import (
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/cognitofake"
+	"fp-apac-cognito-service/internal/user"
+	"fp-apac-cognito-service/internal/validation"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
+	"strings"
+	"testing"
+)
+
+// newConfig returns a Config on the emulator and a pool with its default
+// password policy.
+func newConfig(t *testing.T) (validation.Config, string) {
+	fake := cognitofake.New("ap-southeast-1")
+	pool, err := fake.CreateUserPool(&cognitoidentityprovider.CreateUserPoolInput{PoolName: aws.String("test")})
+	if err != nil {
+		t.Fatalf("creating pool: %s", err)
+	}
+	return validation.Config{CognitoClient: fake}, aws.StringValue(pool.UserPool.Id)
+}
+
+// checkFields fails unless err reports exactly WANT, each given as
+// field:code, in order.
+func checkFields(t *testing.T, err error, want ...string) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("error = %v, want none", err)
+		}
+		return
+	}
+	invalid, ok := err.(*apierror.Error)
+	if !ok {
+		t.Fatalf("error = %v, want invalid fields %v", err, want)
+	}
+	if invalid.Status != 400 || invalid.Code != apierror.CodeInvalidRequest {
+		t.Errorf("error = %d %s, want 400 %s", invalid.Status, invalid.Code, apierror.CodeInvalidRequest)
+	}
+	var got []string
+	for _, field := range invalid.Fields {
+		got = append(got, field.Field+":"+field.Code)
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	config, poolID := newConfig(t)
+	valid := user.UserItem{UserPoolID: poolID, User: user.NewUserItem{Email: "jo@example.com", Name: "Jo", Password: "Passw0rd!"}}
+	with := func(change func(*user.UserItem)) user.UserItem {
+		item := valid
+		change(&item)
+		return item
+	}
+
+	tests := []struct {
+		name   string
+		item   user.UserItem
+		fields []string
+	}{
+		{"accepts a valid user", valid, nil},
+		{"reports every missing field", user.UserItem{}, []string{"user_pool_id:required", "user.email_address:required", "user.name:required", "user.password:required"}},
+		{"checks formats", with(func(i *user.UserItem) { i.User.Email = "Jo <jo@example.com>"; i.User.PhoneNumber = "12345" }),
+			[]string{"user.email_address:malformed", "user.phone_number:malformed"}},
+		{"reports each broken password rule", with(func(i *user.UserItem) { i.User.Password = "pass" }),
+			[]string{"user.password:too_short", "user.password:password_policy", "user.password:password_policy", "user.password:password_policy"}},
+		{"rejects unknown mode", with(func(i *user.UserItem) { i.Mode = "magic" }), []string{"mode:unsupported"}},
+		{"invites without a password", with(func(i *user.UserItem) { i.Mode = user.CreateModeInvite; i.User.Password = "" }), nil},
+		{"checks the temporary password", with(func(i *user.UserItem) {
+			i.Mode = user.CreateModeInvite
+			i.Invite.TemporaryPassword = "Passw0rd"
+		}), []string{"invite.temporary_password:password_policy"}},
+		{"names the medium by index", with(func(i *user.UserItem) {
+			i.Mode = user.CreateModeInvite
+			i.Invite.DeliveryMediums = []string{"EMAIL", "SMS", "PIGEON"}
+		}), []string{"user.phone_number:required", "invite.delivery_mediums[2]:unsupported"}},
+		{"resends with only an email address", user.UserItem{UserPoolID: poolID, Mode: user.CreateModeInvite,
+			User: user.NewUserItem{Email: "jo@example.com"}, Invite: user.InviteOptions{Resend: true}}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkFields(t, config.CreateUser(test.item), test.fields...)
+		})
+	}
+
+	err := config.CreateUser(with(func(i *user.UserItem) { i.UserPoolID = "ap-southeast-1_missing" }))
+	if mapped := apierror.FromError(err); mapped.Status != 404 {
+		t.Errorf("unknown pool = %d %s, want 404", mapped.Status, mapped.Code)
+	}
+	if err := (validation.Config{}).CreateUser(with(func(i *user.UserItem) { i.User.Password = "pass" })); err != nil {
+		t.Errorf("without a client the password is only checked for presence, got %v", err)
+	}
+}
+
+// describing counts the user pools described.
+type describing struct {
+	cognitoidentityprovideriface.CognitoIdentityProviderAPI
+	describes int
+}
+
+func (c *describing) DescribeUserPool(input *cognitoidentityprovider.DescribeUserPoolInput) (*cognitoidentityprovider.DescribeUserPoolOutput, error) {
+	c.describes++
+	return c.CognitoIdentityProviderAPI.DescribeUserPool(input)
+}
+
+func TestCreateUserDescribesThePoolOnce(t *testing.T) {
+	config, poolID := newConfig(t)
+	cognito := &describing{CognitoIdentityProviderAPI: config.CognitoClient}
+	config.CognitoClient = cognito
+
+	for _, password := range []string{"Passw0rd!", "pass", "Passw0rd!"} {
+		config.CreateUser(user.UserItem{UserPoolID: poolID, User: user.NewUserItem{Email: "jo@example.com", Name: "Jo", Password: password}})
+	}
+	if cognito.describes != 1 {
+		t.Errorf("described the pool %d times, want once", cognito.describes)
+	}
+}
+
+func TestImportUsers(t *testing.T) {
+	config := validation.Config{}
+	tests := []struct {
+		name    string
+		request user.ImportRequest
+		fields  []string
+	}{
+		{"accepts a valid import", user.ImportRequest{UserPoolID: "pool", Format: user.ImportFormatCSV, Data: "email_address\n"}, nil},
+		{"reports every missing field", user.ImportRequest{}, []string{"user_pool_id:required", "format:required", "data:required"}},
+		{"checks the options", user.ImportRequest{UserPoolID: "pool", Format: "xml", Data: "x", Mode: "magic", Concurrency: -1,
+			Invite: user.InviteOptions{DeliveryMediums: []string{"SMS", "FAX"}}},
+			[]string{"format:unsupported", "mode:unsupported", "invite.delivery_mediums[1]:unsupported", "concurrency:out_of_range"}},
+		{"requires the native job's name and role", user.ImportRequest{UserPoolID: "pool", Format: user.ImportFormatNDJSON, Data: "{}", NativeJob: &user.NativeImportJob{}},
+			[]string{"native_job.job_name:required", "native_job.cloudwatch_logs_role_arn:required"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkFields(t, config.ImportUsers(test.request), test.fields...)
+		})
+	}
+}
+
+func TestRestoreUsers(t *testing.T) {
+	config := validation.Config{}
+	tests := []struct {
+		name    string
+		request user.RestoreRequest
+		fields  []string
+	}{
+		{"accepts inline data", user.RestoreRequest{UserPoolID: "pool", Format: user.ImportFormatCSV, Data: "x"}, nil},
+		{"accepts an object", user.RestoreRequest{UserPoolID: "pool", Format: user.ImportFormatCSV, Key: "k"}, nil},
+		{"requires data or a key", user.RestoreRequest{UserPoolID: "pool", Format: user.ImportFormatCSV}, []string{"data:required"}},
+		{"refuses data and a key", user.RestoreRequest{UserPoolID: "pool", Format: user.ImportFormatCSV, Data: "x", Key: "k"}, []string{"key:conflict"}},
+		{"checks the concurrency", user.RestoreRequest{UserPoolID: "pool", Format: user.ImportFormatNDJSON, Key: "k", Concurrency: -1},
+			[]string{"concurrency:out_of_range"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkFields(t, config.RestoreUsers(test.request), test.fields...)
+		})
+	}
+}
+
+func TestBulkUsers(t *testing.T) {
+	config := validation.Config{}
+	tests := []struct {
+		name    string
+		request user.BulkUserRequest
+		fields  []string
+	}{
+		{"accepts a filter", user.BulkUserRequest{UserPoolID: "pool", Filter: `email ^= "test"`}, nil},
+		{"accepts email addresses", user.BulkUserRequest{UserPoolID: "pool", EmailAddresses: []string{"jo@example.com"}}, nil},
+		{"requires a filter or email addresses", user.BulkUserRequest{UserPoolID: "pool", Filter: "  "}, []string{"filter:required"}},
+		{"refuses both", user.BulkUserRequest{UserPoolID: "pool", Filter: `email ^= "test"`, EmailAddresses: []string{"jo@example.com"}},
+			[]string{"email_addresses:conflict"}},
+		{"checks each address and rate", user.BulkUserRequest{EmailAddresses: []string{"jo@example.com", "jo"}, Concurrency: -1, RatePerSecond: -1},
+			[]string{"user_pool_id:required", "email_addresses[1]:malformed", "concurrency:out_of_range", "rate_per_second:out_of_range"}},
+		{"caps the rate", user.BulkUserRequest{UserPoolID: "pool", Filter: `email ^= "test"`, RatePerSecond: user.MaxBulkRate + 1},
+			[]string{"rate_per_second:out_of_range"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkFields(t, config.BulkUsers(test.request), test.fields...)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"fmt"
+	"fp-apac-cognito-service/internal/apierror"
+	"fp-apac-cognito-service/internal/userpool"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	"net/url"
+)
+
+// maxValidityDays is the longest Cognito lets a refresh token, or an unused
+// temporary password, stay valid.
+const maxValidityDays = 3650
+
+func (config Config) CreateUserPool(request userpool.CreatePoolRequest) error {
+	var f fields
+	f.required("pool_name", request.PoolName)
+	f.between("wait_days", request.WaitDays, 0, 365)
+	return f.err()
+}
+
+func (config Config) ListUserPool(max int64) error {
+	var f fields
+	f.between("max", max, 1, maxLimit)
+	return f.err()
+}
+
+func (config Config) ListUserPoolClient(request userpool.ListUserPoolClientRequest) error {
+	var f fields
+	f.required("pool_id", request.PoolID)
+	f.between("max", request.Max, 1, maxLimit)
+	return f.err()
+}
+
+func (config Config) DescribeUserPoolClient(request userpool.ListUserPoolClientRequest) error {
+	var f fields
+	f.required("pool_id", request.PoolID)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+func (config Config) CreateUserPoolClient(request userpool.CreateUserPoolClientRequest) error {
+	f := client(request)
+	f.required("client_name", request.ClientName)
+	return f.err()
+}
+
+// UpdateUserPoolClient leaves out the client name, as fields left out keep
+// their current value.
+func (config Config) UpdateUserPoolClient(request userpool.UpdateUserPoolClientRequest) error {
+	f := client(request.CreateUserPoolClientRequest)
+	f.required("client_id", request.ClientID)
+	return f.err()
+}
+
+func (config Config) SetUserPoolMfaConfig(request userpool.MfaConfigRequest) error {
+	var f fields
+	f.required("user_pool_id", request.UserPoolID)
+	if f.required("mfa_configuration", request.MfaConfiguration) {
+		f.oneOf("mfa_configuration", request.MfaConfiguration,
+			cognitoidentityprovider.UserPoolMfaTypeOff,
+			cognitoidentityprovider.UserPoolMfaTypeOn,
+			cognitoidentityprovider.UserPoolMfaTypeOptional)
+	}
+	return f.err()
+}
+
+// client checks the fields an app client is created or updated with.
+func client(request userpool.CreateUserPoolClientRequest) fields {
+	var f fields
+	f.required("user_pool_id", request.UserPoolId)
+	f.between("refresh_token_validity", request.RefreshTokenValidity, 0, maxValidityDays)
+	for i, flow := range request.AllowedOAuthFlows {
+		f.oneOf(fmt.Sprintf("allowed_oauth_flows[%d]", i), flow,
+			cognitoidentityprovider.OAuthFlowTypeCode,
+			cognitoidentityprovider.OAuthFlowTypeImplicit,
+			cognitoidentityprovider.OAuthFlowTypeClientCredentials)
+	}
+	for i, link := range request.CallbackURLs {
+		f.url(fmt.Sprintf("callback_url[%d]", i), link)
+	}
+	for i, link := range request.LogoutURLs {
+		f.url(fmt.Sprintf("logout_urls[%d]", i), link)
+	}
+	f.url("default_redirect_uri", request.DefaultRedirectURI)
+	return f
+}
+
+// url reports VALUE if it is given but is not an absolute URL.
+func (f *fields) url(field string, value string) {
+	if value == "" {
+		return
+	}
+	if parsed, err := url.Parse(value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		f.add(field, apierror.FieldMalformed, "must be an absolute URL, e.g. https://example.com/callback")
+	}
+}